@@ -26,6 +26,10 @@ func main() {
 		log.Fatal("Database error:", err)
 	}
 
+	if err := services.NewPaymentTermService(database.GetDatabase()).SeedMissingPaymentTerms(); err != nil {
+		log.Fatal("Payment terms seeding error:", err)
+	}
+
 	lib.InitialiseJWT(string(config.AppConfig.JWTSecret))
 
 	if err := services.InitializeProvider(); err != nil {
@@ -43,13 +47,15 @@ func main() {
 	app.Use(cors.New(cors.Config{
 		AllowCredentials: true,
 		AllowHeaders: []string{
-			"Origin", "Content-Type", "Authorization", "X-RateLimit-Limit", "X-RateLimit-Reset",
+			"Origin", "Content-Type", "Authorization", "X-RateLimit-Limit", "X-RateLimit-Reset", "Idempotency-Key", "If-Match",
 		},
-		AllowMethods: []string{"DELETE", "GET", "POST", "PUT", "OPTIONS"},
-		AllowOrigins: []string{"*", config.AppConfig.ClientUrl},
+		AllowMethods:  []string{"DELETE", "GET", "POST", "PUT", "OPTIONS"},
+		AllowOrigins:  []string{"*", config.AppConfig.ClientUrl},
+		ExposeHeaders: []string{"ETag", "Idempotent-Replayed"},
 	}))
 	app.Use(middlewares.ErrorHandlerMiddleware())
 	app.Use(middlewares.AuthMiddleware())
+	app.Use(middlewares.IdempotencyMiddleware())
 	app.Use(lib.ErrorHandler())
 
 	app.MaxMultipartMemory = 10 << 20 // 10MB
@@ -57,6 +63,15 @@ func main() {
 	hub := lib.NewHub()
 	go hub.Run()
 
+	overdue := services.NewOverdueService(database.GetDatabase())
+	go overdue.Run(config.AppConfig.OverdueCheckEvery)
+
+	retention := services.NewRetentionService(database.GetDatabase())
+	go retention.Run(config.AppConfig.ArchivePurgeInterval, config.AppConfig.ArchiveRetention)
+
+	webhooks := services.NewWebhookService(database.GetDatabase())
+	go webhooks.Run(config.AppConfig.WebhookDispatchEvery)
+
 	prefix := config.AppConfig.Version
 	router := app.Group(prefix)
 	websocket := lib.NewWebSocketHandler(hub)
@@ -69,10 +84,24 @@ func main() {
 		})
 	})
 
+	routes.ActivityRoutes(router)
 	routes.AuthRoutes(router)
+	routes.ContactRoutes(router)
+	routes.CustomFieldRoutes(router)
 	routes.CustomerRoutes(router)
+	routes.ExpenseRoutes(router)
 	routes.InvoiceRoutes(router)
+	routes.PaymentRoutes(router)
+	routes.PaymentTermRoutes(router)
+	routes.PortalRoutes(router)
+	routes.ReportRoutes(router)
+	routes.SearchRoutes(router)
+	routes.StatementRoutes(router)
+	routes.TagRoutes(router)
+	routes.TaxRateRoutes(router)
+	routes.TimeEntryRoutes(router)
 	routes.UserRoutes(router)
+	routes.WebhookRoutes(router)
 
 	app.NoRoute(lib.GlobalNotFound())
 
@@ -13,61 +13,87 @@ type ApiRoute struct {
 }
 
 type Config struct {
-	AccessTokenExpiresIn time.Duration
-	AppEmail             string
-	ApiUrl               string
-	ClientUrl            string
-	CloudinaryName       string
-	CloudinaryKey        string
-	CloudinarySecret     string
-	CookieDomain         string
-	CurrentUser          string
-	CurrentUserId        string
-	CurrentUserRole      string
-	GinMode              string
-	GoogleClientId       string
-	GoogleClientSecret   string
-	IsDevMode            bool
-	JWTSecret            []byte
-	MaxImageSize         int
-	NonAuthRoutes        []ApiRoute
-	Port                 string
-	PostgresDbUrl        string
-	SmtpHost             string
-	SmtpPassword         string
-	SmtpPort             int
-	SmtpUser             string
-	Version              string
+	AccessTokenExpiresIn   time.Duration
+	AppEmail               string
+	ArchivePurgeInterval   time.Duration
+	ArchiveRetention       time.Duration
+	ApiUrl                 string
+	BaseCurrency           string
+	ClientUrl              string
+	CloudinaryName         string
+	CloudinaryKey          string
+	CloudinarySecret       string
+	CookieDomain           string
+	CurrentCustomer        string
+	CurrentCustomerId      string
+	CurrentIssuer          string
+	CurrentUser            string
+	CurrentUserId          string
+	CurrentUserRole        string
+	GinMode                string
+	GoogleClientId         string
+	GoogleClientSecret     string
+	IsDevMode              bool
+	JWTSecret              []byte
+	MaxImageSize           int
+	NonAuthRoutes          []ApiRoute
+	OverdueCheckEvery      time.Duration
+	Port                   string
+	PortalLinkExpiresIn    time.Duration
+	PortalSessionExpiresIn time.Duration
+	PostgresDbUrl          string
+	QuantityPrecision      int
+	SmtpHost               string
+	SmtpPassword           string
+	SmtpPort               int
+	SmtpUser               string
+	Version                string
+	WebhookDispatchEvery   time.Duration
+	WebhookMaxAttempts     int
+	WebhookTimeout         time.Duration
 }
 
 var AppConfig *Config
 
 func InitializeConfig() {
 	AppConfig = &Config{
-		AccessTokenExpiresIn: time.Hour * 24 * 30,
-		AppEmail:             os.Getenv("APP_EMAIL"),
-		ApiUrl:               os.Getenv("API_URL"),
-		ClientUrl:            os.Getenv("CLIENT_URL"),
-		CloudinaryName:       os.Getenv("CLOUDINARY_NAME"),
-		CloudinaryKey:        os.Getenv("CLOUDINARY_KEY"),
-		CloudinarySecret:     os.Getenv("CLOUDINARY_SECRET"),
-		CookieDomain:         os.Getenv("COOKIE_DOMAIN"),
-		CurrentUser:          "CURRENT_USER",
-		CurrentUserId:        "CURRENT_USER_ID",
-		CurrentUserRole:      "CURRENT_USER_ROLE",
-		GinMode:              os.Getenv("GIN_MODE"),
-		GoogleClientId:       os.Getenv("GOOGLE_CLIENT_ID"),
-		GoogleClientSecret:   os.Getenv("GOOGLE_CLIENT_SECRET"),
-		IsDevMode:            os.Getenv("IS_DEV_MODE") == "true",
-		JWTSecret:            []byte(os.Getenv("JWT_SECRET")),
-		MaxImageSize:         1024 * 1024 * 5,
-		Port:                 os.Getenv("PORT"),
-		PostgresDbUrl:        os.Getenv("POSTGRES_DB_URL"),
-		SmtpHost:             os.Getenv("SMTP_HOST"),
-		SmtpPassword:         os.Getenv("SMTP_PASSWORD"),
-		SmtpPort:             func() int { port, _ := strconv.Atoi(os.Getenv("SMTP_PORT")); return port }(),
-		SmtpUser:             os.Getenv("SMTP_USER"),
-		Version:              os.Getenv("VERSION"),
+		AccessTokenExpiresIn:   time.Hour * 24 * 30,
+		AppEmail:               os.Getenv("APP_EMAIL"),
+		ArchivePurgeInterval:   time.Hour * 24,
+		ArchiveRetention:       time.Hour * 24 * time.Duration(getEnvIntOrDefault("ARCHIVE_RETENTION_DAYS", 90)),
+		ApiUrl:                 os.Getenv("API_URL"),
+		BaseCurrency:           getEnvOrDefault("BASE_CURRENCY", "NGN"),
+		ClientUrl:              os.Getenv("CLIENT_URL"),
+		CloudinaryName:         os.Getenv("CLOUDINARY_NAME"),
+		CloudinaryKey:          os.Getenv("CLOUDINARY_KEY"),
+		CloudinarySecret:       os.Getenv("CLOUDINARY_SECRET"),
+		CookieDomain:           os.Getenv("COOKIE_DOMAIN"),
+		CurrentCustomer:        "CURRENT_CUSTOMER",
+		CurrentCustomerId:      "CURRENT_CUSTOMER_ID",
+		CurrentIssuer:          "CURRENT_ISSUER",
+		CurrentUser:            "CURRENT_USER",
+		CurrentUserId:          "CURRENT_USER_ID",
+		CurrentUserRole:        "CURRENT_USER_ROLE",
+		GinMode:                os.Getenv("GIN_MODE"),
+		GoogleClientId:         os.Getenv("GOOGLE_CLIENT_ID"),
+		GoogleClientSecret:     os.Getenv("GOOGLE_CLIENT_SECRET"),
+		IsDevMode:              os.Getenv("IS_DEV_MODE") == "true",
+		JWTSecret:              []byte(os.Getenv("JWT_SECRET")),
+		MaxImageSize:           1024 * 1024 * 5,
+		OverdueCheckEvery:      time.Minute * time.Duration(getEnvIntOrDefault("OVERDUE_CHECK_MINUTES", 60)),
+		Port:                   os.Getenv("PORT"),
+		PortalLinkExpiresIn:    time.Minute * 15,
+		PortalSessionExpiresIn: time.Hour * 24,
+		PostgresDbUrl:          os.Getenv("POSTGRES_DB_URL"),
+		QuantityPrecision:      getEnvIntOrDefault("QUANTITY_PRECISION", 3),
+		SmtpHost:               os.Getenv("SMTP_HOST"),
+		SmtpPassword:           os.Getenv("SMTP_PASSWORD"),
+		SmtpPort:               func() int { port, _ := strconv.Atoi(os.Getenv("SMTP_PORT")); return port }(),
+		SmtpUser:               os.Getenv("SMTP_USER"),
+		Version:                os.Getenv("VERSION"),
+		WebhookDispatchEvery:   time.Second * time.Duration(getEnvIntOrDefault("WEBHOOK_DISPATCH_SECONDS", 15)),
+		WebhookMaxAttempts:     getEnvIntOrDefault("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookTimeout:         time.Second * 10,
 		NonAuthRoutes: []ApiRoute{
 			{Endpoint: "/api/v1", Method: http.MethodGet},
 			{Endpoint: "/api/v1/health", Method: http.MethodGet},
@@ -75,6 +101,29 @@ func InitializeConfig() {
 			{Endpoint: "/api/v1/auth/:provider", Method: http.MethodGet},
 			{Endpoint: "/api/v1/auth/:provider/callback", Method: http.MethodGet},
 			{Endpoint: "/api/v1/auth/signout", Method: http.MethodPost},
+			// Portal routes are authenticated with customer tokens by the
+			// portal middleware instead.
+			{Endpoint: "/api/v1/portal/login", Method: http.MethodPost},
+			{Endpoint: "/api/v1/portal/verify", Method: http.MethodPost},
+			{Endpoint: "/api/v1/portal/me", Method: http.MethodGet},
+			{Endpoint: "/api/v1/portal/invoices", Method: http.MethodGet},
+			{Endpoint: "/api/v1/portal/invoices/:invoiceId", Method: http.MethodGet},
+			{Endpoint: "/api/v1/portal/invoices/:invoiceId/pdf", Method: http.MethodGet},
+			{Endpoint: "/api/v1/portal/statement", Method: http.MethodGet},
 		},
 	}
 }
+
+func getEnvOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getEnvIntOrDefault(key string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
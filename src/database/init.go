@@ -65,6 +65,12 @@ func InitializeDatabase() error {
 			return
 		}
 
+		if err := setupSearch(database); err != nil {
+			log.Printf("Failed to set up full-text search: %v", err)
+			initErr = err
+			return
+		}
+
 		if err := pingDatabase(database); err != nil {
 			initErr = err
 			return
@@ -144,8 +150,24 @@ func runMigrations(db *gorm.DB) error {
 		&models.BaseModel{},
 		&models.BankInformation{},
 		&models.Customer{},
+		&models.CustomerContact{},
+		&models.CustomerMerge{},
+		&models.CustomFieldDefinition{},
 		&models.Invoice{},
 		&models.InvoiceItem{},
+		&models.InvoiceInstallment{},
+		&models.TaxRate{},
+		&models.PaymentTerm{},
+		&models.InvoiceEvent{},
+		&models.InvoiceComment{},
+		&models.TimeEntry{},
+		&models.Expense{},
+		&models.WebhookEndpoint{},
+		&models.WebhookDelivery{},
+		&models.IdempotencyKey{},
+		&models.Payment{},
+		&models.PortalToken{},
+		&models.Tag{},
 		&models.User{},
 	}
 
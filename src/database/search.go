@@ -0,0 +1,65 @@
+package database
+
+import "gorm.io/gorm"
+
+// searchStatements keep the full-text search columns in sync. Customers only
+// need their own fields, so a generated column is enough. Invoices also index
+// their item descriptions, which live in another table, so their vector is
+// maintained by triggers on both tables. Every statement is idempotent and is
+// run on each start after AutoMigrate.
+var searchStatements = []string{
+	`ALTER TABLE customers ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(email, '')), 'B') ||
+			setweight(to_tsvector('simple', coalesce(phone, '')), 'B')
+		) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_customers_search_vector ON customers USING GIN (search_vector)`,
+
+	`ALTER TABLE invoices ADD COLUMN IF NOT EXISTS search_vector tsvector`,
+	`CREATE INDEX IF NOT EXISTS idx_invoices_search_vector ON invoices USING GIN (search_vector)`,
+	`CREATE OR REPLACE FUNCTION invoice_search_vector(invoice invoices) RETURNS tsvector AS $$
+		SELECT
+			setweight(to_tsvector('simple', coalesce(invoice.reference_no, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(invoice.title, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce((
+				SELECT string_agg(description, ' ') FROM invoice_items WHERE invoice_id = invoice.id
+			), '')), 'B') ||
+			setweight(to_tsvector('simple', coalesce(invoice.note, '')), 'C')
+	$$ LANGUAGE sql STABLE`,
+	`CREATE OR REPLACE FUNCTION invoices_search_vector_trigger() RETURNS trigger AS $$
+		BEGIN
+			NEW.search_vector := invoice_search_vector(NEW);
+			RETURN NEW;
+		END
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS invoices_search_vector_update ON invoices`,
+	`CREATE TRIGGER invoices_search_vector_update BEFORE INSERT OR UPDATE ON invoices
+		FOR EACH ROW EXECUTE FUNCTION invoices_search_vector_trigger()`,
+	`CREATE OR REPLACE FUNCTION invoice_items_search_vector_trigger() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP <> 'INSERT' THEN
+				UPDATE invoices SET search_vector = invoice_search_vector(invoices) WHERE id = OLD.invoice_id;
+			END IF;
+			IF TG_OP <> 'DELETE' THEN
+				UPDATE invoices SET search_vector = invoice_search_vector(invoices) WHERE id = NEW.invoice_id;
+			END IF;
+			RETURN NULL;
+		END
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS invoice_items_search_vector_update ON invoice_items`,
+	`CREATE TRIGGER invoice_items_search_vector_update AFTER INSERT OR UPDATE OR DELETE ON invoice_items
+		FOR EACH ROW EXECUTE FUNCTION invoice_items_search_vector_trigger()`,
+	`UPDATE invoices SET search_vector = invoice_search_vector(invoices) WHERE search_vector IS NULL`,
+}
+
+func setupSearch(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range searchStatements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+type CommentDto struct {
+	Body string `json:"body" validate:"required"`
+}
+
+// ActivityEntry is one line of an invoice timeline: either a system event or
+// an internal comment.
+type ActivityEntry struct {
+	ActorID    *string                `json:"actorId"`
+	Body       string                 `json:"body,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	EditedAt   *time.Time             `json:"editedAt,omitempty"`
+	ID         string                 `json:"id"`
+	Kind       string                 `json:"kind"`
+	OccurredAt time.Time              `json:"occurredAt"`
+	Type       string                 `json:"type,omitempty"`
+}
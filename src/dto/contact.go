@@ -0,0 +1,17 @@
+package dto
+
+type CreateContactDto struct {
+	Email            string `json:"email" validate:"required,email"`
+	IsBillingContact bool   `json:"isBillingContact"`
+	Name             string `json:"name" validate:"required"`
+	Phone            string `json:"phone"`
+	Role             string `json:"role"`
+}
+
+type UpdateContactDto struct {
+	Email            *string `json:"email,omitempty"`
+	IsBillingContact *bool   `json:"isBillingContact,omitempty"`
+	Name             *string `json:"name,omitempty"`
+	Phone            *string `json:"phone,omitempty"`
+	Role             *string `json:"role,omitempty"`
+}
@@ -0,0 +1,24 @@
+package dto
+
+type CreateCustomFieldDto struct {
+	Entity    string   `json:"entity" validate:"required"`
+	Key       string   `json:"key"`
+	Name      string   `json:"name" validate:"required"`
+	Options   []string `json:"options"`
+	Position  int      `json:"position"`
+	Required  bool     `json:"required"`
+	ShowOnPdf bool     `json:"showOnPdf"`
+	Type      string   `json:"type" validate:"required"`
+}
+
+type UpdateCustomFieldDto struct {
+	Name      *string  `json:"name,omitempty"`
+	Options   []string `json:"options,omitempty"`
+	Position  *int     `json:"position,omitempty"`
+	Required  *bool    `json:"required,omitempty"`
+	ShowOnPdf *bool    `json:"showOnPdf,omitempty"`
+}
+
+type CustomFieldParams struct {
+	Entity string `form:"entity"`
+}
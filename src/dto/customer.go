@@ -1,12 +1,29 @@
 package dto
 
 type CreateCustomerDto struct {
-	Email string `json:"email" validate:"required,email"`
-	Name  string `json:"name" validate:"required"`
-	Phone string `json:"phone" validate:"required"`
+	BillingAddress  *AddressDto            `json:"billingAddress,omitempty"`
+	CustomFields    map[string]interface{} `json:"customFields,omitempty"`
+	Email           string                 `json:"email" validate:"required,email"`
+	Name            string                 `json:"name" validate:"required"`
+	PaymentTermID   string                 `json:"paymentTermId,omitempty"`
+	Phone           string                 `json:"phone" validate:"required"`
+	ShippingAddress *AddressDto            `json:"shippingAddress,omitempty"`
 }
 
 type UpdateCustomerDto struct {
-	Name  *string `json:"name,omitempty"`
-	Phone *string `json:"phone,omitempty"`
+	BillingAddress  *AddressDto            `json:"billingAddress,omitempty"`
+	CustomFields    map[string]interface{} `json:"customFields,omitempty"`
+	Name            *string                `json:"name,omitempty"`
+	PaymentTermID   *string                `json:"paymentTermId,omitempty"`
+	Phone           *string                `json:"phone,omitempty"`
+	ShippingAddress *AddressDto            `json:"shippingAddress,omitempty"`
+}
+
+type AddressDto struct {
+	City       string `json:"city"`
+	Country    string `json:"country"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2"`
+	PostalCode string `json:"postalCode"`
+	State      string `json:"state"`
 }
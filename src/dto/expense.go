@@ -0,0 +1,41 @@
+package dto
+
+import "time"
+
+type CreateExpenseDto struct {
+	Amount      float64   `json:"amount" validate:"required"`
+	Billable    *bool     `json:"billable"`
+	Category    string    `json:"category"`
+	Currency    string    `json:"currency" validate:"required"`
+	CustomerID  string    `json:"customerId"`
+	Date        time.Time `json:"date"`
+	Description string    `json:"description"`
+	Markup      float64   `json:"markup"`
+	Vendor      string    `json:"vendor"`
+}
+
+type UpdateExpenseDto struct {
+	Amount      *float64   `json:"amount,omitempty"`
+	Billable    *bool      `json:"billable,omitempty"`
+	Category    *string    `json:"category,omitempty"`
+	Currency    *string    `json:"currency,omitempty"`
+	CustomerID  *string    `json:"customerId,omitempty"`
+	Date        *time.Time `json:"date,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	Markup      *float64   `json:"markup,omitempty"`
+	Vendor      *string    `json:"vendor,omitempty"`
+}
+
+type ExpenseFilter struct {
+	Billed     *bool      `form:"billed"`
+	Category   string     `form:"category"`
+	CustomerID string     `form:"customerId"`
+	From       *time.Time `form:"from" time_format:"2006-01-02"`
+	To         *time.Time `form:"to" time_format:"2006-01-02"`
+}
+
+// BillExpensesDto picks the expenses to add to an invoice. With no IDs every
+// unbilled billable expense of the invoice's customer is added.
+type BillExpensesDto struct {
+	ExpenseIDs []string `json:"expenseIds"`
+}
@@ -1,38 +1,83 @@
 package dto
 
-import "time"
+import (
+	"time"
 
+	"github.com/shopspring/decimal"
+)
+
+// CreateInvoiceDto creates an invoice. With a payment schedule the invoice is
+// due on the last installment's date, so DateDue may be left out and is
+// rejected if it names another day.
 type CreateInvoiceDto struct {
-	Currency     string                 `json:"currency"`
-	CustomerID   string                 `json:"customerId"`
-	DateDue      time.Time              `json:"dateDue"`
+	Currency      string                 `json:"currency"`
+	CustomerID    string                 `json:"customerId"`
+	CustomFields  map[string]interface{} `json:"customFields,omitempty"`
+	DateDue       time.Time              `json:"dateDue"`
+	Discount      float64                `json:"discount"`
+	DiscountType  string                 `json:"discountType"`
+	ExchangeRate  float64                `json:"exchangeRate"`
+	Installments  []InstallmentDto       `json:"installments,omitempty"`
+	IsDraft       bool                   `json:"isDraft"`
+	Items         []CreateInvoiceItemDto `json:"items,omitempty"`
+	Note          string                 `json:"note"`
+	PaymentTermID string                 `json:"paymentTermId"`
+	Tax           float64                `json:"tax"`
+	TaxType       string                 `json:"taxType"`
+	Title         string                 `json:"title"`
+}
+
+type CreateInvoiceItemDto struct {
+	CustomFields map[string]interface{} `json:"customFields,omitempty"`
+	Description  string                 `json:"description"`
 	Discount     float64                `json:"discount"`
 	DiscountType string                 `json:"discountType"`
-	IsDraft      bool                   `json:"isDraft"`
-	Items        []CreateInvoiceItemDto `json:"items,omitempty"`
-	Note         string                 `json:"note"`
-	Tax          float64                `json:"tax"`
-	TaxType      string                 `json:"taxType"`
-	Title        string                 `json:"title"`
+	ExpenseID    string                 `json:"expenseId,omitempty"`
+	LineTotal    float64                `json:"lineTotal"`
+	Quantity     decimal.Decimal        `json:"quantity"`
+	Price        float64                `json:"price"`
+	Taxes        []ItemTaxDto           `json:"taxes,omitempty"`
+	TimeEntryIDs []string               `json:"timeEntryIds,omitempty"`
+	Unit         string                 `json:"unit"`
 }
 
-type CreateInvoiceItemDto struct {
-	Description string  `json:"description"`
-	LineTotal   float64 `json:"lineTotal"`
-	Quantity    int     `json:"quantity"`
-	Price       float64 `json:"price"`
+// ItemTaxDto is either a reference to a rate in the tax catalog or a one-off
+// tax described in full.
+type ItemTaxDto struct {
+	Compound    bool    `json:"compound"`
+	Inclusive   bool    `json:"inclusive"`
+	Name        string  `json:"name"`
+	Rate        float64 `json:"rate"`
+	TaxRateID   string  `json:"taxRateId"`
+	Withholding bool    `json:"withholding"`
 }
 
+// UpdateInvoiceDto changes the fields that are set. As on create, an invoice
+// with a payment schedule is due on its last installment and a DateDue naming
+// another day is rejected.
 type UpdateInvoiceDto struct {
-	Currency     *string                `json:"currency"`
-	CustomerID   *string                `json:"customerId"`
-	DateDue      *time.Time             `json:"dateDue"`
-	Discount     *float64               `json:"discount"`
-	DiscountType *string                `json:"discountType"`
-	Items        []CreateInvoiceItemDto `json:"items"`
-	Note         *string                `json:"note"`
-	Tax          *float64               `json:"tax"`
-	TaxType      *string                `json:"taxType"`
-	Title        *string                `json:"title"`
-	Status       *string                `json:"status"`
+	Currency      *string                `json:"currency"`
+	CustomerID    *string                `json:"customerId"`
+	CustomFields  map[string]interface{} `json:"customFields"`
+	DateDue       *time.Time             `json:"dateDue"`
+	Discount      *float64               `json:"discount"`
+	DiscountType  *string                `json:"discountType"`
+	ExchangeRate  *float64               `json:"exchangeRate"`
+	Installments  []InstallmentDto       `json:"installments"`
+	Items         []CreateInvoiceItemDto `json:"items"`
+	Note          *string                `json:"note"`
+	PaymentTermID *string                `json:"paymentTermId"`
+	Tax           *float64               `json:"tax"`
+	TaxType       *string                `json:"taxType"`
+	Title         *string                `json:"title"`
+	Status        *string                `json:"status"`
+}
+
+// InstallmentDto is one part of a payment schedule. Exactly one of Amount and
+// Percentage must be set.
+type InstallmentDto struct {
+	Amount     *float64  `json:"amount"`
+	DueDate    time.Time `json:"dueDate"`
+	Label      string    `json:"label"`
+	Percentage *float64  `json:"percentage"`
 }
@@ -0,0 +1,5 @@
+package dto
+
+type MergeCustomerDto struct {
+	SourceID string `json:"sourceId" validate:"required"`
+}
@@ -1,18 +1,51 @@
 package dto
 
+import "time"
+
 type Pagination struct {
-	Page  int `json:"page"`
-	Limit int `json:"limit"`
+	Page         int    `json:"page" form:"page"`
+	Limit        int    `json:"limit" form:"limit"`
+	Cursor       string `json:"cursor,omitempty" form:"cursor"`
+	IncludeTotal bool   `json:"includeTotal,omitempty" form:"includeTotal"`
+	Paginate     string `json:"paginate,omitempty" form:"paginate"`
+}
+
+// UsesCursor reports whether keyset pagination was requested, either by
+// passing a cursor or by asking for the first page with paginate=cursor.
+func (p Pagination) UsesCursor() bool {
+	return p.Cursor != "" || p.Paginate == "cursor"
 }
 
 type CustomerPagination struct {
 	Pagination
-	Query *string `json:"query,omitempty"`
+	CustomFields    map[string]string `json:"customFields,omitempty" form:"-"`
+	IncludeArchived bool              `json:"includeArchived,omitempty" form:"includeArchived"`
+	Query           *string           `json:"query,omitempty" form:"query"`
+	TagMatch        string            `json:"tagMatch,omitempty" form:"tagMatch"`
+	Tags            []string          `json:"tags,omitempty" form:"tags"`
+	UserID          string            `json:"-" form:"-"`
 }
 
 type InvoicePagination struct {
 	Pagination
-	Query *string `json:"query,omitempty"`
+	Currency        *string           `json:"currency,omitempty" form:"currency"`
+	CustomerID      *string           `json:"customerId,omitempty" form:"customerId"`
+	CustomFields    map[string]string `json:"customFields,omitempty" form:"-"`
+	DueFrom         *time.Time        `json:"dueFrom,omitempty" form:"dueFrom" time_format:"2006-01-02"`
+	DueTo           *time.Time        `json:"dueTo,omitempty" form:"dueTo" time_format:"2006-01-02"`
+	IssuedFrom      *time.Time        `json:"issuedFrom,omitempty" form:"issuedFrom" time_format:"2006-01-02"`
+	IssuedTo        *time.Time        `json:"issuedTo,omitempty" form:"issuedTo" time_format:"2006-01-02"`
+	IncludeArchived bool              `json:"includeArchived,omitempty" form:"includeArchived"`
+	Overdue         bool              `json:"overdue,omitempty" form:"overdue"`
+	Query           *string           `json:"query,omitempty" form:"query"`
+	SortBy          string            `json:"sortBy,omitempty" form:"sortBy"`
+	SortOrder       string            `json:"sortOrder,omitempty" form:"sortOrder"`
+	Status          []string          `json:"status,omitempty" form:"status"`
+	TagMatch        string            `json:"tagMatch,omitempty" form:"tagMatch"`
+	Tags            []string          `json:"tags,omitempty" form:"tags"`
+	TotalMax        *float64          `json:"totalMax,omitempty" form:"totalMax"`
+	TotalMin        *float64          `json:"totalMin,omitempty" form:"totalMin"`
+	UserID          string            `json:"-" form:"-"`
 }
 
 type PaginatedResponse[T any] struct {
@@ -23,6 +56,14 @@ type PaginatedResponse[T any] struct {
 	TotalPages int `json:"total_pages"`
 }
 
+type CursorPaginatedResponse[T any] struct {
+	Data       []T     `json:"items"`
+	Limit      int     `json:"limit"`
+	NextCursor *string `json:"next_cursor"`
+	PrevCursor *string `json:"prev_cursor"`
+	TotalItems *int    `json:"total_items,omitempty"`
+}
+
 func Paginate[T any](data []T, params Pagination) PaginatedResponse[T] {
 	totalItems := int64(len(data))
 	totalPages := int((totalItems + int64(params.Limit) - 1) / int64(params.Limit))
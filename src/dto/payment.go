@@ -0,0 +1,12 @@
+package dto
+
+import "time"
+
+type CreatePaymentDto struct {
+	Amount    float64    `json:"amount"`
+	Kind      string     `json:"kind"`
+	Method    string     `json:"method"`
+	Note      string     `json:"note"`
+	PaidAt    *time.Time `json:"paidAt"`
+	Reference string     `json:"reference"`
+}
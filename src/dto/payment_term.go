@@ -0,0 +1,15 @@
+package dto
+
+type CreatePaymentTermDto struct {
+	Days      int    `json:"days"`
+	IsDefault bool   `json:"isDefault"`
+	Name      string `json:"name" validate:"required"`
+	Type      string `json:"type" validate:"required"`
+}
+
+type UpdatePaymentTermDto struct {
+	Days      *int    `json:"days,omitempty"`
+	IsDefault *bool   `json:"isDefault,omitempty"`
+	Name      *string `json:"name,omitempty"`
+	Type      *string `json:"type,omitempty"`
+}
@@ -0,0 +1,26 @@
+package dto
+
+import "time"
+
+type PortalLoginDto struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type PortalVerifyDto struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type PortalSession struct {
+	ExpiresAt time.Time `json:"expiresAt"`
+	Token     string    `json:"token"`
+}
+
+type PortalProfile struct {
+	Currency    string  `json:"currency"`
+	CustomerID  string  `json:"customerId"`
+	Email       string  `json:"email"`
+	Issuer      string  `json:"issuer"`
+	IssuerEmail string  `json:"issuerEmail"`
+	Name        string  `json:"name"`
+	Outstanding float64 `json:"outstanding"`
+}
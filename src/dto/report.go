@@ -0,0 +1,74 @@
+package dto
+
+import "time"
+
+type AgingReportParams struct {
+	AsOf   *time.Time `form:"asOf" time_format:"2006-01-02"`
+	Format string     `form:"format"`
+}
+
+type AgingBuckets struct {
+	Current    float64 `json:"current" gorm:"column:current"`
+	Days1To30  float64 `json:"days1To30" gorm:"column:days_1_to_30"`
+	Days31To60 float64 `json:"days31To60" gorm:"column:days_31_to_60"`
+	Days61To90 float64 `json:"days61To90" gorm:"column:days_61_to_90"`
+	Over90     float64 `json:"over90" gorm:"column:over_90"`
+	Total      float64 `json:"total" gorm:"column:total"`
+}
+
+type CustomerAging struct {
+	AgingBuckets
+	CustomerID   string `json:"customerId" gorm:"column:customer_id"`
+	CustomerName string `json:"customerName" gorm:"column:customer_name"`
+	InvoiceCount int    `json:"invoiceCount" gorm:"column:invoice_count"`
+}
+
+type AgingReport struct {
+	AsOf      time.Time       `json:"asOf"`
+	Currency  string          `json:"currency"`
+	Customers []CustomerAging `json:"customers"`
+	Totals    AgingBuckets    `json:"totals"`
+}
+
+type SummaryReportParams struct {
+	From   *time.Time `form:"from" time_format:"2006-01-02"`
+	To     *time.Time `form:"to" time_format:"2006-01-02"`
+	Period string     `form:"period"`
+	Limit  int        `form:"limit"`
+}
+
+type StatusCount struct {
+	Amount float64 `json:"amount" gorm:"column:amount"`
+	Count  int     `json:"count" gorm:"column:count"`
+	Status string  `json:"status" gorm:"column:status"`
+}
+
+type RevenuePeriod struct {
+	Collected float64   `json:"collected" gorm:"column:collected"`
+	Invoiced  float64   `json:"invoiced" gorm:"column:invoiced"`
+	Period    time.Time `json:"period" gorm:"column:period"`
+}
+
+type CustomerRevenue struct {
+	Collected    float64 `json:"collected" gorm:"column:collected"`
+	CustomerID   string  `json:"customerId" gorm:"column:customer_id"`
+	CustomerName string  `json:"customerName" gorm:"column:customer_name"`
+	InvoiceCount int     `json:"invoiceCount" gorm:"column:invoice_count"`
+	Invoiced     float64 `json:"invoiced" gorm:"column:invoiced"`
+	Outstanding  float64 `json:"outstanding" gorm:"column:outstanding"`
+}
+
+type SummaryReport struct {
+	AverageDaysToPay *float64          `json:"averageDaysToPay"`
+	Collected        float64           `json:"collected"`
+	Currency         string            `json:"currency"`
+	From             time.Time         `json:"from"`
+	InvoiceCount     int               `json:"invoiceCount"`
+	Invoiced         float64           `json:"invoiced"`
+	Outstanding      float64           `json:"outstanding"`
+	Period           string            `json:"period"`
+	Revenue          []RevenuePeriod   `json:"revenue"`
+	StatusCounts     []StatusCount     `json:"statusCounts"`
+	To               time.Time         `json:"to"`
+	TopCustomers     []CustomerRevenue `json:"topCustomers"`
+}
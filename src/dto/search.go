@@ -0,0 +1,15 @@
+package dto
+
+type SearchParams struct {
+	Limit int    `form:"limit"`
+	Query string `form:"q"`
+	Types string `form:"types"`
+}
+
+type SearchResult struct {
+	ID       string  `json:"id" gorm:"column:id"`
+	Rank     float64 `json:"rank" gorm:"column:rank"`
+	Subtitle string  `json:"subtitle" gorm:"column:subtitle"`
+	Title    string  `json:"title" gorm:"column:title"`
+	Type     string  `json:"type" gorm:"column:type"`
+}
@@ -0,0 +1,36 @@
+package dto
+
+import "time"
+
+type StatementParams struct {
+	From   *time.Time `form:"from" time_format:"2006-01-02"`
+	To     *time.Time `form:"to" time_format:"2006-01-02"`
+	Format string     `form:"format"`
+}
+
+type StatementEntry struct {
+	Balance        float64   `json:"balance" gorm:"-"`
+	Credit         float64   `json:"credit" gorm:"column:credit"`
+	Currency       string    `json:"currency" gorm:"column:currency"`
+	Date           time.Time `json:"date" gorm:"column:date"`
+	Debit          float64   `json:"debit" gorm:"column:debit"`
+	Description    string    `json:"description" gorm:"column:description"`
+	DocumentID     string    `json:"documentId" gorm:"column:document_id"`
+	OriginalAmount float64   `json:"originalAmount" gorm:"column:original_amount"`
+	Reference      string    `json:"reference" gorm:"column:reference"`
+	Type           string    `json:"type" gorm:"column:type"`
+}
+
+type Statement struct {
+	ClosingBalance float64          `json:"closingBalance"`
+	Currency       string           `json:"currency"`
+	CustomerEmail  string           `json:"customerEmail"`
+	CustomerID     string           `json:"customerId"`
+	CustomerName   string           `json:"customerName"`
+	Entries        []StatementEntry `json:"entries"`
+	From           time.Time        `json:"from"`
+	OpeningBalance float64          `json:"openingBalance"`
+	To             time.Time        `json:"to"`
+	TotalCredits   float64          `json:"totalCredits"`
+	TotalDebits    float64          `json:"totalDebits"`
+}
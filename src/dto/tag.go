@@ -0,0 +1,41 @@
+package dto
+
+import "time"
+
+type CreateTagDto struct {
+	Color string `json:"color"`
+	Name  string `json:"name" validate:"required"`
+}
+
+type UpdateTagDto struct {
+	Color *string `json:"color,omitempty"`
+	Name  *string `json:"name,omitempty"`
+}
+
+type TagIdsDto struct {
+	TagIDs []string `json:"tagIds" validate:"required"`
+}
+
+type TagReportParams struct {
+	From   *time.Time `form:"from" time_format:"2006-01-02"`
+	To     *time.Time `form:"to" time_format:"2006-01-02"`
+	Source string     `form:"source"`
+}
+
+type TagRevenue struct {
+	Collected    float64 `json:"collected" gorm:"column:collected"`
+	InvoiceCount int     `json:"invoiceCount" gorm:"column:invoice_count"`
+	Invoiced     float64 `json:"invoiced" gorm:"column:invoiced"`
+	Outstanding  float64 `json:"outstanding" gorm:"column:outstanding"`
+	TagColor     string  `json:"tagColor" gorm:"column:tag_color"`
+	TagID        string  `json:"tagId" gorm:"column:tag_id"`
+	TagName      string  `json:"tagName" gorm:"column:tag_name"`
+}
+
+type TagReport struct {
+	Currency string       `json:"currency"`
+	From     time.Time    `json:"from"`
+	Source   string       `json:"source"`
+	Tags     []TagRevenue `json:"tags"`
+	To       time.Time    `json:"to"`
+}
@@ -0,0 +1,19 @@
+package dto
+
+type CreateTaxRateDto struct {
+	Compound    bool    `json:"compound"`
+	Description string  `json:"description"`
+	Inclusive   bool    `json:"inclusive"`
+	Name        string  `json:"name" validate:"required"`
+	Rate        float64 `json:"rate"`
+	Withholding bool    `json:"withholding"`
+}
+
+type UpdateTaxRateDto struct {
+	Compound    *bool    `json:"compound,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Inclusive   *bool    `json:"inclusive,omitempty"`
+	Name        *string  `json:"name,omitempty"`
+	Rate        *float64 `json:"rate,omitempty"`
+	Withholding *bool    `json:"withholding,omitempty"`
+}
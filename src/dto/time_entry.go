@@ -0,0 +1,54 @@
+package dto
+
+import "time"
+
+type CreateTimeEntryDto struct {
+	Billable    *bool     `json:"billable"`
+	CustomerID  string    `json:"customerId" validate:"required"`
+	Date        time.Time `json:"date"`
+	Description string    `json:"description"`
+	Duration    int       `json:"duration"`
+	HourlyRate  float64   `json:"hourlyRate"`
+	Project     string    `json:"project"`
+	Task        string    `json:"task"`
+}
+
+type UpdateTimeEntryDto struct {
+	Billable    *bool      `json:"billable,omitempty"`
+	CustomerID  *string    `json:"customerId,omitempty"`
+	Date        *time.Time `json:"date,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	Duration    *int       `json:"duration,omitempty"`
+	HourlyRate  *float64   `json:"hourlyRate,omitempty"`
+	Project     *string    `json:"project,omitempty"`
+	Task        *string    `json:"task,omitempty"`
+}
+
+type StartTimerDto struct {
+	Billable    *bool   `json:"billable"`
+	CustomerID  string  `json:"customerId" validate:"required"`
+	Description string  `json:"description"`
+	HourlyRate  float64 `json:"hourlyRate"`
+	Project     string  `json:"project"`
+	Task        string  `json:"task"`
+}
+
+type TimeEntryFilter struct {
+	Billed     *bool      `form:"billed"`
+	CustomerID string     `form:"customerId"`
+	From       *time.Time `form:"from" time_format:"2006-01-02"`
+	Project    string     `form:"project"`
+	To         *time.Time `form:"to" time_format:"2006-01-02"`
+}
+
+// InvoiceTimeDto asks for a draft invoice from a customer's unbilled time
+// between From and To inclusive. GroupBy is day, project or task.
+type InvoiceTimeDto struct {
+	Currency   string    `json:"currency"`
+	CustomerID string    `json:"customerId" validate:"required"`
+	From       time.Time `json:"from"`
+	GroupBy    string    `json:"groupBy"`
+	Note       string    `json:"note"`
+	Title      string    `json:"title"`
+	To         time.Time `json:"to"`
+}
@@ -1,6 +1,7 @@
 package dto
 
 type UpdateUserDto struct {
+	Address         *AddressDto               `json:"address,omitempty"`
 	BankInformation *UpdateBankInformationDto `json:"bankInformation,omitempty"`
 	CompanyLogo     *string                   `json:"companyLogo,omitempty"`
 	CompanyName     *string                   `json:"companyName,omitempty"`
@@ -0,0 +1,21 @@
+package dto
+
+type CreateWebhookDto struct {
+	Active      *bool    `json:"active"`
+	Description string   `json:"description"`
+	Events      []string `json:"events" validate:"required"`
+	URL         string   `json:"url" validate:"required,url"`
+}
+
+type UpdateWebhookDto struct {
+	Active       *bool    `json:"active,omitempty"`
+	Description  *string  `json:"description,omitempty"`
+	Events       []string `json:"events,omitempty"`
+	RotateSecret bool     `json:"rotateSecret,omitempty"`
+	URL          *string  `json:"url,omitempty"`
+}
+
+type WebhookDeliveryParams struct {
+	Limit  int    `form:"limit"`
+	Status string `form:"status"`
+}
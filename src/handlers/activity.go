@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"invoicer-go/m/src/config"
+	"invoicer-go/m/src/database"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ActivityHandler struct {
+	service *services.ActivityService
+}
+
+func NewActivityHandler() *ActivityHandler {
+	return &ActivityHandler{
+		service: services.NewActivityService(database.GetDatabase()),
+	}
+}
+
+func (h *ActivityHandler) GetActivity() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.Param("id")
+
+		activity, err := h.service.GetActivity(id)
+		if err != nil {
+			handleActivityError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Activity fetched successfully", activity)
+	}
+}
+
+func (h *ActivityHandler) AddComment() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.CommentDto
+		id := ctx.Param("id")
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+		comment, err := h.service.AddComment(id, userId, payload)
+		if err != nil {
+			handleActivityError(ctx, err)
+			return
+		}
+
+		lib.Created(ctx, "Comment added successfully", comment)
+	}
+}
+
+func (h *ActivityHandler) UpdateComment() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.CommentDto
+		id := ctx.Param("id")
+		commentId := ctx.Param("commentId")
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+		comment, err := h.service.UpdateComment(id, commentId, userId, payload)
+		if err != nil {
+			handleActivityError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Comment updated successfully", comment)
+	}
+}
+
+func handleActivityError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvoiceNotFound), errors.Is(err, services.ErrCommentNotFound):
+		lib.NotFound(ctx, err.Error(), "")
+	case errors.Is(err, services.ErrCommentNotAuthor):
+		lib.Forbidden(ctx, err.Error())
+	case errors.Is(err, services.ErrEmptyComment):
+		lib.BadRequest(ctx, err.Error(), "")
+	default:
+		lib.InternalServerError(ctx, err.Error())
+	}
+}
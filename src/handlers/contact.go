@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"invoicer-go/m/src/database"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ContactHandler struct {
+	service *services.ContactService
+}
+
+func NewContactHandler() *ContactHandler {
+	return &ContactHandler{
+		service: services.NewContactService(database.GetDatabase()),
+	}
+}
+
+func (h *ContactHandler) CreateContact() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.CreateContactDto
+		id := ctx.Param("id")
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		contact, err := h.service.CreateContact(id, payload)
+		if err != nil {
+			handleContactError(ctx, err)
+			return
+		}
+
+		lib.Created(ctx, "Contact created successfully", contact)
+	}
+}
+
+func (h *ContactHandler) GetContacts() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.Param("id")
+
+		contacts, err := h.service.GetContacts(id)
+		if err != nil {
+			handleContactError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Contacts fetched successfully", contacts)
+	}
+}
+
+func (h *ContactHandler) GetContact() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.Param("id")
+		contactId := ctx.Param("contactId")
+
+		contact, err := h.service.FindContactById(id, contactId)
+		if err != nil {
+			handleContactError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Contact fetched successfully", contact)
+	}
+}
+
+func (h *ContactHandler) UpdateContact() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.UpdateContactDto
+		id := ctx.Param("id")
+		contactId := ctx.Param("contactId")
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		contact, err := h.service.UpdateContact(id, contactId, payload)
+		if err != nil {
+			handleContactError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Contact updated successfully", contact)
+	}
+}
+
+func (h *ContactHandler) DeleteContact() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.Param("id")
+		contactId := ctx.Param("contactId")
+
+		if err := h.service.DeleteContact(id, contactId); err != nil {
+			handleContactError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Contact deleted successfully", nil)
+	}
+}
+
+func handleContactError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCustomerNotFound), errors.Is(err, services.ErrContactNotFound):
+		lib.NotFound(ctx, err.Error(), "")
+	case errors.Is(err, services.ErrInvalidContactEmail), errors.Is(err, services.ErrInvalidContactName):
+		lib.BadRequest(ctx, err.Error(), "")
+	default:
+		lib.InternalServerError(ctx, err.Error())
+	}
+}
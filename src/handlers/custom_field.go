@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"invoicer-go/m/src/config"
+	"invoicer-go/m/src/database"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CustomFieldHandler struct {
+	service *services.CustomFieldService
+}
+
+func NewCustomFieldHandler() *CustomFieldHandler {
+	return &CustomFieldHandler{
+		service: services.NewCustomFieldService(database.GetDatabase()),
+	}
+}
+
+func (h *CustomFieldHandler) CreateDefinition() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.CreateCustomFieldDto
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		definition, err := h.service.CreateDefinition(userId, payload)
+		if err != nil {
+			handleCustomFieldError(ctx, err)
+			return
+		}
+
+		lib.Created(ctx, "Custom field created successfully", definition)
+	}
+}
+
+func (h *CustomFieldHandler) GetDefinitions() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var params dto.CustomFieldParams
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBindQuery(&params); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		definitions, err := h.service.GetDefinitions(userId, params)
+		if err != nil {
+			handleCustomFieldError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Custom fields fetched successfully", definitions)
+	}
+}
+
+func (h *CustomFieldHandler) UpdateDefinition() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.UpdateCustomFieldDto
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		definition, err := h.service.UpdateDefinition(userId, ctx.Param("id"), payload)
+		if err != nil {
+			handleCustomFieldError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Custom field updated successfully", definition)
+	}
+}
+
+func (h *CustomFieldHandler) DeleteDefinition() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := h.service.DeleteDefinition(userId, ctx.Param("id")); err != nil {
+			handleCustomFieldError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Custom field deleted successfully", nil)
+	}
+}
+
+func handleCustomFieldError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCustomFieldNotFound):
+		lib.NotFound(ctx, err.Error(), "")
+	case errors.Is(err, services.ErrCustomFieldExists):
+		lib.Conflict(ctx, err.Error())
+	case errors.Is(err, services.ErrInvalidCustomFieldEntity), errors.Is(err, services.ErrInvalidCustomFieldType),
+		errors.Is(err, services.ErrInvalidCustomFieldKey), errors.Is(err, services.ErrInvalidCustomFieldName),
+		errors.Is(err, services.ErrCustomFieldOptionsRequired):
+		lib.BadRequest(ctx, err.Error(), "")
+	default:
+		lib.InternalServerError(ctx, err.Error())
+	}
+}
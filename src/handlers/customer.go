@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"errors"
+	"invoicer-go/m/src/config"
 	"invoicer-go/m/src/database"
 	"invoicer-go/m/src/dto"
 	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/models"
 	"invoicer-go/m/src/services"
 
 	"github.com/gin-gonic/gin"
@@ -28,9 +31,11 @@ func (h *CustomerHandler) CreateCustomer() gin.HandlerFunc {
 			return
 		}
 
-		customer, err := h.service.CreateCustomer(payload)
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+		customer, err := h.service.CreateCustomer(userId, payload)
 		if err != nil {
-			lib.InternalServerError(ctx, err.Error())
+			handleCustomerError(ctx, err)
+			return
 		}
 		lib.Success(ctx, "Customer created successfully", customer)
 	}
@@ -46,10 +51,19 @@ func (h *CustomerHandler) UpdateCustomer() gin.HandlerFunc {
 			return
 		}
 
-		customer, err := h.service.UpdateCustomer(id, payload)
+		version, ok := lib.IfMatchVersion(ctx)
+		if !ok {
+			lib.PreconditionRequired(ctx, "Send the customer's ETag in an If-Match header")
+			return
+		}
+
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+		customer, err := h.service.UpdateCustomer(id, userId, version, payload)
 		if err != nil {
-			lib.InternalServerError(ctx, err.Error())
+			handleCustomerError(ctx, err)
+			return
 		}
+		lib.SetETag(ctx, customer.Version)
 		lib.Success(ctx, "Customer updated succesfully", customer)
 	}
 }
@@ -58,11 +72,24 @@ func (h *CustomerHandler) DeleteCustomer() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		id := ctx.Param("id")
 
-		err := h.service.DeleteCustomer(id)
+		if err := h.service.ArchiveCustomer(id); err != nil {
+			handleCustomerError(ctx, err)
+			return
+		}
+		lib.Success(ctx, "Customer archived successfully", nil)
+	}
+}
+
+func (h *CustomerHandler) RestoreCustomer() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.Param("id")
+
+		customer, err := h.service.RestoreCustomer(id)
 		if err != nil {
-			lib.InternalServerError(ctx, err.Error())
+			handleCustomerError(ctx, err)
+			return
 		}
-		lib.Success(ctx, "Customer deleted successfully", nil)
+		lib.Success(ctx, "Customer restored successfully", customer)
 	}
 }
 
@@ -74,10 +101,24 @@ func (h *CustomerHandler) GetCustomers() gin.HandlerFunc {
 			lib.BadRequest(ctx, err.Error(), "400")
 			return
 		}
-
-		customers, err := h.service.GetCustomers(params)
+		params.CustomFields = ctx.QueryMap("cf")
+		params.UserID = ctx.GetString(config.AppConfig.CurrentUserId)
+
+		var customers interface{}
+		var err error
+		if params.UsesCursor() {
+			customers, err = h.service.GetCustomersByCursor(params)
+		} else {
+			customers, err = h.service.GetCustomers(params)
+		}
 		if err != nil {
+			if errors.Is(err, lib.ErrInvalidCursor) || errors.Is(err, services.ErrInvalidTagMatch) ||
+				errors.Is(err, services.ErrInvalidTagId) {
+				lib.BadRequest(ctx, err.Error(), "")
+				return
+			}
 			lib.InternalServerError(ctx, err.Error())
+			return
 		}
 		lib.Success(ctx, "Customers fetched successfully", customers)
 	}
@@ -89,8 +130,64 @@ func (h *CustomerHandler) GetCustomer() gin.HandlerFunc {
 
 		customer, err := h.service.GetCustomer(id)
 		if err != nil {
-			lib.InternalServerError(ctx, err.Error())
+			handleCustomerError(ctx, err)
+			return
 		}
+		lib.SetETag(ctx, customer.Version)
 		lib.Success(ctx, "Customer fetched successfully", customer)
 	}
 }
+
+func (h *CustomerHandler) MergeCustomer() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.MergeCustomerDto
+		id := ctx.Param("id")
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		user := ctx.MustGet(config.AppConfig.CurrentUser).(*models.User)
+		merge, err := h.service.MergeCustomer(id, payload, user)
+		if err != nil {
+			handleCustomerError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Customers merged successfully", merge)
+	}
+}
+
+func (h *CustomerHandler) GetMerges() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.Param("id")
+
+		merges, err := h.service.GetMerges(id)
+		if err != nil {
+			handleCustomerError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Merge history fetched successfully", merges)
+	}
+}
+
+func handleCustomerError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCustomerNotFound), errors.Is(err, services.ErrPaymentTermNotFound):
+		lib.NotFound(ctx, err.Error(), "")
+	case errors.Is(err, services.ErrRecordExists):
+		lib.Conflict(ctx, err.Error())
+	case errors.Is(err, services.ErrVersionConflict):
+		lib.PreconditionFailed(ctx, err.Error())
+	case errors.Is(err, services.ErrInvalidCountryCode), errors.Is(err, services.ErrCustomerArchived),
+		errors.Is(err, services.ErrUnknownCustomField), errors.Is(err, services.ErrInvalidCustomFieldValue),
+		errors.Is(err, services.ErrCustomFieldRequired),
+		errors.Is(err, services.ErrMergeSameCustomer), errors.Is(err, services.ErrCustomerMerged),
+		errors.Is(err, services.ErrCustomerNotArchived):
+		lib.BadRequest(ctx, err.Error(), "")
+	default:
+		lib.InternalServerError(ctx, err.Error())
+	}
+}
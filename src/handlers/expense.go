@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"invoicer-go/m/src/config"
+	"invoicer-go/m/src/database"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExpenseHandler struct {
+	service *services.ExpenseService
+}
+
+func NewExpenseHandler() *ExpenseHandler {
+	return &ExpenseHandler{
+		service: services.NewExpenseService(database.GetDatabase()),
+	}
+}
+
+func (h *ExpenseHandler) CreateExpense() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.CreateExpenseDto
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		expense, err := h.service.CreateExpense(userId, payload)
+		if err != nil {
+			handleExpenseError(ctx, err)
+			return
+		}
+
+		lib.Created(ctx, "Expense created successfully", expense)
+	}
+}
+
+func (h *ExpenseHandler) GetExpenses() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var filter dto.ExpenseFilter
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&filter); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		expenses, err := h.service.GetExpenses(userId, filter)
+		if err != nil {
+			handleExpenseError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Expenses fetched successfully", expenses)
+	}
+}
+
+func (h *ExpenseHandler) GetExpense() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		expense, err := h.service.FindExpenseById(userId, ctx.Param("expenseId"))
+		if err != nil {
+			handleExpenseError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Expense fetched successfully", expense)
+	}
+}
+
+func (h *ExpenseHandler) UpdateExpense() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.UpdateExpenseDto
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		expense, err := h.service.UpdateExpense(userId, ctx.Param("expenseId"), payload)
+		if err != nil {
+			handleExpenseError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Expense updated successfully", expense)
+	}
+}
+
+func (h *ExpenseHandler) DeleteExpense() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := h.service.DeleteExpense(userId, ctx.Param("expenseId")); err != nil {
+			handleExpenseError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Expense deleted successfully", nil)
+	}
+}
+
+func (h *ExpenseHandler) UploadReceipt() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		const bucket = "receipts"
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if _, err := h.service.FindExpenseById(userId, ctx.Param("expenseId")); err != nil {
+			handleExpenseError(ctx, err)
+			return
+		}
+
+		receipt, err := ctx.FormFile("receipt")
+		if err != nil {
+			lib.BadRequest(ctx, "A receipt file is required: "+err.Error(), "400")
+			return
+		}
+		if receipt.Size > int64(config.AppConfig.MaxImageSize) {
+			lib.BadRequest(ctx, "File size exceeds 5MB limit", "400")
+			return
+		}
+
+		url, err := lib.SingleImageUploader(receipt, bucket)
+		if err != nil {
+			lib.BadRequest(ctx, "Failed to upload receipt: "+err.Error(), "400")
+			return
+		}
+
+		expense, err := h.service.SetReceipt(userId, ctx.Param("expenseId"), url)
+		if err != nil {
+			handleExpenseError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Receipt uploaded successfully", expense)
+	}
+}
+
+func (h *ExpenseHandler) BillExpenses() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.BillExpensesDto
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		invoice, err := h.service.BillExpenses(ctx.Param("id"), userId, payload)
+		if err != nil {
+			handleExpenseError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Expenses added to invoice successfully", invoice)
+	}
+}
+
+// handleExpenseError leaves errors from updating the invoice to
+// handleInvoiceError.
+func handleExpenseError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrExpenseNotFound):
+		lib.NotFound(ctx, err.Error(), "")
+	case errors.Is(err, services.ErrExpenseBilled):
+		lib.Conflict(ctx, err.Error())
+	case errors.Is(err, services.ErrInvalidExpense), errors.Is(err, services.ErrInvalidExpenseCategory),
+		errors.Is(err, services.ErrExpenseNeedsCustomer), errors.Is(err, services.ErrExpenseNotBillable),
+		errors.Is(err, services.ErrExpenseCurrency), errors.Is(err, services.ErrNoUnbilledExpenses),
+		errors.Is(err, services.ErrInvoiceAlreadyPaid):
+		lib.BadRequest(ctx, err.Error(), "")
+	default:
+		handleInvoiceError(ctx, err)
+	}
+}
@@ -1,21 +1,29 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"invoicer-go/m/src/config"
 	"invoicer-go/m/src/database"
 	"invoicer-go/m/src/dto"
 	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/models"
 	"invoicer-go/m/src/services"
+	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 type InvoiceHandler struct {
-	service services.InvoiceService
+	service      services.InvoiceService
+	customFields *services.CustomFieldService
 }
 
 func NewInvoiceHandler() *InvoiceHandler {
 	return &InvoiceHandler{
-		service: *services.NewInvoiceService(database.GetDatabase()),
+		service:      *services.NewInvoiceService(database.GetDatabase()),
+		customFields: services.NewCustomFieldService(database.GetDatabase()),
 	}
 }
 
@@ -28,9 +36,11 @@ func (h *InvoiceHandler) CreateInvoice() gin.HandlerFunc {
 			return
 		}
 
-		invoice, err := h.service.CreateInvoice(payload)
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+		invoice, err := h.service.CreateInvoice(userId, payload)
 		if err != nil {
-			lib.InternalServerError(ctx, err.Error())
+			handleInvoiceError(ctx, err)
+			return
 		}
 		lib.Success(ctx, "Invoice created successfully", invoice)
 	}
@@ -46,10 +56,19 @@ func (h *InvoiceHandler) UpdateInvoice() gin.HandlerFunc {
 			return
 		}
 
-		invoice, err := h.service.UpdateInvoice(id, payload)
+		version, ok := lib.IfMatchVersion(ctx)
+		if !ok {
+			lib.PreconditionRequired(ctx, "Send the invoice's ETag in an If-Match header")
+			return
+		}
+
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+		invoice, err := h.service.UpdateInvoice(id, userId, version, payload)
 		if err != nil {
-			lib.InternalServerError(ctx, err.Error())
+			handleInvoiceError(ctx, err)
+			return
 		}
+		lib.SetETag(ctx, invoice.Version)
 		lib.Success(ctx, "Invoice updated succesfully", invoice)
 	}
 }
@@ -57,12 +76,27 @@ func (h *InvoiceHandler) UpdateInvoice() gin.HandlerFunc {
 func (h *InvoiceHandler) DeleteInvoice() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		id := ctx.Param("id")
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := h.service.ArchiveInvoice(id, userId); err != nil {
+			handleInvoiceArchiveError(ctx, err)
+			return
+		}
+		lib.Success(ctx, "Invoice archived successfully", nil)
+	}
+}
+
+func (h *InvoiceHandler) RestoreInvoice() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.Param("id")
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
 
-		err := h.service.DeleteInvoice(id)
+		invoice, err := h.service.RestoreInvoice(id, userId)
 		if err != nil {
-			lib.InternalServerError(ctx, err.Error())
+			handleInvoiceArchiveError(ctx, err)
+			return
 		}
-		lib.Success(ctx, "Customer deleted successfully", nil)
+		lib.Success(ctx, "Invoice restored successfully", invoice)
 	}
 }
 
@@ -74,10 +108,26 @@ func (h *InvoiceHandler) GetInvoices() gin.HandlerFunc {
 			lib.BadRequest(ctx, err.Error(), "400")
 			return
 		}
+		params.CustomFields = ctx.QueryMap("cf")
+		params.UserID = ctx.GetString(config.AppConfig.CurrentUserId)
 
-		invoices, err := h.service.GetInvoices(params)
+		var invoices interface{}
+		var err error
+		if params.UsesCursor() {
+			invoices, err = h.service.GetInvoicesByCursor(params)
+		} else {
+			invoices, err = h.service.GetInvoices(params)
+		}
 		if err != nil {
+			if errors.Is(err, services.ErrInvalidSortField) || errors.Is(err, services.ErrInvalidSortOrder) ||
+				errors.Is(err, services.ErrInvalidInvoiceStatus) || errors.Is(err, lib.ErrInvalidCursor) ||
+				errors.Is(err, services.ErrInvalidTagMatch) || errors.Is(err, services.ErrInvalidTagId) ||
+				errors.Is(err, services.ErrInvalidCustomerId) {
+				lib.BadRequest(ctx, err.Error(), "")
+				return
+			}
 			lib.InternalServerError(ctx, err.Error())
+			return
 		}
 		lib.Success(ctx, "Invoices fetched successfully", invoices)
 	}
@@ -89,8 +139,127 @@ func (h *InvoiceHandler) GetInvoice() gin.HandlerFunc {
 
 		invoice, err := h.service.GetInvoice(id)
 		if err != nil {
-			lib.InternalServerError(ctx, err.Error())
+			handleInvoiceError(ctx, err)
+			return
 		}
+		lib.SetETag(ctx, invoice.Version)
 		lib.Success(ctx, "Invoice fetched successfully", invoice)
 	}
 }
+
+func (h *InvoiceHandler) DownloadInvoicePdf() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.Param("id")
+		facturX := strings.ToLower(ctx.Query("facturx")) == "true"
+		user := ctx.MustGet(config.AppConfig.CurrentUser).(*models.User)
+
+		invoice, err := h.service.GetInvoice(id)
+		if err != nil {
+			if errors.Is(err, services.ErrInvoiceNotFound) {
+				lib.NotFound(ctx, err.Error(), "")
+				return
+			}
+			lib.InternalServerError(ctx, err.Error())
+			return
+		}
+
+		fields, err := h.customFields.PdfFields(user.ID.String())
+		if err != nil {
+			lib.InternalServerError(ctx, err.Error())
+			return
+		}
+
+		content, err := services.RenderInvoicePdf(invoice, user, fields, facturX)
+		if err != nil {
+			if errors.Is(err, services.ErrFacturXCountryRequired) || errors.Is(err, services.ErrFacturXUnsupportedTax) {
+				lib.BadRequest(ctx, err.Error(), "")
+				return
+			}
+			lib.InternalServerError(ctx, err.Error())
+			return
+		}
+
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", invoice.ReferenceNo+".pdf"))
+		ctx.Data(http.StatusOK, "application/pdf", content)
+	}
+}
+
+func (h *InvoiceHandler) SendInvoice() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.Param("id")
+		user := ctx.MustGet(config.AppConfig.CurrentUser).(*models.User)
+
+		if err := h.service.SendInvoice(id, user); err != nil {
+			handleInvoiceEmailError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Invoice sent successfully", nil)
+	}
+}
+
+func (h *InvoiceHandler) SendReminder() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.Param("id")
+		user := ctx.MustGet(config.AppConfig.CurrentUser).(*models.User)
+
+		if err := h.service.SendReminder(id, user); err != nil {
+			handleInvoiceEmailError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Reminder sent successfully", nil)
+	}
+}
+
+func handleInvoiceEmailError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvoiceNotFound):
+		lib.NotFound(ctx, err.Error(), "")
+	case errors.Is(err, services.ErrInvoiceIsDraft), errors.Is(err, services.ErrInvoiceAlreadyPaid),
+		errors.Is(err, services.ErrInvoiceArchived):
+		lib.BadRequest(ctx, err.Error(), "")
+	default:
+		lib.InternalServerError(ctx, err.Error())
+	}
+}
+
+func handleInvoiceArchiveError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvoiceNotFound):
+		lib.NotFound(ctx, err.Error(), "")
+	case errors.Is(err, services.ErrExpenseRebilled), errors.Is(err, services.ErrTimeEntryRebilled):
+		lib.Conflict(ctx, err.Error())
+	case errors.Is(err, services.ErrInvoiceArchived), errors.Is(err, services.ErrInvoiceNotArchived),
+		errors.Is(err, services.ErrCustomerArchived):
+		lib.BadRequest(ctx, err.Error(), "")
+	default:
+		lib.InternalServerError(ctx, err.Error())
+	}
+}
+
+func handleInvoiceError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvoiceNotFound), errors.Is(err, services.ErrCustomerNotFound),
+		errors.Is(err, services.ErrPaymentTermNotFound):
+		lib.NotFound(ctx, err.Error(), "")
+	case errors.Is(err, services.ErrInvoiceTitleExists):
+		lib.Conflict(ctx, err.Error())
+	case errors.Is(err, services.ErrVersionConflict):
+		lib.PreconditionFailed(ctx, err.Error())
+	case errors.Is(err, services.ErrInvoiceArchived), errors.Is(err, services.ErrCustomerArchived),
+		errors.Is(err, services.ErrUnknownCustomField), errors.Is(err, services.ErrInvalidCustomFieldValue),
+		errors.Is(err, services.ErrCustomFieldRequired),
+		errors.Is(err, services.ErrInvalidItemDiscount), errors.Is(err, services.ErrInvoiceTaxWithItems),
+		errors.Is(err, services.ErrInvalidTaxRate),
+		errors.Is(err, services.ErrInvalidQuantity), errors.Is(err, services.ErrInvalidUnit),
+		errors.Is(err, services.ErrInvalidTaxRateKind), errors.Is(err, services.ErrTaxRateNotFound),
+		errors.Is(err, services.ErrInvalidInstallment), errors.Is(err, services.ErrInvalidInstallmentPct),
+		errors.Is(err, services.ErrInstallmentDueDate), errors.Is(err, services.ErrInstallmentsMismatch),
+		errors.Is(err, services.ErrInvalidPaymentTerm), errors.Is(err, services.ErrExpenseNotBillable),
+		errors.Is(err, services.ErrTimeEntryNotBilled):
+		lib.BadRequest(ctx, err.Error(), "")
+	default:
+		lib.InternalServerError(ctx, err.Error())
+	}
+}
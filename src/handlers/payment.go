@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"invoicer-go/m/src/config"
+	"invoicer-go/m/src/database"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PaymentHandler struct {
+	service *services.PaymentService
+}
+
+func NewPaymentHandler() *PaymentHandler {
+	return &PaymentHandler{
+		service: services.NewPaymentService(database.GetDatabase()),
+	}
+}
+
+func (h *PaymentHandler) RecordPayment() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.CreatePaymentDto
+		id := ctx.Param("id")
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+		payment, err := h.service.RecordPayment(id, userId, payload)
+		if err != nil {
+			handlePaymentError(ctx, err)
+			return
+		}
+
+		lib.Created(ctx, "Payment recorded successfully", payment)
+	}
+}
+
+func (h *PaymentHandler) GetPayments() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.Param("id")
+
+		payments, err := h.service.GetPayments(id)
+		if err != nil {
+			handlePaymentError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Payments fetched successfully", payments)
+	}
+}
+
+func (h *PaymentHandler) DeletePayment() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.Param("id")
+		paymentId := ctx.Param("paymentId")
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := h.service.DeletePayment(id, paymentId, userId); err != nil {
+			handlePaymentError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Payment deleted successfully", nil)
+	}
+}
+
+func handlePaymentError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvoiceNotFound), errors.Is(err, services.ErrPaymentNotFound):
+		lib.NotFound(ctx, err.Error(), "")
+	case errors.Is(err, services.ErrInvalidPaymentAmount), errors.Is(err, services.ErrInvalidPaymentKind),
+		errors.Is(err, services.ErrPaymentExceedsBalance), errors.Is(err, services.ErrInvoiceArchived):
+		lib.BadRequest(ctx, err.Error(), "")
+	default:
+		lib.InternalServerError(ctx, err.Error())
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"invoicer-go/m/src/config"
+	"invoicer-go/m/src/database"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PaymentTermHandler struct {
+	service *services.PaymentTermService
+}
+
+func NewPaymentTermHandler() *PaymentTermHandler {
+	return &PaymentTermHandler{
+		service: services.NewPaymentTermService(database.GetDatabase()),
+	}
+}
+
+func (h *PaymentTermHandler) CreatePaymentTerm() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.CreatePaymentTermDto
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		term, err := h.service.CreatePaymentTerm(userId, payload)
+		if err != nil {
+			handlePaymentTermError(ctx, err)
+			return
+		}
+
+		lib.Created(ctx, "Payment term created successfully", term)
+	}
+}
+
+func (h *PaymentTermHandler) GetPaymentTerms() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		terms, err := h.service.GetPaymentTerms(userId)
+		if err != nil {
+			handlePaymentTermError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Payment terms fetched successfully", terms)
+	}
+}
+
+func (h *PaymentTermHandler) GetPaymentTerm() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		term, err := h.service.FindPaymentTermById(userId, ctx.Param("paymentTermId"))
+		if err != nil {
+			handlePaymentTermError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Payment term fetched successfully", term)
+	}
+}
+
+func (h *PaymentTermHandler) UpdatePaymentTerm() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.UpdatePaymentTermDto
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		term, err := h.service.UpdatePaymentTerm(userId, ctx.Param("paymentTermId"), payload)
+		if err != nil {
+			handlePaymentTermError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Payment term updated successfully", term)
+	}
+}
+
+func (h *PaymentTermHandler) DeletePaymentTerm() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := h.service.DeletePaymentTerm(userId, ctx.Param("paymentTermId")); err != nil {
+			handlePaymentTermError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Payment term deleted successfully", nil)
+	}
+}
+
+func handlePaymentTermError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPaymentTermNotFound):
+		lib.NotFound(ctx, err.Error(), "")
+	case errors.Is(err, services.ErrPaymentTermExists):
+		lib.Conflict(ctx, err.Error())
+	case errors.Is(err, services.ErrInvalidPaymentTerm), errors.Is(err, services.ErrInvalidPaymentTermDays):
+		lib.BadRequest(ctx, err.Error(), "")
+	default:
+		lib.InternalServerError(ctx, err.Error())
+	}
+}
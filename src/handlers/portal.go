@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"invoicer-go/m/src/config"
+	"invoicer-go/m/src/database"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/models"
+	"invoicer-go/m/src/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PortalHandler struct {
+	service      *services.PortalService
+	customFields *services.CustomFieldService
+	statements   *services.StatementService
+}
+
+func NewPortalHandler() *PortalHandler {
+	db := database.GetDatabase()
+	return &PortalHandler{
+		service:      services.NewPortalService(db),
+		customFields: services.NewCustomFieldService(db),
+		statements:   services.NewStatementService(db),
+	}
+}
+
+func (h *PortalHandler) InviteCustomer() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.Param("id")
+		user := ctx.MustGet(config.AppConfig.CurrentUser).(*models.User)
+
+		if err := h.service.InviteCustomer(id, user); err != nil {
+			handlePortalError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Portal invitation sent successfully", nil)
+	}
+}
+
+func (h *PortalHandler) RequestLoginLink() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.PortalLoginDto
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		if err := h.service.RequestLoginLink(payload.Email); err != nil {
+			handlePortalError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "If this email has portal access, a login link is on its way", nil)
+	}
+}
+
+func (h *PortalHandler) Verify() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.PortalVerifyDto
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		session, err := h.service.Verify(payload.Token)
+		if err != nil {
+			handlePortalError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Signed in successfully", session)
+	}
+}
+
+func (h *PortalHandler) GetProfile() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		customer := ctx.MustGet(config.AppConfig.CurrentCustomer).(*models.Customer)
+		issuer := ctx.MustGet(config.AppConfig.CurrentIssuer).(*models.User)
+
+		profile, err := h.service.GetProfile(customer, issuer)
+		if err != nil {
+			handlePortalError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Profile fetched successfully", profile)
+	}
+}
+
+func (h *PortalHandler) GetInvoices() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var params dto.InvoicePagination
+		customerId := ctx.GetString(config.AppConfig.CurrentCustomerId)
+
+		if err := ctx.ShouldBindQuery(&params); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		invoices, err := h.service.GetInvoices(customerId, params)
+		if err != nil {
+			handlePortalError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Invoices fetched successfully", invoices)
+	}
+}
+
+func (h *PortalHandler) GetInvoice() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		customerId := ctx.GetString(config.AppConfig.CurrentCustomerId)
+
+		invoice, err := h.service.GetInvoice(customerId, ctx.Param("invoiceId"))
+		if err != nil {
+			handlePortalError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Invoice fetched successfully", invoice)
+	}
+}
+
+func (h *PortalHandler) DownloadInvoicePdf() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		customerId := ctx.GetString(config.AppConfig.CurrentCustomerId)
+		issuer := ctx.MustGet(config.AppConfig.CurrentIssuer).(*models.User)
+
+		invoice, err := h.service.GetInvoice(customerId, ctx.Param("invoiceId"))
+		if err != nil {
+			handlePortalError(ctx, err)
+			return
+		}
+
+		fields, err := h.customFields.PdfFields(issuer.ID.String())
+		if err != nil {
+			lib.InternalServerError(ctx, err.Error())
+			return
+		}
+
+		content, err := services.RenderInvoicePdf(invoice, issuer, fields, false)
+		if err != nil {
+			lib.InternalServerError(ctx, err.Error())
+			return
+		}
+
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", invoice.ReferenceNo+".pdf"))
+		ctx.Data(http.StatusOK, "application/pdf", content)
+	}
+}
+
+func (h *PortalHandler) GetStatement() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var params dto.StatementParams
+		customerId := ctx.GetString(config.AppConfig.CurrentCustomerId)
+
+		if err := ctx.ShouldBindQuery(&params); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		statement, err := h.statements.GetStatement(customerId, params)
+		if err != nil {
+			handlePortalError(ctx, err)
+			return
+		}
+
+		if strings.EqualFold(params.Format, "pdf") {
+			issuer := ctx.MustGet(config.AppConfig.CurrentIssuer).(*models.User)
+			content, err := services.RenderStatementPdf(statement, issuer)
+			if err != nil {
+				lib.InternalServerError(ctx, err.Error())
+				return
+			}
+
+			ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", services.StatementFilename(statement)))
+			ctx.Data(http.StatusOK, "application/pdf", content)
+			return
+		}
+
+		lib.Success(ctx, "Statement fetched successfully", statement)
+	}
+}
+
+func handlePortalError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCustomerNotFound), errors.Is(err, services.ErrInvoiceNotFound):
+		lib.NotFound(ctx, err.Error(), "")
+	case errors.Is(err, services.ErrInvalidPortalLink):
+		lib.Unauthorized(ctx, err.Error())
+	case errors.Is(err, services.ErrInvalidInvoiceStatus), errors.Is(err, services.ErrInvalidSortField),
+		errors.Is(err, services.ErrInvalidSortOrder), errors.Is(err, services.ErrInvalidReportRange),
+		errors.Is(err, lib.ErrInvalidCursor), errors.Is(err, services.ErrInvalidTagMatch),
+		errors.Is(err, services.ErrInvalidTagId), errors.Is(err, services.ErrInvalidCustomerId):
+		lib.BadRequest(ctx, err.Error(), "")
+	default:
+		lib.InternalServerError(ctx, err.Error())
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"invoicer-go/m/src/config"
+	"invoicer-go/m/src/database"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/services"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportHandler struct {
+	service *services.ReportService
+}
+
+func NewReportHandler() *ReportHandler {
+	return &ReportHandler{
+		service: services.NewReportService(database.GetDatabase()),
+	}
+}
+
+func (h *ReportHandler) GetAgingReport() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var params dto.AgingReportParams
+
+		if err := ctx.ShouldBindQuery(&params); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		report, err := h.service.GetAgingReport(params)
+		if err != nil {
+			lib.InternalServerError(ctx, err.Error())
+			return
+		}
+
+		if strings.EqualFold(params.Format, "csv") {
+			lib.CSV(ctx, "aging-"+report.AsOf.Format("2006-01-02")+".csv", agingReportRecords(report))
+			return
+		}
+
+		lib.Success(ctx, "Aging report fetched successfully", report)
+	}
+}
+
+func (h *ReportHandler) GetSummaryReport() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var params dto.SummaryReportParams
+
+		if err := ctx.ShouldBindQuery(&params); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		report, err := h.service.GetSummaryReport(params)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidReportPeriod) || errors.Is(err, services.ErrInvalidReportRange) {
+				lib.BadRequest(ctx, err.Error(), "")
+				return
+			}
+			lib.InternalServerError(ctx, err.Error())
+			return
+		}
+
+		lib.Success(ctx, "Summary report fetched successfully", report)
+	}
+}
+
+func (h *ReportHandler) GetTagReport() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var params dto.TagReportParams
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBindQuery(&params); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		report, err := h.service.GetTagReport(userId, params)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidTagSource) || errors.Is(err, services.ErrInvalidReportRange) {
+				lib.BadRequest(ctx, err.Error(), "")
+				return
+			}
+			lib.InternalServerError(ctx, err.Error())
+			return
+		}
+
+		lib.Success(ctx, "Tag report fetched successfully", report)
+	}
+}
+
+func agingReportRecords(report *dto.AgingReport) [][]string {
+	records := [][]string{{
+		"Customer ID", "Customer", "Invoices", "Current", "1-30", "31-60", "61-90", "90+", "Total (" + report.Currency + ")",
+	}}
+
+	for _, row := range report.Customers {
+		records = append(records, append(
+			[]string{row.CustomerID, row.CustomerName, strconv.Itoa(row.InvoiceCount)},
+			agingBucketCells(row.AgingBuckets)...,
+		))
+	}
+
+	return append(records, append([]string{"", "Total", ""}, agingBucketCells(report.Totals)...))
+}
+
+func agingBucketCells(buckets dto.AgingBuckets) []string {
+	cells := []float64{buckets.Current, buckets.Days1To30, buckets.Days31To60, buckets.Days61To90, buckets.Over90, buckets.Total}
+	values := make([]string, len(cells))
+	for i, cell := range cells {
+		values[i] = strconv.FormatFloat(cell, 'f', 2, 64)
+	}
+	return values
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"errors"
+	"invoicer-go/m/src/database"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SearchHandler struct {
+	service *services.SearchService
+}
+
+func NewSearchHandler() *SearchHandler {
+	return &SearchHandler{
+		service: services.NewSearchService(database.GetDatabase()),
+	}
+}
+
+func (h *SearchHandler) Search() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var params dto.SearchParams
+
+		if err := ctx.ShouldBindQuery(&params); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		results, err := h.service.Search(params)
+		if err != nil {
+			if errors.Is(err, services.ErrEmptySearchQuery) || errors.Is(err, services.ErrInvalidSearchType) {
+				lib.BadRequest(ctx, err.Error(), "")
+				return
+			}
+			lib.InternalServerError(ctx, err.Error())
+			return
+		}
+
+		lib.Success(ctx, "Search results fetched successfully", results)
+	}
+}
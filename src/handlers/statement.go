@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"invoicer-go/m/src/config"
+	"invoicer-go/m/src/database"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/models"
+	"invoicer-go/m/src/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StatementHandler struct {
+	service *services.StatementService
+}
+
+func NewStatementHandler() *StatementHandler {
+	return &StatementHandler{
+		service: services.NewStatementService(database.GetDatabase()),
+	}
+}
+
+func (h *StatementHandler) GetStatement() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var params dto.StatementParams
+		id := ctx.Param("id")
+
+		if err := ctx.ShouldBindQuery(&params); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		statement, err := h.service.GetStatement(id, params)
+		if err != nil {
+			handleStatementError(ctx, err)
+			return
+		}
+
+		if strings.EqualFold(params.Format, "pdf") {
+			user := ctx.MustGet(config.AppConfig.CurrentUser).(*models.User)
+			content, err := services.RenderStatementPdf(statement, user)
+			if err != nil {
+				lib.InternalServerError(ctx, err.Error())
+				return
+			}
+
+			ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", services.StatementFilename(statement)))
+			ctx.Data(http.StatusOK, "application/pdf", content)
+			return
+		}
+
+		lib.Success(ctx, "Statement fetched successfully", statement)
+	}
+}
+
+func (h *StatementHandler) SendStatement() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var params dto.StatementParams
+		id := ctx.Param("id")
+
+		if err := ctx.ShouldBindQuery(&params); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		user := ctx.MustGet(config.AppConfig.CurrentUser).(*models.User)
+		if err := h.service.SendStatement(id, params, user); err != nil {
+			handleStatementError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Statement sent successfully", nil)
+	}
+}
+
+func handleStatementError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCustomerNotFound):
+		lib.NotFound(ctx, err.Error(), "")
+	case errors.Is(err, services.ErrInvalidReportRange):
+		lib.BadRequest(ctx, err.Error(), "")
+	default:
+		lib.InternalServerError(ctx, err.Error())
+	}
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"errors"
+	"invoicer-go/m/src/config"
+	"invoicer-go/m/src/database"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TagHandler struct {
+	service *services.TagService
+}
+
+func NewTagHandler() *TagHandler {
+	return &TagHandler{
+		service: services.NewTagService(database.GetDatabase()),
+	}
+}
+
+func (h *TagHandler) CreateTag() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.CreateTagDto
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		tag, err := h.service.CreateTag(userId, payload)
+		if err != nil {
+			handleTagError(ctx, err)
+			return
+		}
+
+		lib.Created(ctx, "Tag created successfully", tag)
+	}
+}
+
+func (h *TagHandler) GetTags() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		tags, err := h.service.GetTags(userId)
+		if err != nil {
+			handleTagError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Tags fetched successfully", tags)
+	}
+}
+
+func (h *TagHandler) UpdateTag() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.UpdateTagDto
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		tag, err := h.service.UpdateTag(userId, ctx.Param("tagId"), payload)
+		if err != nil {
+			handleTagError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Tag updated successfully", tag)
+	}
+}
+
+func (h *TagHandler) DeleteTag() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := h.service.DeleteTag(userId, ctx.Param("tagId")); err != nil {
+			handleTagError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Tag deleted successfully", nil)
+	}
+}
+
+func (h *TagHandler) AddInvoiceTags() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.TagIdsDto
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		tags, err := h.service.AddInvoiceTags(userId, ctx.Param("id"), payload)
+		if err != nil {
+			handleTagError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Tags added successfully", tags)
+	}
+}
+
+func (h *TagHandler) RemoveInvoiceTag() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		tags, err := h.service.RemoveInvoiceTag(userId, ctx.Param("id"), ctx.Param("tagId"))
+		if err != nil {
+			handleTagError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Tag removed successfully", tags)
+	}
+}
+
+func (h *TagHandler) AddCustomerTags() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.TagIdsDto
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		tags, err := h.service.AddCustomerTags(userId, ctx.Param("id"), payload)
+		if err != nil {
+			handleTagError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Tags added successfully", tags)
+	}
+}
+
+func (h *TagHandler) RemoveCustomerTag() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		tags, err := h.service.RemoveCustomerTag(userId, ctx.Param("id"), ctx.Param("tagId"))
+		if err != nil {
+			handleTagError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Tag removed successfully", tags)
+	}
+}
+
+func handleTagError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTagNotFound), errors.Is(err, services.ErrInvoiceNotFound),
+		errors.Is(err, services.ErrCustomerNotFound):
+		lib.NotFound(ctx, err.Error(), "")
+	case errors.Is(err, services.ErrTagExists):
+		lib.Conflict(ctx, err.Error())
+	case errors.Is(err, services.ErrInvalidTagName), errors.Is(err, services.ErrInvalidTagColor):
+		lib.BadRequest(ctx, err.Error(), "")
+	default:
+		lib.InternalServerError(ctx, err.Error())
+	}
+}
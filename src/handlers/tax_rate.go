@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"invoicer-go/m/src/config"
+	"invoicer-go/m/src/database"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TaxRateHandler struct {
+	service *services.TaxRateService
+}
+
+func NewTaxRateHandler() *TaxRateHandler {
+	return &TaxRateHandler{
+		service: services.NewTaxRateService(database.GetDatabase()),
+	}
+}
+
+func (h *TaxRateHandler) CreateTaxRate() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.CreateTaxRateDto
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		rate, err := h.service.CreateTaxRate(userId, payload)
+		if err != nil {
+			handleTaxRateError(ctx, err)
+			return
+		}
+
+		lib.Created(ctx, "Tax rate created successfully", rate)
+	}
+}
+
+func (h *TaxRateHandler) GetTaxRates() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		rates, err := h.service.GetTaxRates(userId)
+		if err != nil {
+			handleTaxRateError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Tax rates fetched successfully", rates)
+	}
+}
+
+func (h *TaxRateHandler) GetTaxRate() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		rate, err := h.service.FindTaxRateById(userId, ctx.Param("taxRateId"))
+		if err != nil {
+			handleTaxRateError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Tax rate fetched successfully", rate)
+	}
+}
+
+func (h *TaxRateHandler) UpdateTaxRate() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.UpdateTaxRateDto
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		rate, err := h.service.UpdateTaxRate(userId, ctx.Param("taxRateId"), payload)
+		if err != nil {
+			handleTaxRateError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Tax rate updated successfully", rate)
+	}
+}
+
+func (h *TaxRateHandler) DeleteTaxRate() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := h.service.DeleteTaxRate(userId, ctx.Param("taxRateId")); err != nil {
+			handleTaxRateError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Tax rate deleted successfully", nil)
+	}
+}
+
+func handleTaxRateError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTaxRateNotFound):
+		lib.NotFound(ctx, err.Error(), "")
+	case errors.Is(err, services.ErrTaxRateExists):
+		lib.Conflict(ctx, err.Error())
+	case errors.Is(err, services.ErrInvalidTaxRate), errors.Is(err, services.ErrInvalidTaxRateKind):
+		lib.BadRequest(ctx, err.Error(), "")
+	default:
+		lib.InternalServerError(ctx, err.Error())
+	}
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"errors"
+	"invoicer-go/m/src/config"
+	"invoicer-go/m/src/database"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TimeEntryHandler struct {
+	service *services.TimeEntryService
+}
+
+func NewTimeEntryHandler() *TimeEntryHandler {
+	return &TimeEntryHandler{
+		service: services.NewTimeEntryService(database.GetDatabase()),
+	}
+}
+
+func (h *TimeEntryHandler) CreateTimeEntry() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.CreateTimeEntryDto
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		entry, err := h.service.CreateTimeEntry(userId, payload)
+		if err != nil {
+			handleTimeEntryError(ctx, err)
+			return
+		}
+
+		lib.Created(ctx, "Time entry created successfully", entry)
+	}
+}
+
+func (h *TimeEntryHandler) GetTimeEntries() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var filter dto.TimeEntryFilter
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&filter); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		entries, err := h.service.GetTimeEntries(userId, filter)
+		if err != nil {
+			handleTimeEntryError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Time entries fetched successfully", entries)
+	}
+}
+
+func (h *TimeEntryHandler) GetTimeEntry() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		entry, err := h.service.FindTimeEntryById(userId, ctx.Param("timeEntryId"))
+		if err != nil {
+			handleTimeEntryError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Time entry fetched successfully", entry)
+	}
+}
+
+func (h *TimeEntryHandler) UpdateTimeEntry() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.UpdateTimeEntryDto
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		entry, err := h.service.UpdateTimeEntry(userId, ctx.Param("timeEntryId"), payload)
+		if err != nil {
+			handleTimeEntryError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Time entry updated successfully", entry)
+	}
+}
+
+func (h *TimeEntryHandler) DeleteTimeEntry() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := h.service.DeleteTimeEntry(userId, ctx.Param("timeEntryId")); err != nil {
+			handleTimeEntryError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Time entry deleted successfully", nil)
+	}
+}
+
+func (h *TimeEntryHandler) GetTimer() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		entry, err := h.service.GetTimer(userId)
+		if err != nil {
+			handleTimeEntryError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Timer fetched successfully", entry)
+	}
+}
+
+func (h *TimeEntryHandler) StartTimer() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.StartTimerDto
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		entry, err := h.service.StartTimer(userId, payload)
+		if err != nil {
+			handleTimeEntryError(ctx, err)
+			return
+		}
+
+		lib.Created(ctx, "Timer started successfully", entry)
+	}
+}
+
+func (h *TimeEntryHandler) StopTimer() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		entry, err := h.service.StopTimer(userId)
+		if err != nil {
+			handleTimeEntryError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Timer stopped successfully", entry)
+	}
+}
+
+func (h *TimeEntryHandler) InvoiceTime() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.InvoiceTimeDto
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		invoice, err := h.service.InvoiceTime(userId, payload)
+		if err != nil {
+			handleTimeEntryError(ctx, err)
+			return
+		}
+
+		lib.Created(ctx, "Invoice created from time entries successfully", invoice)
+	}
+}
+
+// handleTimeEntryError leaves errors from building the invoice to
+// handleInvoiceError.
+func handleTimeEntryError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTimeEntryNotFound), errors.Is(err, services.ErrNoTimerRunning):
+		lib.NotFound(ctx, err.Error(), "")
+	case errors.Is(err, services.ErrTimerRunning), errors.Is(err, services.ErrTimeEntryBilled):
+		lib.Conflict(ctx, err.Error())
+	case errors.Is(err, services.ErrInvalidTimeEntry), errors.Is(err, services.ErrInvalidTimeGrouping),
+		errors.Is(err, services.ErrNoUnbilledTime), errors.Is(err, services.ErrInvalidReportRange):
+		lib.BadRequest(ctx, err.Error(), "")
+	default:
+		handleInvoiceError(ctx, err)
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"invoicer-go/m/src/config"
 	"invoicer-go/m/src/database"
 	"invoicer-go/m/src/dto"
@@ -52,6 +53,12 @@ func (h *UserHandler) UpdateUser() gin.HandlerFunc {
 			return
 		}
 
+		version, ok := lib.IfMatchVersion(ctx)
+		if !ok {
+			lib.PreconditionRequired(ctx, "Send the profile's ETag in an If-Match header")
+			return
+		}
+
 		var companyLogoPtr *string
 		if len(form.File["companyLogo"]) > 0 {
 			image := form.File["companyLogo"][0]
@@ -76,6 +83,8 @@ func (h *UserHandler) UpdateUser() gin.HandlerFunc {
 			TaxId:       getFormValue(form, "taxId"),
 		}
 
+		payload.Address = extractAddress(form)
+
 		bankInfo := extractBankInformation(form)
 		if bankInfo != nil {
 			payload.BankInformation = bankInfo
@@ -86,12 +95,13 @@ func (h *UserHandler) UpdateUser() gin.HandlerFunc {
 			return
 		}
 
-		user, err := h.service.UpdateUser(id, *payload)
+		user, err := h.service.UpdateUser(id, version, *payload)
 		if err != nil {
-			lib.InternalServerError(ctx, err.Error())
+			handleUserError(ctx, err)
 			return
 		}
 
+		lib.SetETag(ctx, user.Version)
 		lib.Success(ctx, "Profile updated successfully", user)
 	}
 }
@@ -134,6 +144,7 @@ func (h *UserHandler) GetUser() gin.HandlerFunc {
 			return
 		}
 
+		lib.SetETag(ctx, user.Version)
 		lib.Success(ctx, "Profile fetched successfully", user)
 	}
 }
@@ -169,10 +180,40 @@ func extractBankInformation(form *multipart.Form) *dto.UpdateBankInformationDto
 	return nil
 }
 
+func extractAddress(form *multipart.Form) *dto.AddressDto {
+	line1 := getFormValue(form, "address[line1]")
+	line2 := getFormValue(form, "address[line2]")
+	city := getFormValue(form, "address[city]")
+	state := getFormValue(form, "address[state]")
+	postalCode := getFormValue(form, "address[postalCode]")
+	country := getFormValue(form, "address[country]")
+
+	if line1 == nil && line2 == nil && city == nil && state == nil && postalCode == nil && country == nil {
+		return nil
+	}
+
+	return &dto.AddressDto{
+		City:       formValueOrEmpty(city),
+		Country:    formValueOrEmpty(country),
+		Line1:      formValueOrEmpty(line1),
+		Line2:      formValueOrEmpty(line2),
+		PostalCode: formValueOrEmpty(postalCode),
+		State:      formValueOrEmpty(state),
+	}
+}
+
+func formValueOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
 func hasUpdateFields(payload *dto.UpdateUserDto) bool {
 	return payload.Name != nil || payload.Email != nil || payload.Phone != nil ||
 		payload.RcNumber != nil || payload.CompanyLogo != nil || payload.CompanyName != nil ||
-		payload.Website != nil || payload.TaxId != nil || payload.BankInformation != nil
+		payload.Website != nil || payload.TaxId != nil || payload.BankInformation != nil ||
+		payload.Address != nil
 }
 
 func (h *UserHandler) UpdateUserProfile() gin.HandlerFunc {
@@ -195,12 +236,30 @@ func (h *UserHandler) UpdateUserProfile() gin.HandlerFunc {
 			return
 		}
 
-		user, err := h.service.UpdateUser(id, payload)
+		version, ok := lib.IfMatchVersion(ctx)
+		if !ok {
+			lib.PreconditionRequired(ctx, "Send the profile's ETag in an If-Match header")
+			return
+		}
+
+		user, err := h.service.UpdateUser(id, version, payload)
 		if err != nil {
-			lib.InternalServerError(ctx, err.Error())
+			handleUserError(ctx, err)
 			return
 		}
 
+		lib.SetETag(ctx, user.Version)
 		lib.Success(ctx, "Profile updated successfully", user)
 	}
 }
+
+func handleUserError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrVersionConflict):
+		lib.PreconditionFailed(ctx, err.Error())
+	case errors.Is(err, services.ErrInvalidCountryCode):
+		lib.BadRequest(ctx, err.Error(), "")
+	default:
+		lib.InternalServerError(ctx, err.Error())
+	}
+}
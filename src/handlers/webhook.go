@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"invoicer-go/m/src/config"
+	"invoicer-go/m/src/database"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/models"
+	"invoicer-go/m/src/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	service *services.WebhookService
+}
+
+func NewWebhookHandler() *WebhookHandler {
+	return &WebhookHandler{
+		service: services.NewWebhookService(database.GetDatabase()),
+	}
+}
+
+func (h *WebhookHandler) CreateWebhook() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.CreateWebhookDto
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		endpoint, err := h.service.CreateWebhook(userId, payload)
+		if err != nil {
+			handleWebhookError(ctx, err)
+			return
+		}
+
+		lib.Created(ctx, "Webhook created successfully", endpoint)
+	}
+}
+
+func (h *WebhookHandler) GetWebhooks() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		endpoints, err := h.service.GetWebhooks(userId)
+		if err != nil {
+			handleWebhookError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Webhooks fetched successfully", endpoints)
+	}
+}
+
+func (h *WebhookHandler) GetWebhook() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		endpoint, err := h.service.FindWebhookById(userId, ctx.Param("webhookId"))
+		if err != nil {
+			handleWebhookError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Webhook fetched successfully", endpoint)
+	}
+}
+
+func (h *WebhookHandler) UpdateWebhook() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload dto.UpdateWebhookDto
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&payload); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		endpoint, err := h.service.UpdateWebhook(userId, ctx.Param("webhookId"), payload)
+		if err != nil {
+			handleWebhookError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Webhook updated successfully", endpoint)
+	}
+}
+
+func (h *WebhookHandler) DeleteWebhook() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := h.service.DeleteWebhook(userId, ctx.Param("webhookId")); err != nil {
+			handleWebhookError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Webhook deleted successfully", nil)
+	}
+}
+
+func (h *WebhookHandler) GetWebhookEvents() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		lib.Success(ctx, "Webhook events fetched successfully", models.WebhookEvents)
+	}
+}
+
+func (h *WebhookHandler) GetDeliveries() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var params dto.WebhookDeliveryParams
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		if err := ctx.ShouldBind(&params); err != nil {
+			lib.BadRequest(ctx, err.Error(), "400")
+			return
+		}
+
+		deliveries, err := h.service.GetDeliveries(userId, ctx.Param("webhookId"), params)
+		if err != nil {
+			handleWebhookError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Webhook deliveries fetched successfully", deliveries)
+	}
+}
+
+func (h *WebhookHandler) ReplayDelivery() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		delivery, err := h.service.ReplayDelivery(userId, ctx.Param("webhookId"), ctx.Param("deliveryId"))
+		if err != nil {
+			handleWebhookError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Webhook delivery replayed", delivery)
+	}
+}
+
+func (h *WebhookHandler) PingWebhook() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+
+		delivery, err := h.service.Ping(userId, ctx.Param("webhookId"))
+		if err != nil {
+			handleWebhookError(ctx, err)
+			return
+		}
+
+		lib.Success(ctx, "Webhook pinged", delivery)
+	}
+}
+
+func handleWebhookError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound), errors.Is(err, services.ErrWebhookDeliveryNotFound):
+		lib.NotFound(ctx, err.Error(), "")
+	case errors.Is(err, services.ErrInvalidWebhookURL), errors.Is(err, services.ErrWebhookAddressBlocked),
+		errors.Is(err, services.ErrInvalidWebhookEvent), errors.Is(err, services.ErrInvalidDeliveryStatus):
+		lib.BadRequest(ctx, err.Error(), "")
+	default:
+		lib.InternalServerError(ctx, err.Error())
+	}
+}
@@ -0,0 +1,55 @@
+package lib
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const (
+	CursorNext = "next"
+	CursorPrev = "prev"
+)
+
+// Cursor marks a position in a keyset paginated list. It is handed to clients
+// as an opaque string and records the sort it was issued for, so it cannot be
+// replayed against a differently ordered list.
+type Cursor struct {
+	Direction string          `json:"d"`
+	ID        string          `json:"id"`
+	SortBy    string          `json:"s"`
+	SortOrder string          `json:"o"`
+	Value     json.RawMessage `json:"v"`
+}
+
+func EncodeCursor(cursor Cursor) (string, error) {
+	payload, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+func DecodeCursor(encoded string) (*Cursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Direction != CursorNext && cursor.Direction != CursorPrev {
+		return nil, ErrInvalidCursor
+	}
+	if _, err := uuid.Parse(cursor.ID); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
@@ -0,0 +1,37 @@
+package lib
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetETag sends a record's version as its entity tag.
+func SetETag(ctx *gin.Context, version int) {
+	ctx.Header("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// IfMatchVersion reads the version the client last saw from the If-Match
+// header. ok is false when the header is missing. "*" matches any version
+// and is returned as 0; a tag that is not one of ours, including a weak one,
+// is returned as -1 so that it never matches.
+func IfMatchVersion(ctx *gin.Context) (version int, ok bool) {
+	header := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if header == "" {
+		return 0, false
+	}
+	if header == "*" {
+		return 0, true
+	}
+
+	tag, err := strconv.Unquote(header)
+	if err != nil {
+		return -1, true
+	}
+	version, err = strconv.Atoi(tag)
+	if err != nil || version < 1 {
+		return -1, true
+	}
+	return version, true
+}
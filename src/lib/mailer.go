@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"html/template"
 	"invoicer-go/m/src/config"
+	"io"
 	"path/filepath"
 	"sync"
 
@@ -18,10 +19,17 @@ type EmailService struct {
 }
 
 type EmailDto struct {
-	To       []string
-	Subject  string
-	Template string
-	Data     interface{}
+	To          []string
+	Cc          []string
+	Subject     string
+	Template    string
+	Data        interface{}
+	Attachments []EmailAttachment
+}
+
+type EmailAttachment struct {
+	Filename string
+	Content  []byte
 }
 
 var (
@@ -94,9 +102,20 @@ func (es *EmailService) SendEmail(payload EmailDto) error {
 	msg := gomail.NewMessage()
 	msg.SetHeader("From", config.AppConfig.AppEmail)
 	msg.SetHeader("To", payload.To...)
+	if len(payload.Cc) > 0 {
+		msg.SetHeader("Cc", payload.Cc...)
+	}
 	msg.SetHeader("Subject", payload.Subject)
 	msg.SetBody("text/html", html)
 
+	for _, attachment := range payload.Attachments {
+		content := attachment.Content
+		msg.Attach(attachment.Filename, gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := w.Write(content)
+			return err
+		}))
+	}
+
 	return es.dialer.DialAndSend(msg)
 }
 
@@ -27,12 +27,14 @@ type APIResponse struct {
 }
 
 const (
-	NotFoundCode        = "RESOURCE_NOT_FOUND"
-	ValidationErrorCode = "VALIDATION_ERROR"
-	InternalErrorCode   = "INTERNAL_ERROR"
-	UnauthorizedCode    = "UNAUTHORIZED"
-	ForbiddenCode       = "FORBIDDEN"
-	ConflictCode        = "CONFLICT"
+	NotFoundCode             = "RESOURCE_NOT_FOUND"
+	ValidationErrorCode      = "VALIDATION_ERROR"
+	InternalErrorCode        = "INTERNAL_ERROR"
+	UnauthorizedCode         = "UNAUTHORIZED"
+	ForbiddenCode            = "FORBIDDEN"
+	ConflictCode             = "CONFLICT"
+	PreconditionFailedCode   = "PRECONDITION_FAILED"
+	PreconditionRequiredCode = "PRECONDITION_REQUIRED"
 )
 
 func GlobalNotFound() gin.HandlerFunc {
@@ -162,6 +164,44 @@ func Conflict(ctx *gin.Context, message string) {
 	ctx.Abort()
 }
 
+func PreconditionFailed(ctx *gin.Context, message string) {
+	if message == "" {
+		message = "The resource has changed"
+	}
+
+	response := ErrorResponse{
+		Success:   false,
+		Error:     "Precondition Failed",
+		Message:   message,
+		Code:      PreconditionFailedCode,
+		Path:      ctx.Request.URL.Path,
+		Method:    ctx.Request.Method,
+		Timestamp: time.Now().UTC(),
+	}
+
+	ctx.JSON(http.StatusPreconditionFailed, response)
+	ctx.Abort()
+}
+
+func PreconditionRequired(ctx *gin.Context, message string) {
+	if message == "" {
+		message = "An If-Match header is required"
+	}
+
+	response := ErrorResponse{
+		Success:   false,
+		Error:     "Precondition Required",
+		Message:   message,
+		Code:      PreconditionRequiredCode,
+		Path:      ctx.Request.URL.Path,
+		Method:    ctx.Request.Method,
+		Timestamp: time.Now().UTC(),
+	}
+
+	ctx.JSON(http.StatusPreconditionRequired, response)
+	ctx.Abort()
+}
+
 func Forbidden(ctx *gin.Context, message string) {
 	if message == "" {
 		message = "Access forbidden"
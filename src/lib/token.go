@@ -20,6 +20,17 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+// CustomerClaims identify a customer signed in to the portal and the user
+// whose invoices they were invited to see. They carry the portal audience so
+// they can never be used as a user token, and vice versa.
+type CustomerClaims struct {
+	CustomerId uuid.UUID `json:"customer_id"`
+	IssuerId   uuid.UUID `json:"issuer_id"`
+	jwt.RegisteredClaims
+}
+
+const customerAudience = "customer-portal"
+
 var jwtSecret []byte
 
 func InitialiseJWT(secret string) {
@@ -69,6 +80,58 @@ func ValidateToken(tokenString string) (*Claims, error) {
 	}
 
 	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid || len(claims.Audience) > 0 {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+func GenerateCustomerToken(customerId, issuerId uuid.UUID, expiresIn time.Duration) (string, error) {
+	if len(jwtSecret) == 0 {
+		return "", ErrMissingSecretKey
+	}
+
+	claims := CustomerClaims{
+		CustomerId: customerId,
+		IssuerId:   issuerId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{customerAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    "adire-apparel",
+			Subject:   customerId.String(),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret)
+}
+
+func ValidateCustomerToken(tokenString string) (*CustomerClaims, error) {
+	if len(jwtSecret) == 0 {
+		return nil, ErrMissingSecretKey
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &CustomerClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrTokenMalformed
+		}
+		return jwtSecret, nil
+	}, jwt.WithAudience(customerAudience))
+
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+		if errors.Is(err, jwt.ErrTokenMalformed) {
+			return nil, ErrTokenMalformed
+		}
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(*CustomerClaims)
 	if !ok || !token.Valid {
 		return nil, ErrInvalidToken
 	}
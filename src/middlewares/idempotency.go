@@ -0,0 +1,113 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"invoicer-go/m/src/config"
+	"invoicer-go/m/src/database"
+	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/services"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotentRequestBytes = 10 << 20 // 10 MB
+)
+
+// recordingWriter keeps a copy of the response body as it is written.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
+
+// IdempotencyMiddleware makes authenticated POST requests that carry an
+// Idempotency-Key header safe to retry. The first response for a user's key
+// is stored and replayed for later requests with the same key, unless it was
+// a server error, in which case the key is released for the retry. Reusing a
+// key for a different method, path or body is rejected with 409.
+func IdempotencyMiddleware() gin.HandlerFunc {
+	idempotency := services.NewIdempotencyService(database.GetDatabase())
+
+	return func(ctx *gin.Context) {
+		key := strings.TrimSpace(ctx.GetHeader(idempotencyKeyHeader))
+		userId := ctx.GetString(config.AppConfig.CurrentUserId)
+		if ctx.Request.Method != http.MethodPost || key == "" || userId == "" {
+			ctx.Next()
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxIdempotentRequestBytes+1))
+		if err != nil {
+			ctx.Error(lib.NewApiErrror("Unable to read request body", http.StatusBadRequest))
+			ctx.Abort()
+			return
+		}
+		if len(body) > maxIdempotentRequestBytes {
+			ctx.Error(lib.NewApiErrror("Request body is too large", http.StatusRequestEntityTooLarge))
+			ctx.Abort()
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, claimed, err := idempotency.Claim(userId, key, requestFingerprint(ctx, body))
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidIdempotencyKey):
+				ctx.Error(lib.NewApiErrror(err.Error(), http.StatusBadRequest))
+			case errors.Is(err, services.ErrIdempotencyKeyReused), errors.Is(err, services.ErrIdempotencyKeyInFlight):
+				ctx.Error(lib.NewApiErrror(err.Error(), http.StatusConflict))
+			default:
+				ctx.Error(err)
+			}
+			ctx.Abort()
+			return
+		}
+		if !claimed {
+			ctx.Header(idempotentReplayedHeader, "true")
+			ctx.Data(record.ResponseStatus, record.ContentType, record.ResponseBody)
+			ctx.Abort()
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+		ctx.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			err = idempotency.Release(record)
+		} else {
+			err = idempotency.Complete(record, status, writer.Header().Get("Content-Type"), writer.body.Bytes())
+		}
+		if err != nil {
+			log.Printf("Failed to save idempotency key %q: %v", key, err)
+		}
+	}
+}
+
+// requestFingerprint identifies a request by its method, path and body.
+func requestFingerprint(ctx *gin.Context, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(ctx.Request.Method + " " + ctx.Request.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,62 @@
+package middlewares
+
+import (
+	"invoicer-go/m/src/config"
+	"invoicer-go/m/src/database"
+	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CustomerAuthMiddleware guards the customer portal. It only accepts customer
+// tokens issued from a magic link; user tokens are rejected, as are tokens of
+// customers that have since been archived or merged.
+func CustomerAuthMiddleware() gin.HandlerFunc {
+	db := database.GetDatabase()
+	authService := services.NewAuthService(db)
+	customerService := services.NewCustomerService(db)
+
+	return func(ctx *gin.Context) {
+		authHeader := ctx.Request.Header.Get("Authorization")
+		token, ok := extractBearerToken(authHeader)
+		if !ok {
+			ctx.Error(lib.NewApiErrror("No auth token found", http.StatusUnauthorized))
+			ctx.Abort()
+			return
+		}
+
+		claims, err := lib.ValidateCustomerToken(token)
+		if err != nil {
+			ctx.Error(lib.NewApiErrror("Invalid auth token", http.StatusUnauthorized))
+			ctx.Abort()
+			return
+		}
+
+		customer, err := customerService.FindCustomerById(claims.CustomerId.String())
+		if err != nil {
+			ctx.Error(lib.NewApiErrror("Customer not found", http.StatusNotFound))
+			ctx.Abort()
+			return
+		}
+		if customer.ArchivedAt.Valid || customer.MergedIntoID != nil {
+			ctx.Error(lib.NewApiErrror("Customer is no longer active", http.StatusUnauthorized))
+			ctx.Abort()
+			return
+		}
+
+		issuer, err := authService.FindUserById(claims.IssuerId.String())
+		if err != nil {
+			ctx.Error(lib.NewApiErrror("User not found", http.StatusNotFound))
+			ctx.Abort()
+			return
+		}
+
+		ctx.Set(config.AppConfig.CurrentCustomer, customer)
+		ctx.Set(config.AppConfig.CurrentCustomerId, customer.ID.String())
+		ctx.Set(config.AppConfig.CurrentIssuer, issuer)
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type InvoiceEventType string
+
+const (
+	InvoiceCreated       InvoiceEventType = "created"
+	InvoiceSent          InvoiceEventType = "sent"
+	InvoiceViewed        InvoiceEventType = "viewed"
+	InvoiceReminded      InvoiceEventType = "reminded"
+	InvoicePaymentAdded  InvoiceEventType = "payment_recorded"
+	InvoicePaymentVoided InvoiceEventType = "payment_deleted"
+	InvoiceStatusChanged InvoiceEventType = "status_changed"
+	InvoiceArchived      InvoiceEventType = "archived"
+	InvoiceRestored      InvoiceEventType = "restored"
+)
+
+// InvoiceEvent is something that happened to an invoice, recorded by the
+// system. ActorID is the user behind it, if any.
+type InvoiceEvent struct {
+	BaseModel
+	ActorID   *uuid.UUID       `json:"actorId" gorm:"type:uuid"`
+	Data      EventData        `json:"data" gorm:"type:jsonb"`
+	InvoiceID uuid.UUID        `json:"invoiceId" gorm:"type:uuid;index;not null"`
+	Type      InvoiceEventType `json:"type" gorm:"type:varchar(30);not null"`
+}
+
+func (u *InvoiceEvent) BeforeCreate(tx *gorm.DB) error {
+	u.CreatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+// InvoiceComment is an internal note written by a user. Comments are never
+// shown to the customer.
+type InvoiceComment struct {
+	BaseModel
+	Body      string       `json:"body" gorm:"type:text;not null"`
+	EditedAt  sql.NullTime `json:"editedAt"`
+	InvoiceID uuid.UUID    `json:"invoiceId" gorm:"type:uuid;index;not null"`
+	UserID    uuid.UUID    `json:"userId" gorm:"type:uuid;not null"`
+}
+
+func (u *InvoiceComment) BeforeCreate(tx *gorm.DB) error {
+	u.CreatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (u *InvoiceComment) BeforeUpdate(tx *gorm.DB) error {
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+// EventData holds the details of an event, stored as jsonb.
+type EventData map[string]interface{}
+
+func (e EventData) Value() (driver.Value, error) {
+	if e == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(e)
+	return string(raw), err
+}
+
+func (e *EventData) Scan(value interface{}) error {
+	return scanJSON(value, e)
+}
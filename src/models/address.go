@@ -0,0 +1,10 @@
+package models
+
+type Address struct {
+	City       string `json:"city" gorm:"type:varchar(255)"`
+	Country    string `json:"country" gorm:"type:varchar(2)"`
+	Line1      string `json:"line1" gorm:"type:varchar(255)"`
+	Line2      string `json:"line2" gorm:"type:varchar(255)"`
+	PostalCode string `json:"postalCode" gorm:"type:varchar(20)"`
+	State      string `json:"state" gorm:"type:varchar(255)"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CustomerContact struct {
+	BaseModel
+	CustomerID       uuid.UUID `json:"customerId" gorm:"index;not null"`
+	Email            string    `json:"email" gorm:"type:varchar(255);not null"`
+	IsBillingContact bool      `json:"isBillingContact" gorm:"not null;default:false"`
+	Name             string    `json:"name" gorm:"type:varchar(255);not null"`
+	Phone            string    `json:"phone" gorm:"type:varchar(255)"`
+	Role             string    `json:"role" gorm:"type:varchar(100)"`
+}
+
+func (u *CustomerContact) BeforeCreate(tx *gorm.DB) error {
+	u.CreatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (u *CustomerContact) BeforeUpdate(tx *gorm.DB) error {
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
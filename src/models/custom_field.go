@@ -0,0 +1,100 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CustomFieldEntity string
+
+const (
+	CustomFieldInvoice  CustomFieldEntity = "invoice"
+	CustomFieldCustomer CustomFieldEntity = "customer"
+	CustomFieldLineItem CustomFieldEntity = "line_item"
+)
+
+type CustomFieldType string
+
+const (
+	CustomFieldText   CustomFieldType = "text"
+	CustomFieldNumber CustomFieldType = "number"
+	CustomFieldDate   CustomFieldType = "date"
+	CustomFieldSelect CustomFieldType = "select"
+)
+
+// CustomFieldDefinition describes a field a user has added to their invoices,
+// customers or line items. Values are stored under Key in the record's
+// custom_fields column.
+type CustomFieldDefinition struct {
+	BaseModel
+	Entity    CustomFieldEntity `json:"entity" gorm:"type:varchar(20);not null;uniqueIndex:idx_custom_fields_user_entity_key"`
+	Key       string            `json:"key" gorm:"type:varchar(50);not null;uniqueIndex:idx_custom_fields_user_entity_key"`
+	Name      string            `json:"name" gorm:"type:varchar(100);not null"`
+	Options   JSONStrings       `json:"options" gorm:"type:jsonb"`
+	Position  int               `json:"position" gorm:"not null;default:0"`
+	Required  bool              `json:"required" gorm:"not null;default:false"`
+	ShowOnPdf bool              `json:"showOnPdf" gorm:"not null;default:false"`
+	Type      CustomFieldType   `json:"type" gorm:"type:varchar(10);not null"`
+	UserID    uuid.UUID         `json:"userId" gorm:"type:uuid;not null;uniqueIndex:idx_custom_fields_user_entity_key"`
+}
+
+func (u *CustomFieldDefinition) BeforeCreate(tx *gorm.DB) error {
+	u.CreatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (u *CustomFieldDefinition) BeforeUpdate(tx *gorm.DB) error {
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+// CustomFields holds custom field values keyed by definition key, stored as
+// jsonb.
+type CustomFields map[string]interface{}
+
+func (c CustomFields) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+	raw, err := json.Marshal(c)
+	return string(raw), err
+}
+
+func (c *CustomFields) Scan(value interface{}) error {
+	return scanJSON(value, c)
+}
+
+// JSONStrings is a list of strings stored as a jsonb array.
+type JSONStrings []string
+
+func (j JSONStrings) Value() (driver.Value, error) {
+	if j == nil {
+		return "[]", nil
+	}
+	raw, err := json.Marshal(j)
+	return string(raw), err
+}
+
+func (j *JSONStrings) Scan(value interface{}) error {
+	return scanJSON(value, j)
+}
+
+func scanJSON(value interface{}, target interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, target)
+	case string:
+		return json.Unmarshal([]byte(v), target)
+	default:
+		return errors.New("unsupported type for jsonb column")
+	}
+}
@@ -4,14 +4,24 @@ import (
 	"database/sql"
 	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type Customer struct {
 	BaseModel
-	Email string `json:"email" gorm:"type:varchar(255);uniqueIndex;not null"`
-	Name  string `json:"name" gorm:"type:varchar(255);not null"`
-	Phone string `json:"phone" gorm:"type:varchar(255);uniqueIndex;not null"`
+	ArchivedAt      sql.NullTime      `json:"archivedAt" gorm:"index"`
+	BillingAddress  *Address          `json:"billingAddress" gorm:"embedded;embeddedPrefix:billing_"`
+	Contacts        []CustomerContact `json:"contacts,omitempty" gorm:"foreignKey:CustomerID"`
+	CustomFields    CustomFields      `json:"customFields" gorm:"type:jsonb;not null;default:'{}';index:,type:gin"`
+	Email           string            `json:"email" gorm:"type:varchar(255);uniqueIndex;not null"`
+	MergedIntoID    *uuid.UUID        `json:"mergedIntoId,omitempty" gorm:"type:uuid"`
+	Name            string            `json:"name" gorm:"type:varchar(255);not null"`
+	PaymentTermID   *uuid.UUID        `json:"paymentTermId" gorm:"type:uuid"`
+	Phone           string            `json:"phone" gorm:"type:varchar(255);uniqueIndex;not null"`
+	ShippingAddress *Address          `json:"shippingAddress" gorm:"embedded;embeddedPrefix:shipping_"`
+	Tags            []Tag             `json:"tags,omitempty" gorm:"many2many:customer_tags"`
+	Version         int               `json:"version" gorm:"not null;default:1"`
 }
 
 func (u *Customer) BeforeCreate(tx *gorm.DB) error {
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Expense is money a user has spent, optionally on behalf of a customer.
+// A billable expense is rebilled to its customer as an invoice line at Amount
+// plus Markup percent. InvoiceID keeps the invoice it was billed on; the
+// expense counts as billed only while that invoice is not archived.
+type Expense struct {
+	BaseModel
+	Amount      float64      `json:"amount" gorm:"not null"`
+	Billable    bool         `json:"billable" gorm:"not null;default:true"`
+	BilledAt    sql.NullTime `json:"billedAt"`
+	Category    string       `json:"category" gorm:"type:varchar(100);index"`
+	Currency    string       `json:"currency" gorm:"type:varchar(3);not null"`
+	CustomerID  *uuid.UUID   `json:"customerId" gorm:"type:uuid;index"`
+	Date        time.Time    `json:"date" gorm:"type:date;not null;index"`
+	Description string       `json:"description" gorm:"type:text"`
+	InvoiceID   *uuid.UUID   `json:"invoiceId" gorm:"type:uuid;index"`
+	Markup      float64      `json:"markup" gorm:"not null;default:0"`
+	ReceiptURL  string       `json:"receiptUrl" gorm:"type:text"`
+	UserID      uuid.UUID    `json:"userId" gorm:"type:uuid;not null;index"`
+	Vendor      string       `json:"vendor" gorm:"type:varchar(255)"`
+}
+
+func (u *Expense) BeforeCreate(tx *gorm.DB) error {
+	u.CreatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (u *Expense) BeforeUpdate(tx *gorm.DB) error {
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// IdempotencyKey is a POST request a user has made with an Idempotency-Key
+// header, and the response it got. Fingerprint identifies the request, so a
+// key reused for a different request can be told apart from a retry. A zero
+// ResponseStatus means the first request is still being handled.
+type IdempotencyKey struct {
+	BaseModel
+	ContentType    string    `json:"contentType" gorm:"type:varchar(100)"`
+	ExpiresAt      time.Time `json:"expiresAt" gorm:"not null;index"`
+	Fingerprint    string    `json:"fingerprint" gorm:"type:varchar(64);not null"`
+	Key            string    `json:"key" gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_keys_user_key"`
+	ResponseBody   []byte    `json:"-" gorm:"type:bytea"`
+	ResponseStatus int       `json:"responseStatus" gorm:"not null;default:0"`
+	UserID         uuid.UUID `json:"userId" gorm:"type:uuid;not null;uniqueIndex:idx_idempotency_keys_user_key"`
+}
+
+func (u *IdempotencyKey) BeforeCreate(tx *gorm.DB) error {
+	u.CreatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (u *IdempotencyKey) BeforeUpdate(tx *gorm.DB) error {
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
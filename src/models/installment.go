@@ -0,0 +1,35 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InvoiceInstallment is one part of an invoice's payment schedule. A
+// percentage based installment has its amount recalculated whenever the
+// invoice total changes; otherwise Percentage is nil and Amount is fixed.
+type InvoiceInstallment struct {
+	BaseModel
+	Amount     float64       `json:"amount"`
+	AmountPaid float64       `json:"amountPaid" gorm:"-"`
+	DueDate    time.Time     `json:"dueDate" gorm:"index"`
+	InvoiceID  uuid.UUID     `json:"invoiceId" gorm:"index"`
+	Label      string        `json:"label" gorm:"type:varchar(100)"`
+	Percentage *float64      `json:"percentage"`
+	Position   int           `json:"position"`
+	Status     InvoiceStatus `json:"status,omitempty" gorm:"-"`
+}
+
+func (u *InvoiceInstallment) BeforeCreate(tx *gorm.DB) error {
+	u.CreatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (u *InvoiceInstallment) BeforeUpdate(tx *gorm.DB) error {
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -25,33 +26,65 @@ const (
 	Percentage DiscountType = "percentage"
 )
 
+// Invoice totals: TaxTotal is the tax added to the subtotal and
+// WithholdingTotal the tax withheld by the customer, so Total is the amount
+// payable after both. Version goes up with every change and is the
+// invoice's ETag.
 type Invoice struct {
 	BaseModel
-	Currency     string        `json:"currency" gorm:"type:varchar(3)"`
-	CustomerID   uuid.UUID     `json:"customerId" gorm:"index"`
-	Customer     Customer      `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
-	DateDue      time.Time     `json:"dateDue" gorm:"index"`
-	DateIssued   time.Time     `json:"dateIssued"`
-	Discount     float64       `json:"discount"`
-	DiscountType DiscountType  `json:"discountType" gorm:"type:varchar(10)"`
-	Items        []InvoiceItem `json:"items,omitempty" gorm:"foreignKey:InvoiceID"`
-	Note         string        `json:"note" gorm:"type:text"`
-	ReferenceNo  string        `json:"referenceNo" gorm:"type:varchar(100);uniqueIndex"`
-	Status       InvoiceStatus `json:"status" gorm:"type:varchar(10);index"`
-	SubTotal     float64       `json:"subTotal"`
-	Tax          float64       `json:"tax"`
-	TaxType      DiscountType  `json:"taxType" gorm:"type:varchar(10)"`
-	Title        string        `json:"title" gorm:"type:varchar(255)"`
-	Total        float64       `json:"total"`
+	ArchivedAt       sql.NullTime         `json:"archivedAt" gorm:"index"`
+	BillingAddress   *Address             `json:"billingAddress" gorm:"embedded;embeddedPrefix:billing_"`
+	Currency         string               `json:"currency" gorm:"type:varchar(3)"`
+	CustomFields     CustomFields         `json:"customFields" gorm:"type:jsonb;not null;default:'{}';index:,type:gin"`
+	CustomerID       uuid.UUID            `json:"customerId" gorm:"index"`
+	Customer         Customer             `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
+	DateDue          time.Time            `json:"dateDue" gorm:"index"`
+	DateIssued       time.Time            `json:"dateIssued"`
+	Discount         float64              `json:"discount"`
+	DiscountType     DiscountType         `json:"discountType" gorm:"type:varchar(10)"`
+	ExchangeRate     float64              `json:"exchangeRate" gorm:"not null;default:1"`
+	Installments     []InvoiceInstallment `json:"installments,omitempty" gorm:"foreignKey:InvoiceID"`
+	Items            []InvoiceItem        `json:"items,omitempty" gorm:"foreignKey:InvoiceID"`
+	Note             string               `json:"note" gorm:"type:text"`
+	PaymentTermID    *uuid.UUID           `json:"paymentTermId" gorm:"type:uuid"`
+	PaymentTerms     string               `json:"paymentTerms" gorm:"type:varchar(100)"`
+	Payments         []Payment            `json:"payments,omitempty" gorm:"foreignKey:InvoiceID"`
+	ReferenceNo      string               `json:"referenceNo" gorm:"type:varchar(100);uniqueIndex"`
+	Status           InvoiceStatus        `json:"status" gorm:"type:varchar(10);index"`
+	Tags             []Tag                `json:"tags,omitempty" gorm:"many2many:invoice_tags"`
+	SubTotal         float64              `json:"subTotal"`
+	Tax              float64              `json:"tax"`
+	TaxBreakdown     TaxLines             `json:"taxBreakdown" gorm:"type:jsonb;not null;default:'[]'"`
+	TaxTotal         float64              `json:"taxTotal"`
+	TaxType          DiscountType         `json:"taxType" gorm:"type:varchar(10)"`
+	Title            string               `json:"title" gorm:"type:varchar(255)"`
+	Total            float64              `json:"total"`
+	Version          int                  `json:"version" gorm:"not null;default:1"`
+	WithholdingTotal float64              `json:"withholdingTotal"`
 }
 
+// InvoiceItem is a line on an invoice. LineTotal is the quantity times the
+// price less the line's own discount, net of any inclusive taxes; its taxes
+// are charged on LineTotal. Withholding taxes are kept out of TaxAmount.
+// ExpenseID is set on lines that bill an expense and TimeEntryIDs on lines
+// that bill tracked time.
 type InvoiceItem struct {
 	BaseModel
-	InvoiceID   uuid.UUID `json:"invoiceId" gorm:"index"`
-	Description string    `json:"description" gorm:"type:text"`
-	LineTotal   float64   `json:"lineTotal"`
-	Price       float64   `json:"price"`
-	Quantity    int       `json:"quantity"`
+	InvoiceID         uuid.UUID       `json:"invoiceId" gorm:"index"`
+	CustomFields      CustomFields    `json:"customFields" gorm:"type:jsonb;not null;default:'{}'"`
+	Description       string          `json:"description" gorm:"type:text"`
+	Discount          float64         `json:"discount"`
+	DiscountAmount    float64         `json:"discountAmount"`
+	DiscountType      DiscountType    `json:"discountType" gorm:"type:varchar(10)"`
+	ExpenseID         *uuid.UUID      `json:"expenseId,omitempty" gorm:"type:uuid;index"`
+	LineTotal         float64         `json:"lineTotal"`
+	Price             float64         `json:"price"`
+	Quantity          decimal.Decimal `json:"quantity" gorm:"type:numeric(18,6)"`
+	TaxAmount         float64         `json:"taxAmount"`
+	Taxes             ItemTaxes       `json:"taxes" gorm:"type:jsonb;not null;default:'[]'"`
+	TimeEntryIDs      JSONStrings     `json:"timeEntryIds,omitempty" gorm:"type:jsonb;not null;default:'[]'"`
+	Unit              string          `json:"unit" gorm:"type:varchar(3)"`
+	WithholdingAmount float64         `json:"withholdingAmount"`
 }
 
 func (u *Invoice) BeforeCreate(tx *gorm.DB) error {
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CustomerMerge records a duplicate customer being folded into another one.
+// The source's details are copied so the history still reads after the
+// source has been purged.
+type CustomerMerge struct {
+	BaseModel
+	ContactsMoved int       `json:"contactsMoved"`
+	InvoicesMoved int       `json:"invoicesMoved"`
+	MergedByID    uuid.UUID `json:"mergedById" gorm:"type:uuid;not null"`
+	PaymentsMoved int       `json:"paymentsMoved"`
+	SourceEmail   string    `json:"sourceEmail" gorm:"type:varchar(255)"`
+	SourceID      uuid.UUID `json:"sourceId" gorm:"type:uuid;index;not null"`
+	SourceName    string    `json:"sourceName" gorm:"type:varchar(255)"`
+	TargetID      uuid.UUID `json:"targetId" gorm:"type:uuid;index;not null"`
+}
+
+func (u *CustomerMerge) BeforeCreate(tx *gorm.DB) error {
+	u.CreatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (u *CustomerMerge) BeforeUpdate(tx *gorm.DB) error {
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
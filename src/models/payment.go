@@ -0,0 +1,44 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PaymentKind string
+
+const (
+	PaymentReceived PaymentKind = "payment"
+	CreditApplied   PaymentKind = "credit"
+)
+
+type Payment struct {
+	BaseModel
+	Amount    float64     `json:"amount" gorm:"not null"`
+	InvoiceID uuid.UUID   `json:"invoiceId" gorm:"index;not null"`
+	Kind      PaymentKind `json:"kind" gorm:"type:varchar(10);not null;default:payment"`
+	Method    string      `json:"method" gorm:"type:varchar(50)"`
+	Note      string      `json:"note" gorm:"type:text"`
+	PaidAt    time.Time   `json:"paidAt" gorm:"index;not null"`
+	Reference string      `json:"reference" gorm:"type:varchar(255)"`
+}
+
+func (u *Payment) BeforeCreate(tx *gorm.DB) error {
+	if u.Kind == "" {
+		u.Kind = PaymentReceived
+	}
+	if u.PaidAt.IsZero() {
+		u.PaidAt = time.Now()
+	}
+	u.CreatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (u *Payment) BeforeUpdate(tx *gorm.DB) error {
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
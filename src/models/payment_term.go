@@ -0,0 +1,72 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PaymentTermType string
+
+const (
+	// NetTerm is due a number of days after the invoice is issued; zero
+	// days is due on receipt.
+	NetTerm PaymentTermType = "net"
+	// EndOfMonthTerm is due a number of days after the end of the month the
+	// invoice is issued in.
+	EndOfMonthTerm PaymentTermType = "end_of_month"
+)
+
+// PaymentTerm is a named rule in a user's account for working out when an
+// invoice is due. At most one of a user's terms is the default.
+type PaymentTerm struct {
+	BaseModel
+	Days      int             `json:"days" gorm:"not null;default:0"`
+	IsDefault bool            `json:"isDefault" gorm:"not null;default:false"`
+	Name      string          `json:"name" gorm:"type:varchar(100);not null;uniqueIndex:idx_payment_terms_user_name"`
+	Type      PaymentTermType `json:"type" gorm:"type:varchar(20);not null"`
+	UserID    uuid.UUID       `json:"userId" gorm:"type:uuid;not null;uniqueIndex:idx_payment_terms_user_name"`
+}
+
+func (u *PaymentTerm) BeforeCreate(tx *gorm.DB) error {
+	u.CreatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (u *PaymentTerm) BeforeUpdate(tx *gorm.DB) error {
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+// DueDate works out when an invoice issued on the given date is due.
+func (u *PaymentTerm) DueDate(issued time.Time) time.Time {
+	if u.Type == EndOfMonthTerm {
+		endOfMonth := time.Date(issued.Year(), issued.Month()+1, 0, issued.Hour(), issued.Minute(), issued.Second(), 0, issued.Location())
+		return endOfMonth.AddDate(0, 0, u.Days)
+	}
+	return issued.AddDate(0, 0, u.Days)
+}
+
+// Text describes the rule the way it is printed on invoices, such as "Net 30
+// days" or "End of month + 10 days", whatever the term is named.
+func (u *PaymentTerm) Text() string {
+	days := fmt.Sprintf("%d days", u.Days)
+	if u.Days == 1 {
+		days = "1 day"
+	}
+
+	switch {
+	case u.Type == EndOfMonthTerm && u.Days == 0:
+		return "Due at end of month"
+	case u.Type == EndOfMonthTerm:
+		return "End of month + " + days
+	case u.Days == 0:
+		return "Due on receipt"
+	default:
+		return "Net " + days
+	}
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPaymentTermDueDate(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 9, 30, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		term   PaymentTerm
+		issued time.Time
+		want   time.Time
+	}{
+		{"due on receipt", PaymentTerm{Type: NetTerm}, date(2026, time.March, 10), date(2026, time.March, 10)},
+		{"net 30 across a month end", PaymentTerm{Type: NetTerm, Days: 30}, date(2026, time.January, 15), date(2026, time.February, 14)},
+		{"net 15 across a year end", PaymentTerm{Type: NetTerm, Days: 15}, date(2026, time.December, 20), date(2027, time.January, 4)},
+		{"end of month", PaymentTerm{Type: EndOfMonthTerm}, date(2026, time.January, 15), date(2026, time.January, 31)},
+		{"end of month on the last day", PaymentTerm{Type: EndOfMonthTerm}, date(2026, time.April, 30), date(2026, time.April, 30)},
+		{"end of month in a leap year", PaymentTerm{Type: EndOfMonthTerm}, date(2028, time.February, 3), date(2028, time.February, 29)},
+		{"end of month plus days", PaymentTerm{Type: EndOfMonthTerm, Days: 10}, date(2026, time.January, 31), date(2026, time.February, 10)},
+		{"end of december plus days", PaymentTerm{Type: EndOfMonthTerm, Days: 15}, date(2026, time.December, 1), date(2027, time.January, 15)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.term.DueDate(tt.issued); !got.Equal(tt.want) {
+				t.Errorf("DueDate(%v) = %v, want %v", tt.issued, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaymentTermText(t *testing.T) {
+	tests := []struct {
+		term PaymentTerm
+		want string
+	}{
+		{PaymentTerm{Name: "Immediately", Type: NetTerm}, "Due on receipt"},
+		{PaymentTerm{Name: "Standard", Type: NetTerm, Days: 30}, "Net 30 days"},
+		{PaymentTerm{Name: "Next day", Type: NetTerm, Days: 1}, "Net 1 day"},
+		{PaymentTerm{Name: "Month end", Type: EndOfMonthTerm}, "Due at end of month"},
+		{PaymentTerm{Name: "EOM 10", Type: EndOfMonthTerm, Days: 10}, "End of month + 10 days"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.term.Name, func(t *testing.T) {
+			if got := tt.term.Text(); got != tt.want {
+				t.Errorf("Text() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
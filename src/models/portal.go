@@ -0,0 +1,31 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PortalToken is a one-time magic link that signs a customer in to the
+// portal. Only a hash of the token is stored.
+type PortalToken struct {
+	BaseModel
+	CustomerID uuid.UUID    `json:"customerId" gorm:"index;not null"`
+	ExpiresAt  time.Time    `json:"expiresAt" gorm:"not null"`
+	IssuerID   uuid.UUID    `json:"issuerId" gorm:"index;not null"`
+	TokenHash  string       `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	UsedAt     sql.NullTime `json:"usedAt"`
+}
+
+func (u *PortalToken) BeforeCreate(tx *gorm.DB) error {
+	u.CreatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (u *PortalToken) BeforeUpdate(tx *gorm.DB) error {
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
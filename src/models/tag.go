@@ -0,0 +1,27 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Tag struct {
+	BaseModel
+	Color  string    `json:"color" gorm:"type:varchar(7);not null"`
+	Name   string    `json:"name" gorm:"type:varchar(50);not null;uniqueIndex:idx_tags_user_name"`
+	UserID uuid.UUID `json:"userId" gorm:"type:uuid;not null;uniqueIndex:idx_tags_user_name"`
+}
+
+func (u *Tag) BeforeCreate(tx *gorm.DB) error {
+	u.CreatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (u *Tag) BeforeUpdate(tx *gorm.DB) error {
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
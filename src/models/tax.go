@@ -0,0 +1,90 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TaxRate is a named rate in a user's tax catalog. A compound tax is charged
+// on the line amount plus the other taxes, a withholding tax is deducted from
+// the amount payable, and an inclusive tax is already part of the price.
+type TaxRate struct {
+	BaseModel
+	Compound    bool      `json:"compound" gorm:"not null;default:false"`
+	Description string    `json:"description" gorm:"type:text"`
+	Inclusive   bool      `json:"inclusive" gorm:"not null;default:false"`
+	Name        string    `json:"name" gorm:"type:varchar(50);not null;uniqueIndex:idx_tax_rates_user_name"`
+	Rate        float64   `json:"rate" gorm:"not null"`
+	UserID      uuid.UUID `json:"userId" gorm:"type:uuid;not null;uniqueIndex:idx_tax_rates_user_name"`
+	Withholding bool      `json:"withholding" gorm:"not null;default:false"`
+}
+
+func (u *TaxRate) BeforeCreate(tx *gorm.DB) error {
+	u.CreatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (u *TaxRate) BeforeUpdate(tx *gorm.DB) error {
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+// ItemTax is one tax charged on a line item. Taxes picked from the catalog
+// are copied onto the item, so later changes to the catalog leave issued
+// invoices alone.
+type ItemTax struct {
+	Compound    bool       `json:"compound,omitempty"`
+	Inclusive   bool       `json:"inclusive,omitempty"`
+	Name        string     `json:"name"`
+	Rate        float64    `json:"rate"`
+	TaxRateID   *uuid.UUID `json:"taxRateId,omitempty"`
+	Withholding bool       `json:"withholding,omitempty"`
+}
+
+// ItemTaxes is the list of taxes on a line item, stored as a jsonb array.
+type ItemTaxes []ItemTax
+
+func (t ItemTaxes) Value() (driver.Value, error) {
+	if t == nil {
+		return "[]", nil
+	}
+	raw, err := json.Marshal(t)
+	return string(raw), err
+}
+
+func (t *ItemTaxes) Scan(value interface{}) error {
+	return scanJSON(value, t)
+}
+
+// TaxLine is one row of an invoice's tax summary: the total charged at one
+// tax rate, with the amount it was charged on.
+type TaxLine struct {
+	Amount      float64 `json:"amount"`
+	Base        float64 `json:"base"`
+	Compound    bool    `json:"compound,omitempty"`
+	Inclusive   bool    `json:"inclusive,omitempty"`
+	Name        string  `json:"name"`
+	Rate        float64 `json:"rate"`
+	Withholding bool    `json:"withholding,omitempty"`
+}
+
+// TaxLines is an invoice's tax breakdown, stored as a jsonb array.
+type TaxLines []TaxLine
+
+func (t TaxLines) Value() (driver.Value, error) {
+	if t == nil {
+		return "[]", nil
+	}
+	raw, err := json.Marshal(t)
+	return string(raw), err
+}
+
+func (t *TaxLines) Scan(value interface{}) error {
+	return scanJSON(value, t)
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TimeEntry is time a user has spent for a customer. Duration is in minutes;
+// while a timer is running StartedAt is set and Duration is not yet known.
+// Once the entry is put on an invoice InvoiceID and BilledAt are set.
+type TimeEntry struct {
+	BaseModel
+	Billable    bool         `json:"billable" gorm:"not null;default:true"`
+	BilledAt    sql.NullTime `json:"billedAt"`
+	CustomerID  uuid.UUID    `json:"customerId" gorm:"type:uuid;not null;index"`
+	Date        time.Time    `json:"date" gorm:"type:date;not null;index"`
+	Description string       `json:"description" gorm:"type:text"`
+	Duration    int          `json:"duration" gorm:"not null;default:0"`
+	HourlyRate  float64      `json:"hourlyRate" gorm:"not null;default:0"`
+	InvoiceID   *uuid.UUID   `json:"invoiceId" gorm:"type:uuid;index"`
+	Project     string       `json:"project" gorm:"type:varchar(100)"`
+	StartedAt   sql.NullTime `json:"startedAt"`
+	Task        string       `json:"task" gorm:"type:varchar(100)"`
+	UserID      uuid.UUID    `json:"userId" gorm:"type:uuid;not null;index;uniqueIndex:idx_time_entries_running_timer,where:started_at IS NOT NULL"`
+}
+
+func (u *TimeEntry) BeforeCreate(tx *gorm.DB) error {
+	u.CreatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (u *TimeEntry) BeforeUpdate(tx *gorm.DB) error {
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
@@ -0,0 +1,37 @@
+package models
+
+import "github.com/shopspring/decimal"
+
+func init() {
+	// Quantities are sent as plain JSON numbers, as they were when they were
+	// whole numbers, rather than as quoted strings.
+	decimal.MarshalJSONWithoutQuotes = true
+}
+
+// UnitOfMeasure is a unit an item can be billed in. Code is the UN/ECE
+// Recommendation 20 code used in e-invoices, and is what items store.
+type UnitOfMeasure struct {
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+	Symbol string `json:"symbol"`
+}
+
+// UnitsOfMeasure are the units clients can pick by name. Any other UN/ECE
+// code is accepted as is.
+var UnitsOfMeasure = []UnitOfMeasure{
+	{Code: "C62", Name: "unit", Symbol: "unit"},
+	{Code: "H87", Name: "piece", Symbol: "pc"},
+	{Code: "SET", Name: "set", Symbol: "set"},
+	{Code: "MIN", Name: "minute", Symbol: "min"},
+	{Code: "HUR", Name: "hour", Symbol: "h"},
+	{Code: "DAY", Name: "day", Symbol: "d"},
+	{Code: "WEE", Name: "week", Symbol: "wk"},
+	{Code: "MON", Name: "month", Symbol: "mo"},
+	{Code: "GRM", Name: "gram", Symbol: "g"},
+	{Code: "KGM", Name: "kilogram", Symbol: "kg"},
+	{Code: "TNE", Name: "tonne", Symbol: "t"},
+	{Code: "LTR", Name: "litre", Symbol: "l"},
+	{Code: "MTR", Name: "metre", Symbol: "m"},
+	{Code: "MTK", Name: "square metre", Symbol: "m²"},
+	{Code: "KMT", Name: "kilometre", Symbol: "km"},
+}
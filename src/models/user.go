@@ -9,6 +9,7 @@ import (
 
 type User struct {
 	BaseModel
+	Address         *Address         `json:"address" gorm:"embedded;embeddedPrefix:address_"`
 	BankInformation *BankInformation `json:"bankInformation" gorm:"embedded"`
 	CompanyLogo     string           `json:"companyLogo" gorm:"type:varchar(255);not null"`
 	CompanyName     string           `json:"companyName" gorm:"type:varchar(255);not null"`
@@ -18,6 +19,7 @@ type User struct {
 	Provider        string           `json:"provider" gorm:"type:varchar(255);not null"`
 	RcNumber        string           `json:"rcNumber" gorm:"type:varchar(255);uniqueIndex;not null"`
 	TaxId           string           `json:"taxId" gorm:"type:varchar(255);uniqueIndex;not null"`
+	Version         int              `json:"version" gorm:"not null;default:1"`
 	Website         string           `json:"website" gorm:"type:varchar(255);uniqueIndex;not null"`
 }
 
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	WebhookCustomerCreated = "customer.created"
+	WebhookCustomerUpdated = "customer.updated"
+	WebhookInvoiceArchived = "invoice.archived"
+	WebhookInvoiceCreated  = "invoice.created"
+	WebhookInvoiceOverdue  = "invoice.overdue"
+	WebhookInvoicePaid     = "invoice.paid"
+	WebhookInvoiceReminded = "invoice.reminded"
+	WebhookInvoiceRestored = "invoice.restored"
+	WebhookInvoiceSent     = "invoice.sent"
+	WebhookPaymentDeleted  = "payment.deleted"
+	WebhookPaymentRecorded = "payment.recorded"
+	// WebhookPing is only sent by the test-ping endpoint and cannot be
+	// subscribed to.
+	WebhookPing = "ping"
+)
+
+// WebhookEvents are the events an endpoint can subscribe to.
+var WebhookEvents = []string{
+	WebhookCustomerCreated,
+	WebhookCustomerUpdated,
+	WebhookInvoiceArchived,
+	WebhookInvoiceCreated,
+	WebhookInvoiceOverdue,
+	WebhookInvoicePaid,
+	WebhookInvoiceReminded,
+	WebhookInvoiceRestored,
+	WebhookInvoiceSent,
+	WebhookPaymentDeleted,
+	WebhookPaymentRecorded,
+}
+
+// WebhookEndpoint is a URL in a user's account that is sent the events it
+// subscribes to when that user triggers them. Requests are signed with
+// Secret.
+type WebhookEndpoint struct {
+	BaseModel
+	Active      bool        `json:"active" gorm:"not null;default:true"`
+	Description string      `json:"description" gorm:"type:varchar(255)"`
+	Events      JSONStrings `json:"events" gorm:"type:jsonb;not null;default:'[]'"`
+	Secret      string      `json:"secret" gorm:"type:varchar(100);not null"`
+	URL         string      `json:"url" gorm:"type:text;not null"`
+	UserID      uuid.UUID   `json:"userId" gorm:"type:uuid;not null;index"`
+}
+
+func (u *WebhookEndpoint) BeforeCreate(tx *gorm.DB) error {
+	u.CreatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (u *WebhookEndpoint) BeforeUpdate(tx *gorm.DB) error {
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	DeliveryPending   WebhookDeliveryStatus = "pending"
+	DeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	DeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one event sent, or still to be sent, to an endpoint.
+// Payload is kept as the exact body that is signed. A pending delivery is
+// retried at NextAttemptAt until it succeeds or runs out of attempts.
+type WebhookDelivery struct {
+	BaseModel
+	Attempts       int                   `json:"attempts" gorm:"not null;default:0"`
+	EndpointID     uuid.UUID             `json:"endpointId" gorm:"type:uuid;not null;index"`
+	Error          string                `json:"error" gorm:"type:text"`
+	Event          string                `json:"event" gorm:"type:varchar(50);not null"`
+	LastAttemptAt  sql.NullTime          `json:"lastAttemptAt"`
+	NextAttemptAt  sql.NullTime          `json:"nextAttemptAt" gorm:"index"`
+	Payload        string                `json:"payload" gorm:"type:text;not null"`
+	ResponseBody   string                `json:"responseBody" gorm:"type:text"`
+	ResponseStatus int                   `json:"responseStatus"`
+	Status         WebhookDeliveryStatus `json:"status" gorm:"type:varchar(10);not null;index"`
+}
+
+func (u *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	u.CreatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (u *WebhookDelivery) BeforeUpdate(tx *gorm.DB) error {
+	u.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"invoicer-go/m/src/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ActivityRoutes(router *gin.RouterGroup) *gin.RouterGroup {
+	invoices := router.Group("/invoices/:id")
+	handler := handlers.NewActivityHandler()
+
+	invoices.GET("/activity", handler.GetActivity())
+	invoices.POST("/comments", handler.AddComment())
+	invoices.PUT("/comments/:commentId", handler.UpdateComment())
+
+	return invoices
+}
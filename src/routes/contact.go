@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"invoicer-go/m/src/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ContactRoutes(router *gin.RouterGroup) *gin.RouterGroup {
+	contacts := router.Group("/customers/:id/contacts")
+	handler := handlers.NewContactHandler()
+
+	contacts.POST("", handler.CreateContact())
+	contacts.GET("", handler.GetContacts())
+	contacts.GET("/:contactId", handler.GetContact())
+	contacts.PUT("/:contactId", handler.UpdateContact())
+	contacts.DELETE("/:contactId", handler.DeleteContact())
+
+	return contacts
+}
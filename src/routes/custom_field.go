@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"invoicer-go/m/src/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func CustomFieldRoutes(router *gin.RouterGroup) *gin.RouterGroup {
+	customFields := router.Group("/custom-fields")
+	handler := handlers.NewCustomFieldHandler()
+
+	customFields.POST("", handler.CreateDefinition())
+	customFields.GET("", handler.GetDefinitions())
+	customFields.PUT("/:id", handler.UpdateDefinition())
+	customFields.DELETE("/:id", handler.DeleteDefinition())
+
+	return customFields
+}
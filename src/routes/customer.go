@@ -13,8 +13,11 @@ func CustomerRoutes(router *gin.RouterGroup) *gin.RouterGroup {
 	customers.POST("", handler.CreateCustomer())
 	customers.PUT("/:id", handler.UpdateCustomer())
 	customers.DELETE("/:id", handler.DeleteCustomer())
+	customers.POST("/:id/restore", handler.RestoreCustomer())
 	customers.GET("", handler.GetCustomers())
 	customers.GET("/:id", handler.GetCustomer())
+	customers.POST("/:id/merge", handler.MergeCustomer())
+	customers.GET("/:id/merges", handler.GetMerges())
 
 	return customers
 }
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"invoicer-go/m/src/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ExpenseRoutes(router *gin.RouterGroup) *gin.RouterGroup {
+	expenses := router.Group("/expenses")
+	handler := handlers.NewExpenseHandler()
+
+	expenses.POST("", handler.CreateExpense())
+	expenses.GET("", handler.GetExpenses())
+	expenses.GET("/:expenseId", handler.GetExpense())
+	expenses.PUT("/:expenseId", handler.UpdateExpense())
+	expenses.DELETE("/:expenseId", handler.DeleteExpense())
+	expenses.POST("/:expenseId/receipt", handler.UploadReceipt())
+
+	router.POST("/invoices/:id/expenses", handler.BillExpenses())
+
+	return expenses
+}
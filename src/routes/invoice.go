@@ -13,8 +13,12 @@ func InvoiceRoutes(router *gin.RouterGroup) *gin.RouterGroup {
 	invoices.POST("", handler.CreateInvoice())
 	invoices.PUT("/:id", handler.UpdateInvoice())
 	invoices.DELETE("/:id", handler.DeleteInvoice())
+	invoices.POST("/:id/restore", handler.RestoreInvoice())
 	invoices.GET("", handler.GetInvoices())
 	invoices.GET("/:id", handler.GetInvoice())
+	invoices.GET("/:id/pdf", handler.DownloadInvoicePdf())
+	invoices.POST("/:id/send", handler.SendInvoice())
+	invoices.POST("/:id/remind", handler.SendReminder())
 
 	return invoices
 }
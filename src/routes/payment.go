@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"invoicer-go/m/src/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PaymentRoutes(router *gin.RouterGroup) *gin.RouterGroup {
+	payments := router.Group("/invoices/:id/payments")
+	handler := handlers.NewPaymentHandler()
+
+	payments.POST("", handler.RecordPayment())
+	payments.GET("", handler.GetPayments())
+	payments.DELETE("/:paymentId", handler.DeletePayment())
+
+	return payments
+}
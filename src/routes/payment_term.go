@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"invoicer-go/m/src/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PaymentTermRoutes(router *gin.RouterGroup) *gin.RouterGroup {
+	terms := router.Group("/payment-terms")
+	handler := handlers.NewPaymentTermHandler()
+
+	terms.POST("", handler.CreatePaymentTerm())
+	terms.GET("", handler.GetPaymentTerms())
+	terms.GET("/:paymentTermId", handler.GetPaymentTerm())
+	terms.PUT("/:paymentTermId", handler.UpdatePaymentTerm())
+	terms.DELETE("/:paymentTermId", handler.DeletePaymentTerm())
+
+	return terms
+}
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"invoicer-go/m/src/handlers"
+	"invoicer-go/m/src/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PortalRoutes(router *gin.RouterGroup) *gin.RouterGroup {
+	portal := router.Group("/portal")
+	handler := handlers.NewPortalHandler()
+
+	router.POST("/customers/:id/portal/invite", handler.InviteCustomer())
+
+	portal.POST("/login", handler.RequestLoginLink())
+	portal.POST("/verify", handler.Verify())
+
+	customer := portal.Group("", middlewares.CustomerAuthMiddleware())
+	customer.GET("/me", handler.GetProfile())
+	customer.GET("/invoices", handler.GetInvoices())
+	customer.GET("/invoices/:invoiceId", handler.GetInvoice())
+	customer.GET("/invoices/:invoiceId/pdf", handler.DownloadInvoicePdf())
+	customer.GET("/statement", handler.GetStatement())
+
+	return portal
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"invoicer-go/m/src/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ReportRoutes(router *gin.RouterGroup) *gin.RouterGroup {
+	reports := router.Group("/reports")
+	handler := handlers.NewReportHandler()
+
+	reports.GET("/aging", handler.GetAgingReport())
+	reports.GET("/summary", handler.GetSummaryReport())
+	reports.GET("/tags", handler.GetTagReport())
+
+	return reports
+}
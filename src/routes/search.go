@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"invoicer-go/m/src/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SearchRoutes(router *gin.RouterGroup) *gin.RouterGroup {
+	search := router.Group("/search")
+	handler := handlers.NewSearchHandler()
+
+	search.GET("", handler.Search())
+
+	return search
+}
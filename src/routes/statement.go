@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"invoicer-go/m/src/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func StatementRoutes(router *gin.RouterGroup) *gin.RouterGroup {
+	statements := router.Group("/customers/:id/statement")
+	handler := handlers.NewStatementHandler()
+
+	statements.GET("", handler.GetStatement())
+	statements.POST("/send", handler.SendStatement())
+
+	return statements
+}
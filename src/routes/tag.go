@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"invoicer-go/m/src/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TagRoutes(router *gin.RouterGroup) *gin.RouterGroup {
+	tags := router.Group("/tags")
+	handler := handlers.NewTagHandler()
+
+	tags.POST("", handler.CreateTag())
+	tags.GET("", handler.GetTags())
+	tags.PUT("/:tagId", handler.UpdateTag())
+	tags.DELETE("/:tagId", handler.DeleteTag())
+
+	router.POST("/invoices/:id/tags", handler.AddInvoiceTags())
+	router.DELETE("/invoices/:id/tags/:tagId", handler.RemoveInvoiceTag())
+	router.POST("/customers/:id/tags", handler.AddCustomerTags())
+	router.DELETE("/customers/:id/tags/:tagId", handler.RemoveCustomerTag())
+
+	return tags
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"invoicer-go/m/src/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TaxRateRoutes(router *gin.RouterGroup) *gin.RouterGroup {
+	rates := router.Group("/tax-rates")
+	handler := handlers.NewTaxRateHandler()
+
+	rates.POST("", handler.CreateTaxRate())
+	rates.GET("", handler.GetTaxRates())
+	rates.GET("/:taxRateId", handler.GetTaxRate())
+	rates.PUT("/:taxRateId", handler.UpdateTaxRate())
+	rates.DELETE("/:taxRateId", handler.DeleteTaxRate())
+
+	return rates
+}
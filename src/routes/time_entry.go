@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"invoicer-go/m/src/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TimeEntryRoutes(router *gin.RouterGroup) *gin.RouterGroup {
+	entries := router.Group("/time-entries")
+	handler := handlers.NewTimeEntryHandler()
+
+	entries.POST("", handler.CreateTimeEntry())
+	entries.GET("", handler.GetTimeEntries())
+	entries.GET("/timer", handler.GetTimer())
+	entries.POST("/timer/start", handler.StartTimer())
+	entries.POST("/timer/stop", handler.StopTimer())
+	entries.POST("/invoice", handler.InvoiceTime())
+	entries.GET("/:timeEntryId", handler.GetTimeEntry())
+	entries.PUT("/:timeEntryId", handler.UpdateTimeEntry())
+	entries.DELETE("/:timeEntryId", handler.DeleteTimeEntry())
+
+	return entries
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"invoicer-go/m/src/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func WebhookRoutes(router *gin.RouterGroup) *gin.RouterGroup {
+	webhooks := router.Group("/webhooks")
+	handler := handlers.NewWebhookHandler()
+
+	webhooks.POST("", handler.CreateWebhook())
+	webhooks.GET("", handler.GetWebhooks())
+	webhooks.GET("/events", handler.GetWebhookEvents())
+	webhooks.GET("/:webhookId", handler.GetWebhook())
+	webhooks.PUT("/:webhookId", handler.UpdateWebhook())
+	webhooks.DELETE("/:webhookId", handler.DeleteWebhook())
+	webhooks.POST("/:webhookId/ping", handler.PingWebhook())
+	webhooks.GET("/:webhookId/deliveries", handler.GetDeliveries())
+	webhooks.POST("/:webhookId/deliveries/:deliveryId/replay", handler.ReplayDelivery())
+
+	return webhooks
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"errors"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/models"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ActivityService struct {
+	database *gorm.DB
+}
+
+func NewActivityService(database *gorm.DB) *ActivityService {
+	return &ActivityService{
+		database: database,
+	}
+}
+
+var (
+	ErrCommentNotFound  = errors.New("comment not found")
+	ErrCommentNotAuthor = errors.New("only the author can edit a comment")
+	ErrEmptyComment     = errors.New("comment body is required")
+)
+
+const (
+	activityKindComment = "comment"
+	activityKindEvent   = "event"
+)
+
+// recordInvoiceEvent adds an event to the invoice timeline and queues the
+// webhook it triggers. An empty actorId records the event as done by the
+// system or the customer.
+func recordInvoiceEvent(tx *gorm.DB, invoiceId uuid.UUID, kind models.InvoiceEventType, actorId string, data models.EventData) error {
+	event := &models.InvoiceEvent{
+		Data:      data,
+		InvoiceID: invoiceId,
+		Type:      kind,
+	}
+	if actor, err := uuid.Parse(actorId); err == nil {
+		event.ActorID = &actor
+	}
+	if err := tx.Create(event).Error; err != nil {
+		return err
+	}
+	return queueInvoiceWebhook(tx, event)
+}
+
+// GetActivity returns the invoice's events and internal comments merged into
+// one list, oldest first.
+func (s *ActivityService) GetActivity(invoiceId string) ([]dto.ActivityEntry, error) {
+	invoice, err := NewInvoiceService(s.database).FindInvoiceById(invoiceId)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []models.InvoiceEvent
+	if err := s.database.Where("invoice_id = ?", invoice.ID).Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	var comments []models.InvoiceComment
+	if err := s.database.Where("invoice_id = ?", invoice.ID).Find(&comments).Error; err != nil {
+		return nil, err
+	}
+
+	entries := make([]dto.ActivityEntry, 0, len(events)+len(comments))
+	for _, event := range events {
+		entry := dto.ActivityEntry{
+			Data:       event.Data,
+			ID:         event.ID.String(),
+			Kind:       activityKindEvent,
+			OccurredAt: event.CreatedAt.Time,
+			Type:       string(event.Type),
+		}
+		if event.ActorID != nil {
+			actor := event.ActorID.String()
+			entry.ActorID = &actor
+		}
+		entries = append(entries, entry)
+	}
+	for _, comment := range comments {
+		entries = append(entries, commentEntry(comment))
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].OccurredAt.Before(entries[j].OccurredAt)
+	})
+
+	return entries, nil
+}
+
+func (s *ActivityService) AddComment(invoiceId, userId string, payload dto.CommentDto) (*dto.ActivityEntry, error) {
+	invoice, err := NewInvoiceService(s.database).FindInvoiceById(invoiceId)
+	if err != nil {
+		return nil, err
+	}
+
+	body := strings.TrimSpace(payload.Body)
+	if body == "" {
+		return nil, ErrEmptyComment
+	}
+
+	comment := &models.InvoiceComment{
+		Body:      body,
+		InvoiceID: invoice.ID,
+		UserID:    uuid.MustParse(userId),
+	}
+	if err := s.database.Create(comment).Error; err != nil {
+		return nil, err
+	}
+
+	entry := commentEntry(*comment)
+	return &entry, nil
+}
+
+// UpdateComment changes the body of a comment. Only the user who wrote it may
+// edit it.
+func (s *ActivityService) UpdateComment(invoiceId, commentId, userId string, payload dto.CommentDto) (*dto.ActivityEntry, error) {
+	var comment models.InvoiceComment
+	err := s.database.Where("id = ? AND invoice_id = ?", commentId, invoiceId).First(&comment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+	if comment.UserID.String() != userId {
+		return nil, ErrCommentNotAuthor
+	}
+
+	body := strings.TrimSpace(payload.Body)
+	if body == "" {
+		return nil, ErrEmptyComment
+	}
+
+	comment.Body = body
+	comment.EditedAt.Time, comment.EditedAt.Valid = time.Now(), true
+	if err := s.database.Save(&comment).Error; err != nil {
+		return nil, err
+	}
+
+	entry := commentEntry(comment)
+	return &entry, nil
+}
+
+func commentEntry(comment models.InvoiceComment) dto.ActivityEntry {
+	author := comment.UserID.String()
+	entry := dto.ActivityEntry{
+		ActorID:    &author,
+		Body:       comment.Body,
+		ID:         comment.ID.String(),
+		Kind:       activityKindComment,
+		OccurredAt: comment.CreatedAt.Time,
+	}
+	if comment.EditedAt.Valid {
+		entry.EditedAt = &comment.EditedAt.Time
+	}
+	return entry
+}
@@ -138,6 +138,7 @@ func (s *AuthService) SigninWithOauth(payload *goth.User) (*SigninResponse, erro
 	} else {
 
 		user = s.updateUserFromOAuth(user, payload)
+		user.Version++
 		if err = s.database.Save(user).Error; err != nil {
 			return nil, fmt.Errorf("failed to update user: %w", err)
 		}
@@ -162,7 +163,13 @@ func (s *AuthService) createUserFromOAuth(payload *goth.User) (*models.User, err
 		Provider: payload.Provider,
 	}
 
-	if err := s.database.Create(user).Error; err != nil {
+	err := s.database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return createStandardPaymentTerms(tx, user.ID)
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to create user in database: %w", err)
 	}
 
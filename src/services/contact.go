@@ -0,0 +1,163 @@
+package services
+
+import (
+	"errors"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/models"
+	"net/mail"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ContactService struct {
+	database *gorm.DB
+}
+
+func NewContactService(database *gorm.DB) *ContactService {
+	return &ContactService{
+		database: database,
+	}
+}
+
+var (
+	ErrContactNotFound     = errors.New("contact not found")
+	ErrInvalidContactEmail = errors.New("contact email is not a valid email address")
+	ErrInvalidContactName  = errors.New("contact name is required")
+)
+
+func (s *ContactService) CreateContact(customerId string, payload dto.CreateContactDto) (*models.CustomerContact, error) {
+	customer, err := NewCustomerService(s.database).FindCustomerById(customerId)
+	if err != nil {
+		return nil, err
+	}
+
+	contact := &models.CustomerContact{
+		CustomerID:       customer.ID,
+		Email:            strings.TrimSpace(payload.Email),
+		IsBillingContact: payload.IsBillingContact,
+		Name:             strings.TrimSpace(payload.Name),
+		Phone:            strings.TrimSpace(payload.Phone),
+		Role:             strings.TrimSpace(payload.Role),
+	}
+	if err := validateContact(contact); err != nil {
+		return nil, err
+	}
+
+	if err := s.database.Create(contact).Error; err != nil {
+		return nil, err
+	}
+
+	return contact, nil
+}
+
+func (s *ContactService) GetContacts(customerId string) ([]models.CustomerContact, error) {
+	if _, err := NewCustomerService(s.database).FindCustomerById(customerId); err != nil {
+		return nil, err
+	}
+
+	var contacts []models.CustomerContact
+	if err := s.database.Where("customer_id = ?", customerId).Order("created_at ASC").Find(&contacts).Error; err != nil {
+		return nil, err
+	}
+
+	return contacts, nil
+}
+
+func (s *ContactService) UpdateContact(customerId, contactId string, payload dto.UpdateContactDto) (*models.CustomerContact, error) {
+	contact, err := s.FindContactById(customerId, contactId)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Email != nil {
+		contact.Email = strings.TrimSpace(*payload.Email)
+	}
+	if payload.IsBillingContact != nil {
+		contact.IsBillingContact = *payload.IsBillingContact
+	}
+	if payload.Name != nil {
+		contact.Name = strings.TrimSpace(*payload.Name)
+	}
+	if payload.Phone != nil {
+		contact.Phone = strings.TrimSpace(*payload.Phone)
+	}
+	if payload.Role != nil {
+		contact.Role = strings.TrimSpace(*payload.Role)
+	}
+	if err := validateContact(contact); err != nil {
+		return nil, err
+	}
+
+	if err := s.database.Save(contact).Error; err != nil {
+		return nil, err
+	}
+
+	return contact, nil
+}
+
+func (s *ContactService) DeleteContact(customerId, contactId string) error {
+	if _, err := uuid.Parse(contactId); err != nil {
+		return ErrContactNotFound
+	}
+
+	result := s.database.Where("id = ? AND customer_id = ?", contactId, customerId).Delete(&models.CustomerContact{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrContactNotFound
+	}
+	return nil
+}
+
+func (s *ContactService) FindContactById(customerId, contactId string) (*models.CustomerContact, error) {
+	if _, err := uuid.Parse(contactId); err != nil {
+		return nil, ErrContactNotFound
+	}
+
+	contact := &models.CustomerContact{}
+	err := s.database.Where("id = ? AND customer_id = ?", contactId, customerId).First(contact).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrContactNotFound
+		}
+		return nil, err
+	}
+
+	return contact, nil
+}
+
+// Recipients resolves who customer emails go to. Billing contacts are the
+// addressees and every other contact is copied in; a customer without billing
+// contacts is emailed at its own address.
+func (s *ContactService) Recipients(customer *models.Customer) (to []string, cc []string, err error) {
+	var contacts []models.CustomerContact
+	if err := s.database.Where("customer_id = ?", customer.ID).Order("created_at ASC").Find(&contacts).Error; err != nil {
+		return nil, nil, err
+	}
+
+	for _, contact := range contacts {
+		if contact.IsBillingContact {
+			to = append(to, contact.Email)
+		} else {
+			cc = append(cc, contact.Email)
+		}
+	}
+	if len(to) == 0 {
+		to = []string{customer.Email}
+	}
+
+	return to, cc, nil
+}
+
+func validateContact(contact *models.CustomerContact) error {
+	if contact.Name == "" {
+		return ErrInvalidContactName
+	}
+	if _, err := mail.ParseAddress(contact.Email); err != nil {
+		return ErrInvalidContactEmail
+	}
+	return nil
+}
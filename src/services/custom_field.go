@@ -0,0 +1,379 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/models"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CustomFieldService struct {
+	database *gorm.DB
+}
+
+func NewCustomFieldService(database *gorm.DB) *CustomFieldService {
+	return &CustomFieldService{
+		database: database,
+	}
+}
+
+var (
+	ErrCustomFieldExists          = errors.New("a custom field with this key already exists")
+	ErrCustomFieldNotFound        = errors.New("custom field not found")
+	ErrCustomFieldOptionsRequired = errors.New("select fields need at least one option")
+	ErrCustomFieldRequired        = errors.New("custom field is required")
+	ErrInvalidCustomFieldEntity   = errors.New("entity must be one of invoice, customer or line_item")
+	ErrInvalidCustomFieldKey      = errors.New("key must start with a letter and only contain lowercase letters, digits and underscores")
+	ErrInvalidCustomFieldName     = errors.New("custom field name is required")
+	ErrInvalidCustomFieldType     = errors.New("type must be one of text, number, date or select")
+	ErrInvalidCustomFieldValue    = errors.New("invalid custom field value")
+	ErrUnknownCustomField         = errors.New("unknown custom field")
+)
+
+const customFieldDateLayout = "2006-01-02"
+
+var (
+	customFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+	customFieldKeyStrip   = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+func (s *CustomFieldService) CreateDefinition(userId string, payload dto.CreateCustomFieldDto) (*models.CustomFieldDefinition, error) {
+	definition := &models.CustomFieldDefinition{
+		Entity:    models.CustomFieldEntity(strings.ToLower(strings.TrimSpace(payload.Entity))),
+		Key:       strings.TrimSpace(payload.Key),
+		Name:      strings.TrimSpace(payload.Name),
+		Options:   trimOptions(payload.Options),
+		Position:  payload.Position,
+		Required:  payload.Required,
+		ShowOnPdf: payload.ShowOnPdf,
+		Type:      models.CustomFieldType(strings.ToLower(strings.TrimSpace(payload.Type))),
+		UserID:    uuid.MustParse(userId),
+	}
+	if definition.Key == "" {
+		definition.Key = strings.Trim(customFieldKeyStrip.ReplaceAllString(strings.ToLower(definition.Name), "_"), "_")
+	}
+
+	switch definition.Entity {
+	case models.CustomFieldInvoice, models.CustomFieldCustomer, models.CustomFieldLineItem:
+	default:
+		return nil, ErrInvalidCustomFieldEntity
+	}
+	switch definition.Type {
+	case models.CustomFieldText, models.CustomFieldNumber, models.CustomFieldDate, models.CustomFieldSelect:
+	default:
+		return nil, ErrInvalidCustomFieldType
+	}
+	if !customFieldKeyPattern.MatchString(definition.Key) {
+		return nil, ErrInvalidCustomFieldKey
+	}
+	if err := validateDefinition(definition); err != nil {
+		return nil, err
+	}
+
+	var count int64
+	err := s.database.Model(&models.CustomFieldDefinition{}).
+		Where("user_id = ? AND entity = ? AND key = ?", userId, definition.Entity, definition.Key).
+		Count(&count).Error
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrCustomFieldExists
+	}
+
+	if err := s.database.Create(definition).Error; err != nil {
+		return nil, err
+	}
+
+	return definition, nil
+}
+
+func (s *CustomFieldService) GetDefinitions(userId string, params dto.CustomFieldParams) ([]models.CustomFieldDefinition, error) {
+	query := s.database.Where("user_id = ?", userId)
+	if params.Entity != "" {
+		query = query.Where("entity = ?", strings.ToLower(params.Entity))
+	}
+
+	definitions := []models.CustomFieldDefinition{}
+	if err := query.Order("entity ASC, position ASC, name ASC").Find(&definitions).Error; err != nil {
+		return nil, err
+	}
+	return definitions, nil
+}
+
+// UpdateDefinition changes how a field is presented and validated. The key,
+// entity and type are fixed once values have been stored under them.
+func (s *CustomFieldService) UpdateDefinition(userId, id string, payload dto.UpdateCustomFieldDto) (*models.CustomFieldDefinition, error) {
+	definition, err := s.FindDefinitionById(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Name != nil {
+		definition.Name = strings.TrimSpace(*payload.Name)
+	}
+	if payload.Options != nil {
+		definition.Options = trimOptions(payload.Options)
+	}
+	if payload.Position != nil {
+		definition.Position = *payload.Position
+	}
+	if payload.Required != nil {
+		definition.Required = *payload.Required
+	}
+	if payload.ShowOnPdf != nil {
+		definition.ShowOnPdf = *payload.ShowOnPdf
+	}
+	if err := validateDefinition(definition); err != nil {
+		return nil, err
+	}
+
+	if err := s.database.Save(definition).Error; err != nil {
+		return nil, err
+	}
+
+	return definition, nil
+}
+
+// DeleteDefinition removes the field. Values already stored under its key are
+// left in place but are no longer validated or shown.
+func (s *CustomFieldService) DeleteDefinition(userId, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrCustomFieldNotFound
+	}
+
+	result := s.database.Where("id = ? AND user_id = ?", id, userId).Delete(&models.CustomFieldDefinition{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCustomFieldNotFound
+	}
+	return nil
+}
+
+func (s *CustomFieldService) FindDefinitionById(userId, id string) (*models.CustomFieldDefinition, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrCustomFieldNotFound
+	}
+
+	definition := &models.CustomFieldDefinition{}
+	if err := s.database.Where("id = ? AND user_id = ?", id, userId).First(definition).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCustomFieldNotFound
+		}
+		return nil, err
+	}
+	return definition, nil
+}
+
+// PdfFields returns the user's fields that should be printed on invoices, in
+// display order.
+func (s *CustomFieldService) PdfFields(userId string) ([]models.CustomFieldDefinition, error) {
+	definitions := []models.CustomFieldDefinition{}
+	err := s.database.Where("user_id = ? AND show_on_pdf = ?", userId, true).
+		Order("position ASC, name ASC").
+		Find(&definitions).Error
+	return definitions, err
+}
+
+// ApplyValues validates input against the user's definitions for the entity
+// and merges it into the current values. A null input value clears the field.
+// Only the submitted keys are checked, so values set by someone else's
+// definitions are kept. Required fields are enforced when current is nil,
+// that is when the record is being created, and cannot be cleared later.
+func (s *CustomFieldService) ApplyValues(userId string, entity models.CustomFieldEntity, current models.CustomFields, input map[string]interface{}) (models.CustomFields, error) {
+	definitions, err := s.definitionsFor(userId, entity)
+	if err != nil {
+		return nil, err
+	}
+	return applyCustomFields(definitions, current, input)
+}
+
+func (s *CustomFieldService) definitionsFor(userId string, entity models.CustomFieldEntity) ([]models.CustomFieldDefinition, error) {
+	var definitions []models.CustomFieldDefinition
+	err := s.database.Where("user_id = ? AND entity = ?", userId, entity).Find(&definitions).Error
+	return definitions, err
+}
+
+func applyCustomFields(definitions []models.CustomFieldDefinition, current models.CustomFields, input map[string]interface{}) (models.CustomFields, error) {
+	byKey := make(map[string]models.CustomFieldDefinition, len(definitions))
+	for _, definition := range definitions {
+		byKey[definition.Key] = definition
+	}
+
+	values := models.CustomFields{}
+	for key, value := range current {
+		values[key] = value
+	}
+
+	for key, value := range input {
+		definition, ok := byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCustomField, key)
+		}
+		if value != nil {
+			normalized, err := normalizeCustomFieldValue(definition, value)
+			if err != nil {
+				return nil, err
+			}
+			if normalized != nil {
+				values[key] = normalized
+				continue
+			}
+		}
+		if definition.Required {
+			return nil, fmt.Errorf("%w: %s", ErrCustomFieldRequired, definition.Name)
+		}
+		delete(values, key)
+	}
+
+	if current == nil {
+		for key, definition := range byKey {
+			if _, ok := values[key]; !ok && definition.Required {
+				return nil, fmt.Errorf("%w: %s", ErrCustomFieldRequired, definition.Name)
+			}
+		}
+	}
+
+	return values, nil
+}
+
+func normalizeCustomFieldValue(definition models.CustomFieldDefinition, value interface{}) (interface{}, error) {
+	invalid := fmt.Errorf("%w: %s must be a %s", ErrInvalidCustomFieldValue, definition.Name, definition.Type)
+
+	switch definition.Type {
+	case models.CustomFieldNumber:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case json.Number:
+			return v.Float64()
+		case string:
+			if strings.TrimSpace(v) == "" {
+				return nil, nil
+			}
+			number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, invalid
+			}
+			return number, nil
+		}
+		return nil, invalid
+	case models.CustomFieldDate:
+		text, ok := value.(string)
+		if !ok {
+			return nil, invalid
+		}
+		if text = strings.TrimSpace(text); text == "" {
+			return nil, nil
+		}
+		date, err := time.Parse(customFieldDateLayout, text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be a date formatted as YYYY-MM-DD", ErrInvalidCustomFieldValue, definition.Name)
+		}
+		return date.Format(customFieldDateLayout), nil
+	case models.CustomFieldSelect:
+		text, ok := value.(string)
+		if !ok {
+			return nil, invalid
+		}
+		if text = strings.TrimSpace(text); text == "" {
+			return nil, nil
+		}
+		for _, option := range definition.Options {
+			if option == text {
+				return text, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s must be one of %s", ErrInvalidCustomFieldValue, definition.Name, strings.Join(definition.Options, ", "))
+	default:
+		text, ok := value.(string)
+		if !ok {
+			return nil, invalid
+		}
+		if text = strings.TrimSpace(text); text == "" {
+			return nil, nil
+		}
+		return text, nil
+	}
+}
+
+// filterByCustomFields keeps rows whose custom field values equal the given
+// ones. Containment is used so the jsonb GIN index applies; numeric looking
+// values also match fields stored as numbers.
+func filterByCustomFields(query *gorm.DB, column string, filters map[string]string) *gorm.DB {
+	for key, value := range filters {
+		text, _ := json.Marshal(map[string]string{key: value})
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			query = query.Where(fmt.Sprintf("%s @> ?::jsonb", column), string(text))
+			continue
+		}
+		numeric, _ := json.Marshal(map[string]float64{key: number})
+		query = query.Where(fmt.Sprintf("(%s @> ?::jsonb OR %s @> ?::jsonb)", column, column), string(text), string(numeric))
+	}
+	return query
+}
+
+// customFieldLines formats the values of the given definitions for print,
+// skipping fields without a value.
+func customFieldLines(definitions []models.CustomFieldDefinition, entity models.CustomFieldEntity, values models.CustomFields) [][2]string {
+	lines := [][2]string{}
+	for _, definition := range definitions {
+		if definition.Entity != entity {
+			continue
+		}
+		value, ok := values[definition.Key]
+		if !ok || value == nil {
+			continue
+		}
+
+		text := fmt.Sprint(value)
+		switch v := value.(type) {
+		case float64:
+			text = strconv.FormatFloat(v, 'f', -1, 64)
+		case string:
+			if definition.Type == models.CustomFieldDate {
+				if date, err := time.Parse(customFieldDateLayout, v); err == nil {
+					text = formatPdfDate(date)
+				}
+			}
+		}
+		lines = append(lines, [2]string{definition.Name, text})
+	}
+	return lines
+}
+
+func validateDefinition(definition *models.CustomFieldDefinition) error {
+	if definition.Name == "" {
+		return ErrInvalidCustomFieldName
+	}
+	if definition.Type == models.CustomFieldSelect && len(definition.Options) == 0 {
+		return ErrCustomFieldOptionsRequired
+	}
+	if definition.Type != models.CustomFieldSelect {
+		definition.Options = nil
+	}
+	return nil
+}
+
+func trimOptions(options []string) models.JSONStrings {
+	trimmed := models.JSONStrings{}
+	seen := map[string]bool{}
+	for _, option := range options {
+		if option = strings.TrimSpace(option); option != "" && !seen[option] {
+			seen[option] = true
+			trimmed = append(trimmed, option)
+		}
+	}
+	return trimmed
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"invoicer-go/m/src/models"
+	"testing"
+)
+
+func TestApplyCustomFields(t *testing.T) {
+	definitions := []models.CustomFieldDefinition{
+		{Key: "po", Name: "PO number", Type: models.CustomFieldText, Required: true},
+		{Key: "hours", Name: "Hours", Type: models.CustomFieldNumber},
+	}
+
+	tests := []struct {
+		name    string
+		current models.CustomFields
+		input   map[string]interface{}
+		want    models.CustomFields
+		wantErr error
+	}{
+		{
+			name:    "create without a required field",
+			input:   map[string]interface{}{"hours": 2.0},
+			wantErr: ErrCustomFieldRequired,
+		},
+		{
+			name:  "create with a required field",
+			input: map[string]interface{}{"po": "A-1"},
+			want:  models.CustomFields{"po": "A-1"},
+		},
+		{
+			name:    "update keeps values of other definitions",
+			current: models.CustomFields{"legacy": "kept", "po": "A-1"},
+			input:   map[string]interface{}{"hours": "1.5"},
+			want:    models.CustomFields{"legacy": "kept", "po": "A-1", "hours": 1.5},
+		},
+		{
+			name:    "update does not check required fields it leaves alone",
+			current: models.CustomFields{},
+			input:   map[string]interface{}{"hours": 3.0},
+			want:    models.CustomFields{"hours": 3.0},
+		},
+		{
+			name:    "null clears an optional field",
+			current: models.CustomFields{"po": "A-1", "hours": 3.0},
+			input:   map[string]interface{}{"hours": nil},
+			want:    models.CustomFields{"po": "A-1"},
+		},
+		{
+			name:    "null cannot clear a required field",
+			current: models.CustomFields{"po": "A-1"},
+			input:   map[string]interface{}{"po": nil},
+			wantErr: ErrCustomFieldRequired,
+		},
+		{
+			name:    "unknown submitted key",
+			current: models.CustomFields{},
+			input:   map[string]interface{}{"legacy": "x"},
+			wantErr: ErrUnknownCustomField,
+		},
+		{
+			name:    "invalid submitted value",
+			current: models.CustomFields{},
+			input:   map[string]interface{}{"hours": "many"},
+			wantErr: ErrInvalidCustomFieldValue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyCustomFields(definitions, tt.current, tt.input)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("values = %v, want %v", got, tt.want)
+			}
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Errorf("values[%q] = %v, want %v", key, got[key], value)
+				}
+			}
+		})
+	}
+}
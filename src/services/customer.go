@@ -1,12 +1,24 @@
 package services
 
 import (
+	"database/sql"
 	"errors"
 	"invoicer-go/m/src/dto"
 	"invoicer-go/m/src/models"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCustomerArchived    = errors.New("customer is archived")
+	ErrCustomerMerged      = errors.New("merged customers cannot be restored")
+	ErrCustomerNotArchived = errors.New("customer is not archived")
+	ErrInvalidCountryCode  = errors.New("country must be an ISO 3166-1 alpha-2 code")
+	ErrMergeSameCustomer   = errors.New("a customer cannot be merged into itself")
 )
 
 type CustomerService struct {
@@ -19,7 +31,7 @@ func NewCustomerService(database *gorm.DB) *CustomerService {
 	}
 }
 
-func (s *CustomerService) CreateCustomer(payload dto.CreateCustomerDto) (*models.Customer, error) {
+func (s *CustomerService) CreateCustomer(userId string, payload dto.CreateCustomerDto) (*models.Customer, error) {
 	existingCustomer, err := s.FindCustomerByEmail(payload.Email)
 	if err != nil && !errors.Is(err, ErrCustomerNotFound) {
 		return nil, err
@@ -28,24 +40,56 @@ func (s *CustomerService) CreateCustomer(payload dto.CreateCustomerDto) (*models
 		return nil, ErrRecordExists
 	}
 
+	billingAddress, err := addressFromDto(payload.BillingAddress)
+	if err != nil {
+		return nil, err
+	}
+	shippingAddress, err := addressFromDto(payload.ShippingAddress)
+	if err != nil {
+		return nil, err
+	}
+	customFields, err := NewCustomFieldService(s.database).ApplyValues(userId, models.CustomFieldCustomer, nil, payload.CustomFields)
+	if err != nil {
+		return nil, err
+	}
+
 	newCustomer := &models.Customer{
-		Name:  payload.Name,
-		Email: payload.Email,
-		Phone: payload.Phone,
+		BillingAddress:  billingAddress,
+		CustomFields:    customFields,
+		Name:            payload.Name,
+		Email:           payload.Email,
+		Phone:           payload.Phone,
+		ShippingAddress: shippingAddress,
+	}
+	if payload.PaymentTermID != "" {
+		term, err := NewPaymentTermService(s.database).FindPaymentTermById(userId, payload.PaymentTermID)
+		if err != nil {
+			return nil, err
+		}
+		newCustomer.PaymentTermID = &term.ID
 	}
 
-	if err := s.database.Create(newCustomer).Error; err != nil {
+	err = s.database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(newCustomer).Error; err != nil {
+			return err
+		}
+		return queueCustomerWebhook(tx, userId, models.WebhookCustomerCreated, newCustomer)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return newCustomer, nil
 }
 
-func (s *CustomerService) UpdateCustomer(id string, payload dto.UpdateCustomerDto) (*models.Customer, error) {
+func (s *CustomerService) UpdateCustomer(id, userId string, version int, payload dto.UpdateCustomerDto) (*models.Customer, error) {
 	customer, err := s.FindCustomerById(id)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkVersion(customer.Version, version); err != nil {
+		return nil, err
+	}
 
 	if payload.Name != nil {
 		customer.Name = *payload.Name
@@ -53,32 +97,241 @@ func (s *CustomerService) UpdateCustomer(id string, payload dto.UpdateCustomerDt
 	if payload.Phone != nil {
 		customer.Phone = *payload.Phone
 	}
+	if payload.PaymentTermID != nil {
+		customer.PaymentTermID = nil
+		if *payload.PaymentTermID != "" {
+			term, err := NewPaymentTermService(s.database).FindPaymentTermById(userId, *payload.PaymentTermID)
+			if err != nil {
+				return nil, err
+			}
+			customer.PaymentTermID = &term.ID
+		}
+	}
+	if payload.BillingAddress != nil {
+		if customer.BillingAddress, err = addressFromDto(payload.BillingAddress); err != nil {
+			return nil, err
+		}
+	}
+	if payload.ShippingAddress != nil {
+		if customer.ShippingAddress, err = addressFromDto(payload.ShippingAddress); err != nil {
+			return nil, err
+		}
+	}
+	if payload.CustomFields != nil {
+		customFields := NewCustomFieldService(s.database)
+		customer.CustomFields, err = customFields.ApplyValues(userId, models.CustomFieldCustomer, customer.CustomFields, payload.CustomFields)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	if err := s.database.Save(customer).Error; err != nil {
+	err = s.database.Transaction(func(tx *gorm.DB) error {
+		if err := saveVersioned(tx, customer, &customer.Version); err != nil {
+			return err
+		}
+		return queueCustomerWebhook(tx, userId, models.WebhookCustomerUpdated, customer)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return customer, nil
 }
 
-func (s *CustomerService) DeleteCustomer(id string) error {
+// ArchiveCustomer hides the customer, and the invoices it still has, from
+// lists and reports. Archived records are kept until the retention job purges
+// them, and can be restored until then.
+func (s *CustomerService) ArchiveCustomer(id string) error {
 	customer, err := s.FindCustomerById(id)
 	if err != nil {
 		return err
 	}
+	if customer.ArchivedAt.Valid {
+		return ErrCustomerArchived
+	}
 
+	archivedAt := time.Now()
 	return s.database.Transaction(func(tx *gorm.DB) error {
-		var invoiceCount int64
-		if err := tx.Model(&models.Invoice{}).Where("customer_id = ?", id).Count(&invoiceCount).Error; err != nil {
+		err := tx.Model(&models.Invoice{}).
+			Where("customer_id = ? AND archived_at IS NULL", customer.ID).
+			Updates(map[string]interface{}{
+				"archived_at": archivedAt,
+				"version":     nextVersion,
+			}).Error
+		if err != nil {
 			return err
 		}
 
-		if invoiceCount > 0 {
-			return errors.New("cannot delete customer with existing invoices")
+		return tx.Model(customer).Updates(map[string]interface{}{
+			"archived_at": archivedAt,
+			"version":     nextVersion,
+		}).Error
+	})
+}
+
+// RestoreCustomer brings an archived customer back together with the invoices
+// that were archived along with it. Invoices archived on their own stay
+// archived.
+func (s *CustomerService) RestoreCustomer(id string) (*models.Customer, error) {
+	customer, err := s.FindCustomerById(id)
+	if err != nil {
+		return nil, err
+	}
+	if !customer.ArchivedAt.Valid {
+		return nil, ErrCustomerNotArchived
+	}
+	if customer.MergedIntoID != nil {
+		return nil, ErrCustomerMerged
+	}
+
+	err = s.database.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Invoice{}).
+			Where("customer_id = ? AND archived_at = ?", customer.ID, customer.ArchivedAt.Time).
+			Updates(map[string]interface{}{
+				"archived_at": nil,
+				"version":     nextVersion,
+			}).Error
+		if err != nil {
+			return err
 		}
 
-		return tx.Delete(customer).Error
+		return tx.Model(customer).Updates(map[string]interface{}{
+			"archived_at": nil,
+			"version":     nextVersion,
+		}).Error
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	customer.ArchivedAt = sql.NullTime{}
+	customer.Version++
+	return customer, nil
+}
+
+// MergeCustomer folds a duplicate customer into the target. The source's
+// invoices, and with them their payments, its contacts, portal links, time
+// entries, expenses and tags move to the target in one transaction. The
+// source is archived and pointed at the target rather than deleted, and the
+// merge is kept in the target's history.
+func (s *CustomerService) MergeCustomer(targetId string, payload dto.MergeCustomerDto, user *models.User) (*models.CustomerMerge, error) {
+	if targetId == payload.SourceID {
+		return nil, ErrMergeSameCustomer
+	}
+
+	target, err := s.FindCustomerById(targetId)
+	if err != nil {
+		return nil, err
+	}
+	source, err := s.FindCustomerById(payload.SourceID)
+	if err != nil {
+		return nil, err
+	}
+	if target.ArchivedAt.Valid || source.ArchivedAt.Valid {
+		return nil, ErrCustomerArchived
+	}
+
+	merge := &models.CustomerMerge{
+		MergedByID:  user.ID,
+		SourceEmail: source.Email,
+		SourceID:    source.ID,
+		SourceName:  source.Name,
+		TargetID:    target.ID,
+	}
+
+	err = s.database.Transaction(func(tx *gorm.DB) error {
+		// Lock both customers, in a fixed order, and check them again so a
+		// concurrent merge or archive cannot slip in between.
+		var locked []models.Customer
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []uuid.UUID{source.ID, target.ID}).
+			Order("id").
+			Find(&locked).Error
+		if err != nil {
+			return err
+		}
+		if len(locked) != 2 {
+			return ErrCustomerNotFound
+		}
+		for _, customer := range locked {
+			if customer.ArchivedAt.Valid || customer.MergedIntoID != nil {
+				return ErrCustomerArchived
+			}
+		}
+
+		var payments int64
+		err = tx.Model(&models.Payment{}).
+			Where("invoice_id IN (?)", tx.Model(&models.Invoice{}).Select("id").Where("customer_id = ?", source.ID)).
+			Count(&payments).Error
+		if err != nil {
+			return err
+		}
+		merge.PaymentsMoved = int(payments)
+
+		result := tx.Model(&models.Invoice{}).Where("customer_id = ?", source.ID).Updates(map[string]interface{}{
+			"customer_id": target.ID,
+			"version":     nextVersion,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		merge.InvoicesMoved = int(result.RowsAffected)
+
+		result = tx.Model(&models.CustomerContact{}).Where("customer_id = ?", source.ID).Update("customer_id", target.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		merge.ContactsMoved = int(result.RowsAffected)
+
+		if err := tx.Model(&models.PortalToken{}).Where("customer_id = ?", source.ID).Update("customer_id", target.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.TimeEntry{}).Where("customer_id = ?", source.ID).Update("customer_id", target.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Expense{}).Where("customer_id = ?", source.ID).Update("customer_id", target.ID).Error; err != nil {
+			return err
+		}
+
+		err = tx.Exec(`INSERT INTO customer_tags (customer_id, tag_id)
+			SELECT ?, tag_id FROM customer_tags WHERE customer_id = ?
+			ON CONFLICT DO NOTHING`, target.ID, source.ID).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM customer_tags WHERE customer_id = ?", source.ID).Error; err != nil {
+			return err
+		}
+
+		err = tx.Model(source).Updates(map[string]interface{}{
+			"archived_at":    time.Now(),
+			"merged_into_id": target.ID,
+			"version":        nextVersion,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(merge).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return merge, nil
+}
+
+func (s *CustomerService) GetMerges(customerId string) ([]models.CustomerMerge, error) {
+	if _, err := s.FindCustomerById(customerId); err != nil {
+		return nil, err
+	}
+
+	var merges []models.CustomerMerge
+	if err := s.database.Where("target_id = ?", customerId).Order("created_at DESC").Find(&merges).Error; err != nil {
+		return nil, err
+	}
+
+	return merges, nil
 }
 
 func (s *CustomerService) GetCustomers(params dto.CustomerPagination) (*dto.PaginatedResponse[models.Customer], error) {
@@ -95,11 +348,9 @@ func (s *CustomerService) GetCustomers(params dto.CustomerPagination) (*dto.Pagi
 	var customers []models.Customer
 	var totalItems int64
 
-	query := s.database.Model(&models.Customer{})
-
-	if params.Query != nil && strings.TrimSpace(*params.Query) != "" {
-		search := "%" + strings.ToLower(strings.TrimSpace(*params.Query)) + "%"
-		query = query.Where("LOWER(name) LIKE ? OR LOWER(email) LIKE ?", search, search)
+	query, err := s.filterCustomers(params)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := query.Count(&totalItems).Error; err != nil {
@@ -115,8 +366,9 @@ func (s *CustomerService) GetCustomers(params dto.CustomerPagination) (*dto.Pagi
 	offset := (params.Page - 1) * params.Limit
 
 	if err := query.Offset(offset).
+		Preload("Tags").
 		Limit(params.Limit).
-		Order("created_at DESC").
+		Order(customerSort.order()).
 		Find(&customers).Error; err != nil {
 		return nil, err
 	}
@@ -135,8 +387,83 @@ func (s *CustomerService) GetCustomers(params dto.CustomerPagination) (*dto.Pagi
 	}, nil
 }
 
+// customerSort is the only ordering the customer list offers, newest first.
+var customerSort = keysetSort{
+	key:      "createdAt",
+	column:   sortColumn{column: "customers.created_at", kind: sortByTime},
+	idColumn: "customers.id",
+}
+
+func (s *CustomerService) filterCustomers(params dto.CustomerPagination) (*gorm.DB, error) {
+	query := s.database.Model(&models.Customer{})
+
+	if !params.IncludeArchived {
+		query = query.Where("customers.archived_at IS NULL")
+	}
+
+	if params.Query != nil {
+		if search := prefixSearchQuery(*params.Query); search != "" {
+			query = query.Where("customers.search_vector @@ to_tsquery('simple', ?)", search)
+		}
+	}
+
+	query = filterByCustomFields(query, "customers.custom_fields", params.CustomFields)
+
+	return filterByTags(query, params.UserID, "customers.id", "customer_tags", "customer_id", params.Tags, params.TagMatch)
+}
+
+func (s *CustomerService) GetCustomersByCursor(params dto.CustomerPagination) (*dto.CursorPaginatedResponse[models.Customer], error) {
+	limit := normalizeLimit(params.Limit)
+
+	cursor, value, err := customerSort.decodeCursor(params.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := s.filterCustomers(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var totalItems *int
+	if params.IncludeTotal {
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return nil, err
+		}
+		total := int(count)
+		totalItems = &total
+	}
+
+	var customers []models.Customer
+	if err := applyKeyset(query, customerSort, cursor, value, limit).Preload("Tags").Find(&customers).Error; err != nil {
+		return nil, err
+	}
+
+	page, err := keysetPage(customers, customerSort, cursor, limit, func(customer models.Customer) (interface{}, string) {
+		return customer.CreatedAt.Time, customer.ID.String()
+	})
+	if err != nil {
+		return nil, err
+	}
+	page.TotalItems = totalItems
+
+	return page, nil
+}
+
 func (s *CustomerService) GetCustomer(id string) (*models.Customer, error) {
-	return s.FindCustomerById(id)
+	customer := &models.Customer{}
+	err := s.database.Preload("Contacts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Preload("Tags").Where("id = ?", id).First(customer).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCustomerNotFound
+		}
+		return nil, err
+	}
+
+	return customer, nil
 }
 
 func (s *CustomerService) FindCustomerByEmail(email string) (*models.Customer, error) {
@@ -172,3 +499,35 @@ func (s *CustomerService) FindCustomersByIds(ids []string) ([]models.Customer, e
 	}
 	return customers, nil
 }
+
+func addressFromDto(payload *dto.AddressDto) (*models.Address, error) {
+	if payload == nil {
+		return nil, nil
+	}
+
+	country := strings.ToUpper(strings.TrimSpace(payload.Country))
+	if country != "" && !isCountryCode(country) {
+		return nil, ErrInvalidCountryCode
+	}
+
+	return &models.Address{
+		City:       strings.TrimSpace(payload.City),
+		Country:    country,
+		Line1:      strings.TrimSpace(payload.Line1),
+		Line2:      strings.TrimSpace(payload.Line2),
+		PostalCode: strings.TrimSpace(payload.PostalCode),
+		State:      strings.TrimSpace(payload.State),
+	}, nil
+}
+
+func isCountryCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,433 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/models"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ExpenseService struct {
+	database *gorm.DB
+}
+
+func NewExpenseService(database *gorm.DB) *ExpenseService {
+	return &ExpenseService{
+		database: database,
+	}
+}
+
+var (
+	ErrExpenseBilled          = errors.New("expense is already on an invoice")
+	ErrExpenseCurrency        = errors.New("expense currency does not match the invoice currency")
+	ErrExpenseNeedsCustomer   = errors.New("a billable expense needs a customer")
+	ErrExpenseNotBillable     = errors.New("expense is not an unbilled, billable expense of the invoice's customer")
+	ErrExpenseNotFound        = errors.New("expense not found")
+	ErrExpenseRebilled        = errors.New("some of the invoice's expenses have since been billed on another invoice or deleted")
+	ErrInvalidExpense         = errors.New("expense needs an amount above 0, a 3-letter currency and a markup between 0 and 1000 percent")
+	ErrInvalidExpenseCategory = errors.New("expense vendor can be at most 255 and category at most 100 characters")
+	ErrNoUnbilledExpenses     = errors.New("no unbilled expenses for this customer")
+)
+
+// unbilledExpenseCondition matches expenses that are not on an invoice, or
+// only on one that has since been archived.
+const unbilledExpenseCondition = "(expenses.invoice_id IS NULL OR expenses.invoice_id IN (SELECT id FROM invoices WHERE archived_at IS NOT NULL))"
+
+func (s *ExpenseService) CreateExpense(userId string, payload dto.CreateExpenseDto) (*models.Expense, error) {
+	expense := &models.Expense{
+		Amount:      payload.Amount,
+		Billable:    payload.Billable == nil || *payload.Billable,
+		Category:    strings.TrimSpace(payload.Category),
+		Currency:    strings.ToUpper(strings.TrimSpace(payload.Currency)),
+		Date:        entryDate(payload.Date),
+		Description: strings.TrimSpace(payload.Description),
+		Markup:      payload.Markup,
+		UserID:      uuid.MustParse(userId),
+		Vendor:      strings.TrimSpace(payload.Vendor),
+	}
+	if payload.CustomerID != "" {
+		customer, err := findActiveCustomer(s.database, payload.CustomerID)
+		if err != nil {
+			return nil, err
+		}
+		expense.CustomerID = &customer.ID
+	}
+	if err := validateExpense(expense); err != nil {
+		return nil, err
+	}
+
+	if err := s.database.Create(expense).Error; err != nil {
+		return nil, err
+	}
+
+	return expense, nil
+}
+
+func (s *ExpenseService) GetExpenses(userId string, filter dto.ExpenseFilter) ([]models.Expense, error) {
+	query := s.database.Where("user_id = ?", userId)
+	if filter.CustomerID != "" {
+		query = query.Where("customer_id = ?", filter.CustomerID)
+	}
+	if filter.Category != "" {
+		query = query.Where("LOWER(category) = LOWER(?)", filter.Category)
+	}
+	if filter.From != nil {
+		query = query.Where("date >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("date <= ?", *filter.To)
+	}
+	if filter.Billed != nil {
+		if *filter.Billed {
+			query = query.Where("NOT " + unbilledExpenseCondition)
+		} else {
+			query = query.Where(unbilledExpenseCondition)
+		}
+	}
+
+	var expenses []models.Expense
+	if err := query.Order("date DESC, created_at DESC").Find(&expenses).Error; err != nil {
+		return nil, err
+	}
+	return expenses, nil
+}
+
+func (s *ExpenseService) UpdateExpense(userId, id string, payload dto.UpdateExpenseDto) (*models.Expense, error) {
+	expense, err := s.findUnbilledExpense(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Amount != nil {
+		expense.Amount = *payload.Amount
+	}
+	if payload.Billable != nil {
+		expense.Billable = *payload.Billable
+	}
+	if payload.Category != nil {
+		expense.Category = strings.TrimSpace(*payload.Category)
+	}
+	if payload.Currency != nil {
+		expense.Currency = strings.ToUpper(strings.TrimSpace(*payload.Currency))
+	}
+	if payload.CustomerID != nil {
+		expense.CustomerID = nil
+		if *payload.CustomerID != "" {
+			customer, err := findActiveCustomer(s.database, *payload.CustomerID)
+			if err != nil {
+				return nil, err
+			}
+			expense.CustomerID = &customer.ID
+		}
+	}
+	if payload.Date != nil {
+		expense.Date = entryDate(*payload.Date)
+	}
+	if payload.Description != nil {
+		expense.Description = strings.TrimSpace(*payload.Description)
+	}
+	if payload.Markup != nil {
+		expense.Markup = *payload.Markup
+	}
+	if payload.Vendor != nil {
+		expense.Vendor = strings.TrimSpace(*payload.Vendor)
+	}
+	if err := validateExpense(expense); err != nil {
+		return nil, err
+	}
+
+	if err := s.database.Save(expense).Error; err != nil {
+		return nil, err
+	}
+
+	return expense, nil
+}
+
+func (s *ExpenseService) DeleteExpense(userId, id string) error {
+	expense, err := s.findUnbilledExpense(userId, id)
+	if err != nil {
+		return err
+	}
+
+	return s.database.Delete(expense).Error
+}
+
+// SetReceipt records where the expense's uploaded receipt is stored.
+func (s *ExpenseService) SetReceipt(userId, id, url string) (*models.Expense, error) {
+	expense, err := s.FindExpenseById(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	expense.ReceiptURL = url
+	if err := s.database.Model(expense).Update("receipt_url", url).Error; err != nil {
+		return nil, err
+	}
+
+	return expense, nil
+}
+
+func (s *ExpenseService) FindExpenseById(userId, id string) (*models.Expense, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrExpenseNotFound
+	}
+
+	expense := &models.Expense{}
+	if err := s.database.Where("id = ? AND user_id = ?", id, userId).First(expense).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExpenseNotFound
+		}
+		return nil, err
+	}
+	return expense, nil
+}
+
+// BillExpenses adds unbilled, billable expenses of the invoice's customer to
+// the invoice as line items, one per expense with its markup applied, and
+// links the expenses to the invoice.
+func (s *ExpenseService) BillExpenses(invoiceId, userId string, payload dto.BillExpensesDto) (*models.Invoice, error) {
+	var invoice *models.Invoice
+	err := s.database.Transaction(func(tx *gorm.DB) error {
+		invoices := NewInvoiceService(tx)
+
+		var err error
+		if invoice, err = invoices.FindInvoiceById(invoiceId); err != nil {
+			return err
+		}
+		if invoice.ArchivedAt.Valid {
+			return ErrInvoiceArchived
+		}
+		if invoice.Status == models.Paid {
+			return ErrInvoiceAlreadyPaid
+		}
+
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND customer_id = ? AND billable = ?", userId, invoice.CustomerID, true).
+			Where(unbilledExpenseCondition)
+		if len(payload.ExpenseIDs) > 0 {
+			for _, id := range payload.ExpenseIDs {
+				if _, err := uuid.Parse(id); err != nil {
+					return ErrExpenseNotBillable
+				}
+			}
+			query = query.Where("id IN ?", payload.ExpenseIDs)
+		}
+
+		var expenses []models.Expense
+		if err := query.Order("date ASC, created_at ASC").Find(&expenses).Error; err != nil {
+			return err
+		}
+		if len(expenses) == 0 {
+			return ErrNoUnbilledExpenses
+		}
+		if len(payload.ExpenseIDs) > 0 && len(expenses) != len(payload.ExpenseIDs) {
+			return ErrExpenseNotBillable
+		}
+		for _, expense := range expenses {
+			if !strings.EqualFold(expense.Currency, invoice.Currency) {
+				return ErrExpenseCurrency
+			}
+		}
+
+		items, err := invoices.buildInvoiceItems(userId, expenseInvoiceItems(expenses))
+		if err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].InvoiceID = invoice.ID
+			items[i].ExpenseID = &expenses[i].ID
+		}
+		existing := len(invoice.Items)
+		invoice.Items = append(invoice.Items, items...)
+		if err := checkInvoiceTax(invoice, invoice.Items); err != nil {
+			return err
+		}
+
+		installments, err := findInstallments(tx, invoice.ID.String())
+		if err != nil {
+			return err
+		}
+		invoices.calculateInvoiceTotals(invoice)
+		if err := scheduleInstallments(invoice, installments); err != nil {
+			return err
+		}
+
+		if err := saveVersioned(tx.Omit(clause.Associations), invoice, &invoice.Version); err != nil {
+			return err
+		}
+		for i := range invoice.Items {
+			if i < existing {
+				err = tx.Save(&invoice.Items[i]).Error
+			} else {
+				err = tx.Create(&invoice.Items[i]).Error
+			}
+			if err != nil {
+				return err
+			}
+		}
+		for i := range installments {
+			if err := tx.Save(&installments[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		ids := make([]uuid.UUID, len(expenses))
+		for i, expense := range expenses {
+			ids[i] = expense.ID
+		}
+		return tx.Model(&models.Expense{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"billed_at":  time.Now(),
+			"invoice_id": invoice.ID,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.database.Preload("Customer").Preload("Items").Preload("Installments", orderInstallments).First(invoice, invoice.ID).Error; err != nil {
+		return nil, err
+	}
+
+	return invoice, nil
+}
+
+// linkExpenseLines carries the expense links over to an invoice's new lines.
+// A submitted line keeps billing its expense by sending back the expenseId
+// of the line it replaces; the expenses whose lines are dropped become
+// unbilled again.
+func linkExpenseLines(tx *gorm.DB, invoiceId uuid.UUID, payload []dto.CreateInvoiceItemDto, items []models.InvoiceItem) error {
+	kept := []uuid.UUID{}
+	for i, item := range payload {
+		if item.ExpenseID == "" {
+			continue
+		}
+		id, err := uuid.Parse(item.ExpenseID)
+		if err != nil || slices.Contains(kept, id) {
+			return ErrExpenseNotBillable
+		}
+		items[i].ExpenseID = &id
+		kept = append(kept, id)
+	}
+
+	if len(kept) > 0 {
+		var linked int64
+		err := tx.Model(&models.Expense{}).Where("id IN ? AND invoice_id = ?", kept, invoiceId).Count(&linked).Error
+		if err != nil {
+			return err
+		}
+		if int(linked) != len(kept) {
+			return ErrExpenseNotBillable
+		}
+	}
+
+	released := tx.Model(&models.Expense{}).Where("invoice_id = ?", invoiceId)
+	if len(kept) > 0 {
+		released = released.Where("id NOT IN ?", kept)
+	}
+	return released.Updates(map[string]interface{}{
+		"billed_at":  nil,
+		"invoice_id": nil,
+	}).Error
+}
+
+// checkExpenseLines makes sure every expense billed on an archived invoice
+// is still linked to it, so restoring the invoice cannot bill an expense
+// that was released and billed again in the meantime.
+func checkExpenseLines(tx *gorm.DB, invoice *models.Invoice) error {
+	ids := []uuid.UUID{}
+	for _, item := range invoice.Items {
+		if item.ExpenseID != nil && !slices.Contains(ids, *item.ExpenseID) {
+			ids = append(ids, *item.ExpenseID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var expenses []models.Expense
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ? AND invoice_id = ?", ids, invoice.ID).
+		Find(&expenses).Error
+	if err != nil {
+		return err
+	}
+	if len(expenses) != len(ids) {
+		return ErrExpenseRebilled
+	}
+	return nil
+}
+
+// findUnbilledExpense finds an expense that can still be changed, that is
+// one not on a live invoice.
+func (s *ExpenseService) findUnbilledExpense(userId, id string) (*models.Expense, error) {
+	expense, err := s.FindExpenseById(userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if expense.InvoiceID == nil {
+		return expense, nil
+	}
+
+	var billed int64
+	err = s.database.Model(&models.Invoice{}).
+		Where("id = ? AND archived_at IS NULL", *expense.InvoiceID).
+		Count(&billed).Error
+	if err != nil {
+		return nil, err
+	}
+	if billed > 0 {
+		return nil, ErrExpenseBilled
+	}
+	return expense, nil
+}
+
+func validateExpense(expense *models.Expense) error {
+	if expense.Amount <= 0 || len(expense.Currency) != 3 || expense.Markup < 0 || expense.Markup > 1000 {
+		return ErrInvalidExpense
+	}
+	if len([]rune(expense.Vendor)) > 255 || len([]rune(expense.Category)) > 100 {
+		return ErrInvalidExpenseCategory
+	}
+	if expense.Billable && expense.CustomerID == nil {
+		return ErrExpenseNeedsCustomer
+	}
+	return nil
+}
+
+// expenseInvoiceItems turns expenses into single-unit invoice lines priced at
+// the amount plus markup.
+func expenseInvoiceItems(expenses []models.Expense) []dto.CreateInvoiceItemDto {
+	items := make([]dto.CreateInvoiceItemDto, 0, len(expenses))
+	for _, expense := range expenses {
+		items = append(items, dto.CreateInvoiceItemDto{
+			Description: expenseLineDescription(expense),
+			Price:       roundAmount(expense.Amount * (1 + expense.Markup/100)),
+			Quantity:    decimal.NewFromInt(1),
+			Unit:        "C62",
+		})
+	}
+	return items
+}
+
+func expenseLineDescription(expense models.Expense) string {
+	label := expense.Vendor
+	if label == "" {
+		label = "Expense"
+	}
+	if expense.Category != "" {
+		label = fmt.Sprintf("%s (%s)", label, expense.Category)
+	}
+	label += ", " + expense.Date.Format("Jan 2, 2006")
+	if expense.Description != "" {
+		label += ": " + expense.Description
+	}
+	return label
+}
@@ -0,0 +1,556 @@
+package services
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"invoicer-go/m/src/models"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrFacturXCountryRequired = errors.New("Factur-X needs a country on both your business address and the customer's billing address")
+	ErrFacturXUnsupportedTax  = errors.New("Factur-X supports one VAT rate per line charged on the line amount; compound, stacked or fixed taxes and discounts given after tax cannot be exported")
+)
+
+const (
+	facturXFilename       = "factur-x.xml"
+	facturXProfile        = "EN 16931"
+	facturXGuidelineID    = "urn:cen.eu:en16931:2017"
+	facturXDateFormat     = "102"
+	facturXInvoiceType    = "380"
+	facturXDefaultUnit    = "C62"
+	facturXTaxTypeCode    = "VAT"
+	facturXVATScheme      = "VA"
+	facturXTaxScheme      = "FC"
+	facturXStandardRate   = "S"
+	facturXZeroRated      = "Z"
+	facturXExempt         = "E"
+	facturXExemptReason   = "Exempt from VAT"
+	facturXSEPATransfer   = "58"
+	facturXCreditTransfer = "30"
+)
+
+type ciiInvoice struct {
+	XMLName     xml.Name       `xml:"rsm:CrossIndustryInvoice"`
+	XmlnsRsm    string         `xml:"xmlns:rsm,attr"`
+	XmlnsRam    string         `xml:"xmlns:ram,attr"`
+	XmlnsUdt    string         `xml:"xmlns:udt,attr"`
+	XmlnsQdt    string         `xml:"xmlns:qdt,attr"`
+	Context     ciiContext     `xml:"rsm:ExchangedDocumentContext"`
+	Document    ciiDocument    `xml:"rsm:ExchangedDocument"`
+	Transaction ciiTransaction `xml:"rsm:SupplyChainTradeTransaction"`
+}
+
+type ciiContext struct {
+	GuidelineID string `xml:"ram:GuidelineSpecifiedDocumentContextParameter>ram:ID"`
+}
+
+type ciiDocument struct {
+	ID        string      `xml:"ram:ID"`
+	TypeCode  string      `xml:"ram:TypeCode"`
+	IssueDate ciiDateTime `xml:"ram:IssueDateTime"`
+	Notes     []ciiNote   `xml:"ram:IncludedNote,omitempty"`
+}
+
+type ciiNote struct {
+	Content string `xml:"ram:Content"`
+}
+
+type ciiDateTime struct {
+	Value ciiDateString `xml:"udt:DateTimeString"`
+}
+
+type ciiDateString struct {
+	Format string `xml:"format,attr"`
+	Value  string `xml:",chardata"`
+}
+
+type ciiTransaction struct {
+	Lines      []ciiLineItem       `xml:"ram:IncludedSupplyChainTradeLineItem"`
+	Agreement  ciiAgreement        `xml:"ram:ApplicableHeaderTradeAgreement"`
+	Delivery   struct{}            `xml:"ram:ApplicableHeaderTradeDelivery"`
+	Settlement ciiHeaderSettlement `xml:"ram:ApplicableHeaderTradeSettlement"`
+}
+
+type ciiLineItem struct {
+	LineID     string            `xml:"ram:AssociatedDocumentLineDocument>ram:LineID"`
+	Product    ciiProduct        `xml:"ram:SpecifiedTradeProduct"`
+	NetPrice   string            `xml:"ram:SpecifiedLineTradeAgreement>ram:NetPriceProductTradePrice>ram:ChargeAmount"`
+	Quantity   ciiQuantity       `xml:"ram:SpecifiedLineTradeDelivery>ram:BilledQuantity"`
+	Settlement ciiLineSettlement `xml:"ram:SpecifiedLineTradeSettlement"`
+}
+
+type ciiProduct struct {
+	Name string `xml:"ram:Name"`
+}
+
+type ciiQuantity struct {
+	UnitCode string `xml:"unitCode,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type ciiLineSettlement struct {
+	Tax       ciiLineTax `xml:"ram:ApplicableTradeTax"`
+	LineTotal string     `xml:"ram:SpecifiedTradeSettlementLineMonetarySummation>ram:LineTotalAmount"`
+}
+
+type ciiLineTax struct {
+	TypeCode     string `xml:"ram:TypeCode"`
+	CategoryCode string `xml:"ram:CategoryCode"`
+	Rate         string `xml:"ram:RateApplicablePercent"`
+}
+
+type ciiAgreement struct {
+	Seller ciiParty `xml:"ram:SellerTradeParty"`
+	Buyer  ciiParty `xml:"ram:BuyerTradeParty"`
+}
+
+type ciiParty struct {
+	Name              string                `xml:"ram:Name"`
+	LegalOrganization *ciiLegalOrganization `xml:"ram:SpecifiedLegalOrganization,omitempty"`
+	Contact           *ciiContact           `xml:"ram:DefinedTradeContact,omitempty"`
+	Address           *ciiAddress           `xml:"ram:PostalTradeAddress,omitempty"`
+	Email             *ciiEmail             `xml:"ram:URIUniversalCommunication,omitempty"`
+	TaxRegistration   *ciiTaxRegistration   `xml:"ram:SpecifiedTaxRegistration,omitempty"`
+}
+
+type ciiLegalOrganization struct {
+	ID string `xml:"ram:ID"`
+}
+
+type ciiContact struct {
+	PersonName string    `xml:"ram:PersonName,omitempty"`
+	Phone      *ciiPhone `xml:"ram:TelephoneUniversalCommunication,omitempty"`
+}
+
+type ciiPhone struct {
+	Number string `xml:"ram:CompleteNumber"`
+}
+
+type ciiAddress struct {
+	PostalCode  string `xml:"ram:PostcodeCode,omitempty"`
+	LineOne     string `xml:"ram:LineOne,omitempty"`
+	LineTwo     string `xml:"ram:LineTwo,omitempty"`
+	City        string `xml:"ram:CityName,omitempty"`
+	CountryID   string `xml:"ram:CountryID"`
+	Subdivision string `xml:"ram:CountrySubDivisionName,omitempty"`
+}
+
+type ciiEmail struct {
+	URI ciiSchemedID `xml:"ram:URIID"`
+}
+
+type ciiTaxRegistration struct {
+	ID ciiSchemedID `xml:"ram:ID"`
+}
+
+type ciiSchemedID struct {
+	SchemeID string `xml:"schemeID,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type ciiHeaderSettlement struct {
+	Currency       string               `xml:"ram:InvoiceCurrencyCode"`
+	PaymentMeans   *ciiPaymentMeans     `xml:"ram:SpecifiedTradeSettlementPaymentMeans,omitempty"`
+	Taxes          []ciiHeaderTax       `xml:"ram:ApplicableTradeTax"`
+	Allowances     []ciiAllowance       `xml:"ram:SpecifiedTradeAllowanceCharge,omitempty"`
+	PaymentTerms   *ciiPaymentTerms     `xml:"ram:SpecifiedTradePaymentTerms,omitempty"`
+	MonetaryTotals ciiMonetarySummation `xml:"ram:SpecifiedTradeSettlementHeaderMonetarySummation"`
+}
+
+type ciiPaymentMeans struct {
+	TypeCode string                `xml:"ram:TypeCode"`
+	Account  *ciiCreditorAccount   `xml:"ram:PayeePartyCreditorFinancialAccount,omitempty"`
+	Bank     *ciiCreditorInstitute `xml:"ram:PayeeSpecifiedCreditorFinancialInstitution,omitempty"`
+}
+
+type ciiCreditorAccount struct {
+	IBAN          string `xml:"ram:IBANID,omitempty"`
+	AccountName   string `xml:"ram:AccountName,omitempty"`
+	ProprietaryID string `xml:"ram:ProprietaryID,omitempty"`
+}
+
+type ciiCreditorInstitute struct {
+	BIC string `xml:"ram:BICID"`
+}
+
+type ciiHeaderTax struct {
+	CalculatedAmount string `xml:"ram:CalculatedAmount"`
+	TypeCode         string `xml:"ram:TypeCode"`
+	ExemptionReason  string `xml:"ram:ExemptionReason,omitempty"`
+	BasisAmount      string `xml:"ram:BasisAmount"`
+	CategoryCode     string `xml:"ram:CategoryCode"`
+	Rate             string `xml:"ram:RateApplicablePercent"`
+}
+
+type ciiAllowance struct {
+	Indicator   bool       `xml:"ram:ChargeIndicator>udt:Indicator"`
+	Amount      string     `xml:"ram:ActualAmount"`
+	Reason      string     `xml:"ram:Reason,omitempty"`
+	CategoryTax ciiLineTax `xml:"ram:CategoryTradeTax"`
+}
+
+type ciiPaymentTerms struct {
+	Description string       `xml:"ram:Description,omitempty"`
+	DueDate     *ciiDateTime `xml:"ram:DueDateDateTime,omitempty"`
+}
+
+type ciiMonetarySummation struct {
+	LineTotal      string    `xml:"ram:LineTotalAmount"`
+	AllowanceTotal string    `xml:"ram:AllowanceTotalAmount"`
+	TaxBasisTotal  string    `xml:"ram:TaxBasisTotalAmount"`
+	TaxTotal       ciiAmount `xml:"ram:TaxTotalAmount"`
+	GrandTotal     string    `xml:"ram:GrandTotalAmount"`
+	TotalPrepaid   string    `xml:"ram:TotalPrepaidAmount,omitempty"`
+	DuePayable     string    `xml:"ram:DuePayableAmount"`
+}
+
+type ciiAmount struct {
+	Currency string `xml:"currencyID,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// BuildFacturXDocument renders the invoice as a Cross Industry Invoice using
+// the Factur-X EN 16931 profile. The figures are taken from the invoice as
+// stored, so the XML always agrees with the PDF it is embedded in.
+func BuildFacturXDocument(invoice *models.Invoice, seller *models.User) ([]byte, error) {
+	billingAddress := invoiceBillingAddress(invoice)
+	if seller.Address == nil || seller.Address.Country == "" || billingAddress == nil || billingAddress.Country == "" {
+		return nil, ErrFacturXCountryRequired
+	}
+
+	discountAmount := roundAmount(invoiceDiscountAmount(invoice))
+	lineTotal := roundAmount(invoice.SubTotal)
+	groups, lineGroups, mainGroup, err := facturXTaxGroups(invoice, discountAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	taxAmount, taxBasis := 0.0, 0.0
+	for _, group := range groups {
+		taxAmount += group.amount
+		taxBasis += group.basis
+	}
+	taxAmount, taxBasis = roundAmount(taxAmount), roundAmount(taxBasis)
+
+	doc := ciiInvoice{
+		XmlnsRsm: "urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100",
+		XmlnsRam: "urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100",
+		XmlnsUdt: "urn:un:unece:uncefact:data:standard:UnqualifiedDataType:100",
+		XmlnsQdt: "urn:un:unece:uncefact:data:standard:QualifiedDataType:100",
+		Context:  ciiContext{GuidelineID: facturXGuidelineID},
+		Document: ciiDocument{
+			ID:        invoice.ReferenceNo,
+			TypeCode:  facturXInvoiceType,
+			IssueDate: ciiDate(invoice.DateIssued),
+		},
+	}
+
+	if invoice.Note != "" {
+		doc.Document.Notes = append(doc.Document.Notes, ciiNote{Content: invoice.Note})
+	}
+
+	for i, item := range invoice.Items {
+		lineTax := groups[lineGroups[i]].lineTax()
+
+		// The net price is what the line is actually charged per unit, after
+		// its discount and without any tax included in the price.
+		netPrice := item.Price
+		if !item.Quantity.IsZero() {
+			netPrice = item.LineTotal / item.Quantity.InexactFloat64()
+		}
+		unit := item.Unit
+		if unit == "" {
+			unit = facturXDefaultUnit
+		}
+		doc.Transaction.Lines = append(doc.Transaction.Lines, ciiLineItem{
+			LineID:   strconv.Itoa(i + 1),
+			Product:  ciiProduct{Name: item.Description},
+			NetPrice: formatAmount(netPrice),
+			Quantity: ciiQuantity{UnitCode: unit, Value: item.Quantity.String()},
+			Settlement: ciiLineSettlement{
+				Tax:       lineTax,
+				LineTotal: formatAmount(item.LineTotal),
+			},
+		})
+	}
+
+	doc.Transaction.Agreement = ciiAgreement{
+		Seller: sellerParty(seller),
+		Buyer:  buyerParty(&invoice.Customer, billingAddress),
+	}
+
+	settlement := ciiHeaderSettlement{
+		Currency:     invoice.Currency,
+		PaymentMeans: sellerPaymentMeans(seller),
+		MonetaryTotals: ciiMonetarySummation{
+			LineTotal:      formatAmount(lineTotal),
+			AllowanceTotal: formatAmount(discountAmount),
+			TaxBasisTotal:  formatAmount(taxBasis),
+			TaxTotal:       ciiAmount{Currency: invoice.Currency, Value: formatAmount(taxAmount)},
+			GrandTotal:     formatAmount(invoice.Total + invoice.WithholdingTotal),
+			DuePayable:     formatAmount(invoice.Total),
+		},
+	}
+	// CII has no withholding tax, so tax withheld by the customer is reported
+	// as already paid against the grand total.
+	if invoice.WithholdingTotal != 0 {
+		settlement.MonetaryTotals.TotalPrepaid = formatAmount(invoice.WithholdingTotal)
+	}
+
+	for _, group := range groups {
+		lineTax := group.lineTax()
+		settlement.Taxes = append(settlement.Taxes, ciiHeaderTax{
+			CalculatedAmount: formatAmount(group.amount),
+			TypeCode:         lineTax.TypeCode,
+			ExemptionReason:  group.exemptionReason(),
+			BasisAmount:      formatAmount(group.basis),
+			CategoryCode:     lineTax.CategoryCode,
+			Rate:             lineTax.Rate,
+		})
+	}
+
+	if discountAmount != 0 {
+		settlement.Allowances = append(settlement.Allowances, ciiAllowance{
+			Indicator:   false,
+			Amount:      formatAmount(discountAmount),
+			Reason:      "Discount",
+			CategoryTax: groups[mainGroup].lineTax(),
+		})
+	}
+
+	if !invoice.DateDue.IsZero() {
+		dueDate := ciiDate(invoice.DateDue)
+		settlement.PaymentTerms = &ciiPaymentTerms{Description: invoice.PaymentTerms, DueDate: &dueDate}
+	}
+
+	doc.Transaction.Settlement = settlement
+
+	output, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), output...), nil
+}
+
+// facturXTaxGroup is one VAT breakdown entry: the lines taxed in the same
+// category at the same rate.
+type facturXTaxGroup struct {
+	amount   float64
+	basis    float64
+	category string
+	lines    int
+	rate     float64
+}
+
+func (g facturXTaxGroup) lineTax() ciiLineTax {
+	return ciiLineTax{
+		TypeCode:     facturXTaxTypeCode,
+		CategoryCode: g.category,
+		Rate:         formatDecimal(g.rate),
+	}
+}
+
+// exemptionReason is required on the breakdown of exempt lines.
+func (g facturXTaxGroup) exemptionReason() string {
+	if g.category == facturXExempt {
+		return facturXExemptReason
+	}
+	return ""
+}
+
+// facturXTaxGroups puts each line in the VAT category and rate it is taxed
+// at and returns the group of each line. The invoice level discount and tax
+// are booked against the group with the largest basis, whose index is
+// returned last.
+//
+// A line in CII carries exactly one VAT rate, so lines with more than one
+// tax that is not withheld, or with a compound tax, cannot be exported, and
+// neither can a group whose tax is not its rate of its basis, give or take
+// the cent of rounding on each line. That is the case for a fixed invoice
+// level tax, or for an invoice level discount given after tax.
+func facturXTaxGroups(invoice *models.Invoice, discountAmount float64) ([]facturXTaxGroup, []int, int, error) {
+	itemTaxes := false
+	for _, item := range invoice.Items {
+		itemTaxes = itemTaxes || len(item.Taxes) > 0
+	}
+	if !itemTaxes && invoice.TaxType == models.Fixed && invoice.Tax != 0 {
+		return nil, nil, 0, ErrFacturXUnsupportedTax
+	}
+
+	groups := []facturXTaxGroup{}
+	lineGroups := make([]int, len(invoice.Items))
+	for i, item := range invoice.Items {
+		category, rate, err := facturXLineCategory(invoice, item, itemTaxes)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+
+		index := -1
+		for g := range groups {
+			if groups[g].category == category && groups[g].rate == rate {
+				index = g
+			}
+		}
+		if index < 0 {
+			groups = append(groups, facturXTaxGroup{category: category, rate: rate})
+			index = len(groups) - 1
+		}
+		groups[index].basis += item.LineTotal
+		groups[index].amount += item.TaxAmount
+		groups[index].lines++
+		lineGroups[i] = index
+	}
+	if len(groups) == 0 {
+		category, rate, _ := facturXLineCategory(invoice, models.InvoiceItem{}, false)
+		groups = append(groups, facturXTaxGroup{category: category, rate: rate})
+	}
+
+	main := 0
+	for g := range groups {
+		if groups[g].basis > groups[main].basis {
+			main = g
+		}
+	}
+	groups[main].basis -= discountAmount
+	groups[main].amount += invoiceTaxAmount(invoice)
+
+	for g := range groups {
+		groups[g].basis = roundAmount(groups[g].basis)
+		groups[g].amount = roundAmount(groups[g].amount)
+
+		tolerance := 0.005*float64(max(groups[g].lines, 1)) + 0.005
+		if math.Abs(groups[g].basis*groups[g].rate/100-groups[g].amount) > tolerance {
+			return nil, nil, 0, ErrFacturXUnsupportedTax
+		}
+	}
+	return groups, lineGroups, main, nil
+}
+
+// facturXLineCategory returns the VAT category and rate of a line: standard
+// rated for a positive rate, zero rated for an explicit 0% tax and exempt
+// when no tax is charged at all. Withholding taxes are reported separately
+// and play no part here.
+func facturXLineCategory(invoice *models.Invoice, item models.InvoiceItem, itemTaxes bool) (string, float64, error) {
+	rate, taxed := 0.0, false
+	if itemTaxes {
+		for _, tax := range item.Taxes {
+			if tax.Withholding {
+				continue
+			}
+			if taxed || tax.Compound {
+				return "", 0, ErrFacturXUnsupportedTax
+			}
+			rate, taxed = tax.Rate, true
+		}
+	} else if invoice.Tax != 0 {
+		rate, taxed = invoiceTaxRate(invoice), true
+	}
+
+	switch {
+	case !taxed:
+		return facturXExempt, 0, nil
+	case rate == 0:
+		return facturXZeroRated, 0, nil
+	default:
+		return facturXStandardRate, rate, nil
+	}
+}
+
+func sellerParty(seller *models.User) ciiParty {
+	party := ciiParty{Name: seller.CompanyName, Address: ciiPostalAddress(seller.Address)}
+	if party.Name == "" {
+		party.Name = seller.Name
+	}
+	if seller.RcNumber != "" {
+		party.LegalOrganization = &ciiLegalOrganization{ID: seller.RcNumber}
+	}
+	if seller.Name != "" || seller.Phone != "" {
+		party.Contact = &ciiContact{PersonName: seller.Name}
+		if seller.Phone != "" {
+			party.Contact.Phone = &ciiPhone{Number: seller.Phone}
+		}
+	}
+	if seller.Email != "" {
+		party.Email = &ciiEmail{URI: ciiSchemedID{SchemeID: "EM", Value: seller.Email}}
+	}
+	if seller.TaxId != "" {
+		party.TaxRegistration = &ciiTaxRegistration{ID: ciiSchemedID{SchemeID: taxIdScheme(seller.TaxId), Value: seller.TaxId}}
+	}
+	return party
+}
+
+func buyerParty(customer *models.Customer, address *models.Address) ciiParty {
+	party := ciiParty{Name: customer.Name, Address: ciiPostalAddress(address)}
+	if customer.Email != "" {
+		party.Email = &ciiEmail{URI: ciiSchemedID{SchemeID: "EM", Value: customer.Email}}
+	}
+	return party
+}
+
+func ciiPostalAddress(address *models.Address) *ciiAddress {
+	if address == nil || address.Country == "" {
+		return nil
+	}
+	return &ciiAddress{
+		PostalCode:  address.PostalCode,
+		LineOne:     address.Line1,
+		LineTwo:     address.Line2,
+		City:        address.City,
+		CountryID:   address.Country,
+		Subdivision: address.State,
+	}
+}
+
+// taxIdScheme reports a tax id as a VAT identifier only when it starts with
+// a country code, as EN 16931 requires of those (BR-CO-09); anything else is
+// a local tax registration number.
+func taxIdScheme(taxId string) string {
+	if len(taxId) > 2 && isCountryCode(strings.ToUpper(taxId[:2])) {
+		return facturXVATScheme
+	}
+	return facturXTaxScheme
+}
+
+func sellerPaymentMeans(seller *models.User) *ciiPaymentMeans {
+	bank := seller.BankInformation
+	if bank == nil || (bank.Iban == "" && bank.AccountNumber == "") {
+		return nil
+	}
+
+	means := &ciiPaymentMeans{
+		TypeCode: facturXCreditTransfer,
+		Account:  &ciiCreditorAccount{AccountName: bank.AccountName},
+	}
+	if bank.Iban != "" {
+		means.TypeCode = facturXSEPATransfer
+		means.Account.IBAN = bank.Iban
+	} else {
+		means.Account.ProprietaryID = bank.AccountNumber
+	}
+	if bank.BankSwiftCode != "" {
+		means.Bank = &ciiCreditorInstitute{BIC: bank.BankSwiftCode}
+	}
+	return means
+}
+
+func ciiDate(t time.Time) ciiDateTime {
+	return ciiDateTime{Value: ciiDateString{Format: facturXDateFormat, Value: t.Format("20060102")}}
+}
+
+func roundAmount(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+func formatAmount(value float64) string {
+	return fmt.Sprintf("%.2f", roundAmount(value))
+}
+
+func formatDecimal(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
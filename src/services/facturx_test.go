@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"invoicer-go/m/src/models"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestFacturXTaxGroups(t *testing.T) {
+	line := func(price float64, taxes ...models.ItemTax) models.InvoiceItem {
+		return models.InvoiceItem{Price: price, Quantity: decimal.NewFromInt(1), Taxes: taxes}
+	}
+	vat := models.ItemTax{Name: "VAT", Rate: 7.5}
+
+	type group struct {
+		category string
+		rate     float64
+		basis    float64
+		amount   float64
+	}
+	tests := []struct {
+		name       string
+		invoice    models.Invoice
+		wantGroups []group
+		wantErr    error
+	}{
+		{
+			name:       "one group per rate and category",
+			invoice:    models.Invoice{Items: []models.InvoiceItem{line(100, vat), line(50, vat), line(20)}},
+			wantGroups: []group{{"S", 7.5, 150, 11.25}, {"E", 0, 20, 0}},
+		},
+		{
+			name:       "explicit zero rate is zero rated",
+			invoice:    models.Invoice{Items: []models.InvoiceItem{line(40, models.ItemTax{Name: "VAT", Rate: 0})}},
+			wantGroups: []group{{"Z", 0, 40, 0}},
+		},
+		{
+			name:       "inclusive tax reports the net basis",
+			invoice:    models.Invoice{Items: []models.InvoiceItem{line(107.5, models.ItemTax{Name: "VAT", Rate: 7.5, Inclusive: true})}},
+			wantGroups: []group{{"S", 7.5, 100, 7.5}},
+		},
+		{
+			name:       "withholding is left out of the VAT breakdown",
+			invoice:    models.Invoice{Items: []models.InvoiceItem{line(100, vat, models.ItemTax{Name: "WHT", Rate: 5, Withholding: true})}},
+			wantGroups: []group{{"S", 7.5, 100, 7.5}},
+		},
+		{
+			name: "invoice level percentage tax",
+			invoice: models.Invoice{
+				Tax:     10,
+				TaxType: models.Percentage,
+				Items:   []models.InvoiceItem{line(30), line(70)},
+			},
+			wantGroups: []group{{"S", 10, 100, 10}},
+		},
+		{
+			name:    "stacked taxes on a line are rejected",
+			invoice: models.Invoice{Items: []models.InvoiceItem{line(100, vat, models.ItemTax{Name: "Levy", Rate: 2})}},
+			wantErr: ErrFacturXUnsupportedTax,
+		},
+		{
+			name:    "compound taxes are rejected",
+			invoice: models.Invoice{Items: []models.InvoiceItem{line(100, models.ItemTax{Name: "Levy", Rate: 2, Compound: true})}},
+			wantErr: ErrFacturXUnsupportedTax,
+		},
+		{
+			name: "fixed invoice level tax is rejected",
+			invoice: models.Invoice{
+				Tax:     15,
+				TaxType: models.Fixed,
+				Items:   []models.InvoiceItem{line(100)},
+			},
+			wantErr: ErrFacturXUnsupportedTax,
+		},
+		{
+			name: "discount given after line taxes is rejected",
+			invoice: models.Invoice{
+				Discount:     50,
+				DiscountType: models.Fixed,
+				Items:        []models.InvoiceItem{line(100, vat)},
+			},
+			wantErr: ErrFacturXUnsupportedTax,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := tt.invoice
+			NewInvoiceService(nil).calculateInvoiceTotals(&invoice)
+
+			groups, _, _, err := facturXTaxGroups(&invoice, roundAmount(invoiceDiscountAmount(&invoice)))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(groups) != len(tt.wantGroups) {
+				t.Fatalf("got %d groups, want %d", len(groups), len(tt.wantGroups))
+			}
+			for i, want := range tt.wantGroups {
+				got := groups[i]
+				if got.category != want.category || got.rate != want.rate ||
+					!amountsEqual(got.basis, want.basis) || !amountsEqual(got.amount, want.amount) {
+					t.Errorf("group %d = %s %v%% of %v is %v, want %s %v%% of %v is %v", i,
+						got.category, got.rate, got.basis, got.amount, want.category, want.rate, want.basis, want.amount)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"invoicer-go/m/src/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyService struct {
+	database *gorm.DB
+}
+
+func NewIdempotencyService(database *gorm.DB) *IdempotencyService {
+	return &IdempotencyService{
+		database: database,
+	}
+}
+
+var (
+	ErrIdempotencyKeyInFlight = errors.New("a request with this idempotency key is still being processed")
+	ErrIdempotencyKeyReused   = errors.New("this idempotency key was already used for a different request")
+	ErrInvalidIdempotencyKey  = errors.New("idempotency key must be between 1 and 255 characters")
+)
+
+const (
+	// IdempotencyKeyTTL is how long a response is kept for replay.
+	IdempotencyKeyTTL = 24 * time.Hour
+	// idempotencyLockTimeout is how long a request can hold its key before
+	// it is treated as abandoned and a retry may take the key over.
+	idempotencyLockTimeout = 5 * time.Minute
+)
+
+// Claim takes the user's key for a request. It returns the new claim and
+// true when the request should go ahead, or the stored record and false when
+// its response should be replayed instead.
+func (s *IdempotencyService) Claim(userId, key, fingerprint string) (*models.IdempotencyKey, bool, error) {
+	if key == "" || len(key) > 255 {
+		return nil, false, ErrInvalidIdempotencyKey
+	}
+
+	now := time.Now()
+	err := s.database.
+		Where("user_id = ? AND key = ?", userId, key).
+		Where("expires_at < ? OR (response_status = 0 AND created_at < ?)", now, now.Add(-idempotencyLockTimeout)).
+		Delete(&models.IdempotencyKey{}).Error
+	if err != nil {
+		return nil, false, err
+	}
+
+	record := &models.IdempotencyKey{
+		ExpiresAt:   now.Add(IdempotencyKeyTTL),
+		Fingerprint: fingerprint,
+		Key:         key,
+		UserID:      uuid.MustParse(userId),
+	}
+	result := s.database.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return record, true, nil
+	}
+
+	existing := &models.IdempotencyKey{}
+	if err := s.database.Where("user_id = ? AND key = ?", userId, key).First(existing).Error; err != nil {
+		return nil, false, err
+	}
+	if existing.Fingerprint != fingerprint {
+		return nil, false, ErrIdempotencyKeyReused
+	}
+	if existing.ResponseStatus == 0 {
+		return nil, false, ErrIdempotencyKeyInFlight
+	}
+	return existing, false, nil
+}
+
+// Complete stores the response to replay for the claimed key.
+func (s *IdempotencyService) Complete(record *models.IdempotencyKey, status int, contentType string, body []byte) error {
+	record.ContentType = contentType
+	record.ResponseBody = body
+	record.ResponseStatus = status
+	return s.database.Save(record).Error
+}
+
+// Release gives up a claim, so the request can be retried with the same key.
+func (s *IdempotencyService) Release(record *models.IdempotencyKey) error {
+	return s.database.Delete(record).Error
+}
+
+// PurgeExpired deletes the keys that can no longer be replayed.
+func (s *IdempotencyService) PurgeExpired(now time.Time) (int64, error) {
+	result := s.database.Where("expires_at < ?", now).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
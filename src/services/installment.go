@@ -0,0 +1,190 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/models"
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidInstallment    = errors.New("each installment needs either an amount or a percentage")
+	ErrInstallmentDueDate    = errors.New("each installment needs a due date and the invoice is due on the last one")
+	ErrInstallmentsMismatch  = errors.New("payment schedule must add up to the invoice total")
+	ErrInvalidInstallmentPct = errors.New("installment percentage must be between 0 and 100")
+)
+
+// installmentOverdueCondition matches pending invoices with an amount due
+// that has not been paid: past the due date when there is no schedule, or
+// behind on the installments due so far when there is one.
+const installmentOverdueCondition = `(NOT EXISTS (SELECT 1 FROM invoice_installments ii WHERE ii.invoice_id = invoices.id) AND invoices.date_due < @now)
+OR (SELECT COALESCE(SUM(ii.amount), 0) FROM invoice_installments ii WHERE ii.invoice_id = invoices.id AND ii.due_date < @now)
+	> (SELECT COALESCE(SUM(p.amount), 0) FROM payments p WHERE p.invoice_id = invoices.id) + @tolerance`
+
+// buildInstallments validates the submitted schedule and orders it by due
+// date. Amounts for percentage based installments are filled in by
+// scheduleInstallments once the invoice total is known.
+func buildInstallments(payload []dto.InstallmentDto) ([]models.InvoiceInstallment, error) {
+	installments := make([]models.InvoiceInstallment, 0, len(payload))
+	for _, item := range payload {
+		if (item.Amount == nil) == (item.Percentage == nil) {
+			return nil, ErrInvalidInstallment
+		}
+		if item.DueDate.IsZero() {
+			return nil, ErrInstallmentDueDate
+		}
+
+		installment := models.InvoiceInstallment{
+			DueDate: item.DueDate,
+			Label:   item.Label,
+		}
+		if item.Percentage != nil {
+			if *item.Percentage <= 0 || *item.Percentage > 100 {
+				return nil, ErrInvalidInstallmentPct
+			}
+			percentage := *item.Percentage
+			installment.Percentage = &percentage
+		} else {
+			if *item.Amount <= 0 {
+				return nil, fmt.Errorf("%w: amount must be greater than zero", ErrInvalidInstallment)
+			}
+			installment.Amount = roundAmount(*item.Amount)
+		}
+		installments = append(installments, installment)
+	}
+
+	sort.SliceStable(installments, func(i, j int) bool {
+		return installments[i].DueDate.Before(installments[j].DueDate)
+	})
+	for i := range installments {
+		installments[i].Position = i + 1
+	}
+	return installments, nil
+}
+
+// scheduleInstallments works out each installment's amount from the invoice
+// total and checks that the schedule adds up to it. The invoice becomes due
+// in full on the date of its last installment.
+func scheduleInstallments(invoice *models.Invoice, installments []models.InvoiceInstallment) error {
+	if len(installments) == 0 {
+		return nil
+	}
+
+	total := roundAmount(invoice.Total)
+	sum := 0.0
+	last := -1
+	for i := range installments {
+		if installments[i].Percentage != nil {
+			installments[i].Amount = roundAmount(total * *installments[i].Percentage / 100)
+			last = i
+		}
+		sum += installments[i].Amount
+	}
+
+	// Rounding each percentage share to cents can leave a cent or two over or
+	// under the total; the last percentage based installment absorbs it.
+	if last >= 0 && math.Abs(total-sum) <= 0.01*float64(len(installments)) {
+		installments[last].Amount = roundAmount(installments[last].Amount + total - sum)
+		sum = total
+	}
+	if math.Abs(total-sum) > balanceTolerance {
+		return fmt.Errorf("%w: schedule is %s, total is %s", ErrInstallmentsMismatch, formatAmount(sum), formatAmount(total))
+	}
+
+	invoice.DateDue = installments[len(installments)-1].DueDate
+	return nil
+}
+
+// checkScheduleDueDate rejects a due date sent with, or against, a payment
+// schedule that ends on another day, as scheduleInstallments would otherwise
+// replace it without the client knowing.
+func checkScheduleDueDate(dateDue time.Time, installments []models.InvoiceInstallment) error {
+	if len(installments) == 0 {
+		return nil
+	}
+
+	last := installments[0].DueDate
+	for _, installment := range installments[1:] {
+		if installment.DueDate.After(last) {
+			last = installment.DueDate
+		}
+	}
+
+	year, month, day := dateDue.Date()
+	lastYear, lastMonth, lastDay := last.Date()
+	if year != lastYear || month != lastMonth || day != lastDay {
+		return ErrInstallmentDueDate
+	}
+	return nil
+}
+
+// allocateInstallmentPayments applies the amount paid to the installments in
+// due date order and sets the paid amount and status of each.
+func allocateInstallmentPayments(installments []models.InvoiceInstallment, paid float64, now time.Time) {
+	for i := range installments {
+		applied := math.Min(paid, installments[i].Amount)
+		if applied < 0 {
+			applied = 0
+		}
+		installments[i].AmountPaid = roundAmount(applied)
+		paid -= applied
+
+		switch {
+		case installments[i].Amount-applied <= balanceTolerance:
+			installments[i].Status = models.Paid
+		case installments[i].DueDate.Before(now):
+			installments[i].Status = models.Overdue
+		default:
+			installments[i].Status = models.Pending
+		}
+	}
+}
+
+// nextInstallment returns the earliest installment that is not fully paid.
+// The installments must already have their payments allocated.
+func nextInstallment(installments []models.InvoiceInstallment) *models.InvoiceInstallment {
+	for i := range installments {
+		if installments[i].Status != models.Paid {
+			return &installments[i]
+		}
+	}
+	return nil
+}
+
+// invoiceOverdue reports whether an unpaid invoice is late: past its due date
+// when it has no schedule, or behind on any installment due so far.
+func invoiceOverdue(invoice *models.Invoice, installments []models.InvoiceInstallment, paid float64, now time.Time) bool {
+	if len(installments) == 0 {
+		return invoice.DateDue.Before(now)
+	}
+
+	due := 0.0
+	for _, installment := range installments {
+		if installment.DueDate.Before(now) {
+			due += installment.Amount
+		}
+	}
+	return due > paid+balanceTolerance
+}
+
+func installmentLabel(installment models.InvoiceInstallment) string {
+	if installment.Label != "" {
+		return installment.Label
+	}
+	return fmt.Sprintf("Installment %d", installment.Position)
+}
+
+func findInstallments(tx *gorm.DB, invoiceId string) ([]models.InvoiceInstallment, error) {
+	var installments []models.InvoiceInstallment
+	err := tx.Where("invoice_id = ?", invoiceId).Order("position ASC").Find(&installments).Error
+	return installments, err
+}
+
+func orderInstallments(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"errors"
+	"invoicer-go/m/src/models"
+	"testing"
+	"time"
+)
+
+func TestScheduleInstallments(t *testing.T) {
+	percent := func(value float64) *float64 { return &value }
+	jan := time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2026, time.February, 28, 0, 0, 0, 0, time.UTC)
+	mar := time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		total        float64
+		installments []models.InvoiceInstallment
+		wantAmounts  []float64
+		wantErr      error
+	}{
+		{
+			name:  "percentages that divide evenly",
+			total: 200,
+			installments: []models.InvoiceInstallment{
+				{DueDate: jan, Percentage: percent(25)},
+				{DueDate: feb, Percentage: percent(75)},
+			},
+			wantAmounts: []float64{50, 150},
+		},
+		{
+			name:  "last percentage absorbs the cent remainder",
+			total: 100,
+			installments: []models.InvoiceInstallment{
+				{DueDate: jan, Percentage: percent(100.0 / 3)},
+				{DueDate: feb, Percentage: percent(100.0 / 3)},
+				{DueDate: mar, Percentage: percent(100.0 / 3)},
+			},
+			wantAmounts: []float64{33.33, 33.33, 33.34},
+		},
+		{
+			name:  "fixed amounts with a percentage",
+			total: 250,
+			installments: []models.InvoiceInstallment{
+				{DueDate: jan, Amount: 50},
+				{DueDate: feb, Percentage: percent(80)},
+			},
+			wantAmounts: []float64{50, 200},
+		},
+		{
+			name:  "fixed amounts that add up",
+			total: 99.99,
+			installments: []models.InvoiceInstallment{
+				{DueDate: jan, Amount: 33.33},
+				{DueDate: feb, Amount: 66.66},
+			},
+			wantAmounts: []float64{33.33, 66.66},
+		},
+		{
+			name:  "fixed amounts short of the total",
+			total: 250,
+			installments: []models.InvoiceInstallment{
+				{DueDate: jan, Amount: 100},
+				{DueDate: feb, Amount: 100},
+			},
+			wantErr: ErrInstallmentsMismatch,
+		},
+		{
+			name:  "percentages well short of the total are not rounded up",
+			total: 100,
+			installments: []models.InvoiceInstallment{
+				{DueDate: jan, Percentage: percent(40)},
+				{DueDate: feb, Percentage: percent(40)},
+			},
+			wantErr: ErrInstallmentsMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := &models.Invoice{Total: tt.total}
+			err := scheduleInstallments(invoice, tt.installments)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for i, want := range tt.wantAmounts {
+				if !amountsEqual(tt.installments[i].Amount, want) {
+					t.Errorf("installment %d = %v, want %v", i+1, tt.installments[i].Amount, want)
+				}
+			}
+			last := tt.installments[len(tt.installments)-1].DueDate
+			if !invoice.DateDue.Equal(last) {
+				t.Errorf("DateDue = %v, want the last installment's %v", invoice.DateDue, last)
+			}
+		})
+	}
+}
+
+func TestScheduleInstallmentsWithoutSchedule(t *testing.T) {
+	due := time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC)
+	invoice := &models.Invoice{Total: 100, DateDue: due}
+	if err := scheduleInstallments(invoice, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !invoice.DateDue.Equal(due) {
+		t.Errorf("DateDue = %v, want it unchanged at %v", invoice.DateDue, due)
+	}
+}
+
+func TestCheckScheduleDueDate(t *testing.T) {
+	feb := time.Date(2026, time.February, 28, 0, 0, 0, 0, time.UTC)
+	mar := time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC)
+	schedule := []models.InvoiceInstallment{{DueDate: feb}, {DueDate: mar}}
+
+	tests := []struct {
+		name         string
+		dateDue      time.Time
+		installments []models.InvoiceInstallment
+		wantErr      error
+	}{
+		{"no schedule", feb, nil, nil},
+		{"last installment date", mar, schedule, nil},
+		{"same day at another time", mar.Add(15 * time.Hour), schedule, nil},
+		{"earlier installment date", feb, schedule, ErrInstallmentDueDate},
+		{"after the schedule", mar.AddDate(0, 0, 1), schedule, ErrInstallmentDueDate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkScheduleDueDate(tt.dateDue, tt.installments); !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInvoiceOverdue(t *testing.T) {
+	now := time.Date(2026, time.March, 15, 0, 0, 0, 0, time.UTC)
+	past := now.AddDate(0, 0, -1)
+	future := now.AddDate(0, 0, 1)
+	schedule := []models.InvoiceInstallment{{DueDate: past, Amount: 40}, {DueDate: future, Amount: 60}}
+
+	tests := []struct {
+		name         string
+		dateDue      time.Time
+		installments []models.InvoiceInstallment
+		paid         float64
+		want         bool
+	}{
+		{"past due date without schedule", past, nil, 0, true},
+		{"due date still ahead", future, nil, 0, false},
+		{"behind on an installment", future, schedule, 39.5, true},
+		{"up to date on installments", future, schedule, 40, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := &models.Invoice{DateDue: tt.dateDue, Total: 100}
+			if got := invoiceOverdue(invoice, tt.installments, tt.paid, now); got != tt.want {
+				t.Errorf("invoiceOverdue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,12 +1,19 @@
 package services
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
+	"invoicer-go/m/src/config"
 	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/lib"
 	"invoicer-go/m/src/models"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -21,40 +28,279 @@ func NewInvoiceService(database *gorm.DB) *InvoiceService {
 }
 
 var (
-	ErrInvoiceTitleExists = errors.New("an invoice with this title already exists")
+	ErrInvalidInvoiceStatus = errors.New("invalid invoice status")
+	ErrInvalidCustomerId    = errors.New("customer id must be a uuid")
+	ErrInvoiceAlreadyPaid   = errors.New("invoice has already been paid")
+	ErrInvoiceArchived      = errors.New("invoice is archived")
+	ErrInvoiceNotArchived   = errors.New("invoice is not archived")
+	ErrInvoiceIsDraft       = errors.New("draft invoices cannot be sent")
+	ErrInvalidSortField     = errors.New("invalid sort field")
+	ErrInvalidSortOrder     = errors.New("sort order must be either asc or desc")
+	ErrInvoiceTitleExists   = errors.New("an invoice with this title already exists")
+	ErrInvalidItemDiscount  = errors.New("item discount must be a fixed amount up to the line amount or a percentage up to 100")
+	ErrInvoiceTaxWithItems  = errors.New("an invoice tax cannot be combined with item taxes")
+	ErrInvalidQuantity      = errors.New("item quantity has too many decimal places")
+	ErrInvalidUnit          = errors.New("item unit must be a known unit or a UN/ECE unit code")
 )
 
+// invoiceSortColumns maps the sort keys accepted by GetInvoices to the columns
+// they order by.
+var invoiceSortColumns = map[string]sortColumn{
+	"createdAt":    {column: "invoices.created_at", kind: sortByTime},
+	"customerName": {column: "customers.name", kind: sortByText},
+	"dateDue":      {column: "invoices.date_due", kind: sortByTime},
+	"dateIssued":   {column: "invoices.date_issued", kind: sortByTime},
+	"referenceNo":  {column: "invoices.reference_no", kind: sortByText},
+	"total":        {column: "invoices.total", kind: sortByNumber},
+}
+
+// calculateInvoiceTotals works out each line's discount and taxes, then the
+// invoice totals. The invoice level discount and tax still apply to the whole
+// subtotal, so invoices without item taxes are calculated as before.
 func (s *InvoiceService) calculateInvoiceTotals(invoice *models.Invoice) {
 	invoice.SubTotal = 0
+	breakdown := models.TaxLines{}
 	for i := range invoice.Items {
-		invoice.Items[i].LineTotal = float64(invoice.Items[i].Quantity) * invoice.Items[i].Price
-		invoice.SubTotal += invoice.Items[i].LineTotal
+		item := &invoice.Items[i]
+		gross := lineAmount(item.Quantity, item.Price)
+		item.DiscountAmount = roundAmount(rateAmount(item.DiscountType, item.Discount, gross))
+
+		net, bases, amounts := lineTaxes(roundAmount(gross-item.DiscountAmount), item.Taxes)
+		item.LineTotal = net
+		item.TaxAmount, item.WithholdingAmount = 0, 0
+		for t, tax := range item.Taxes {
+			if tax.Withholding {
+				item.WithholdingAmount += amounts[t]
+			} else {
+				item.TaxAmount += amounts[t]
+			}
+			breakdown = addTaxLine(breakdown, tax, bases[t], amounts[t])
+		}
+		item.TaxAmount = roundAmount(item.TaxAmount)
+		item.WithholdingAmount = roundAmount(item.WithholdingAmount)
+		invoice.SubTotal += item.LineTotal
 	}
 
-	var discountAmount, taxAmount float64
+	if tax := invoiceTaxAmount(invoice); tax != 0 {
+		breakdown = addTaxLine(breakdown, models.ItemTax{Name: "Tax", Rate: invoiceTaxRate(invoice)}, invoice.SubTotal, tax)
+	}
 
-	switch invoice.DiscountType {
-	case models.Fixed:
-		discountAmount = invoice.Discount
-	case models.Percentage:
-		discountAmount = invoice.Discount * invoice.SubTotal / 100
+	invoice.TaxBreakdown = breakdown
+	invoice.TaxTotal, invoice.WithholdingTotal = 0, 0
+	for _, line := range breakdown {
+		if line.Withholding {
+			invoice.WithholdingTotal += line.Amount
+		} else {
+			invoice.TaxTotal += line.Amount
+		}
+	}
+	invoice.TaxTotal = roundAmount(invoice.TaxTotal)
+	invoice.WithholdingTotal = roundAmount(invoice.WithholdingTotal)
+	invoice.Total = invoice.SubTotal + invoice.TaxTotal - invoice.WithholdingTotal - invoiceDiscountAmount(invoice)
+}
+
+// lineTaxes works out the taxes on a line amount and returns the net amount
+// with the base and amount of each tax. Inclusive taxes are backed out of the
+// amount first, so that the net amount and those taxes add up to it exactly.
+// Simple and withholding taxes are charged on the net amount. Compound taxes
+// come after all of those, wherever they are listed: each is charged on the
+// net amount plus the simple taxes and the compound taxes listed before it.
+func lineTaxes(amount float64, taxes models.ItemTaxes) (float64, []float64, []float64) {
+	bases := make([]float64, len(taxes))
+	amounts := make([]float64, len(taxes))
+
+	inclusive := 0.0
+	for _, tax := range taxes {
+		if tax.Inclusive {
+			inclusive += tax.Rate
+		}
+	}
+
+	net := amount
+	if inclusive > 0 {
+		net = roundAmount(amount * 100 / (100 + inclusive))
+		remainder := roundAmount(amount - net)
+		last := -1
+		for t, tax := range taxes {
+			if tax.Inclusive {
+				amounts[t] = roundAmount(net * tax.Rate / 100)
+				remainder -= amounts[t]
+				last = t
+			}
+		}
+		amounts[last] = roundAmount(amounts[last] + remainder)
+	}
+
+	base := net
+	for t, tax := range taxes {
+		if tax.Compound {
+			continue
+		}
+		bases[t] = net
+		if !tax.Inclusive {
+			amounts[t] = roundAmount(net * tax.Rate / 100)
+		}
+		if !tax.Withholding {
+			base += amounts[t]
+		}
+	}
+	for t, tax := range taxes {
+		if tax.Compound {
+			bases[t] = roundAmount(base)
+			amounts[t] = roundAmount(base * tax.Rate / 100)
+			base += amounts[t]
+		}
+	}
+
+	return net, bases, amounts
+}
+
+// addTaxLine adds an amount to the summary line for the same tax, starting a
+// new line the first time the tax is seen.
+func addTaxLine(lines models.TaxLines, tax models.ItemTax, base, amount float64) models.TaxLines {
+	for i := range lines {
+		if lines[i].Name == tax.Name && lines[i].Rate == tax.Rate && lines[i].Withholding == tax.Withholding &&
+			lines[i].Compound == tax.Compound && lines[i].Inclusive == tax.Inclusive {
+			lines[i].Base = roundAmount(lines[i].Base + base)
+			lines[i].Amount = roundAmount(lines[i].Amount + amount)
+			return lines
+		}
+	}
+	return append(lines, models.TaxLine{
+		Amount:      roundAmount(amount),
+		Base:        roundAmount(base),
+		Compound:    tax.Compound,
+		Inclusive:   tax.Inclusive,
+		Name:        tax.Name,
+		Rate:        tax.Rate,
+		Withholding: tax.Withholding,
+	})
+}
+
+// invoiceTaxLines returns the invoice's tax breakdown. Invoices calculated
+// before the breakdown was stored fall back to their single invoice tax.
+func invoiceTaxLines(invoice *models.Invoice) models.TaxLines {
+	if len(invoice.TaxBreakdown) > 0 {
+		return invoice.TaxBreakdown
+	}
+	if tax := invoiceTaxAmount(invoice); tax != 0 {
+		return addTaxLine(nil, models.ItemTax{Name: "Tax", Rate: invoiceTaxRate(invoice)}, invoice.SubTotal, tax)
+	}
+	return nil
+}
+
+// invoiceTaxRate is the rate of the invoice level tax, or zero when it is a
+// fixed amount.
+func invoiceTaxRate(invoice *models.Invoice) float64 {
+	if invoice.TaxType == models.Percentage {
+		return invoice.Tax
 	}
+	return 0
+}
+
+// lineAmount multiplies the quantity by the price in decimal arithmetic, so
+// 0.75 kg at 19.99 comes to exactly 14.99 rather than a float approximation,
+// and rounds the result to cents.
+func lineAmount(quantity decimal.Decimal, price float64) float64 {
+	return quantity.Mul(decimal.NewFromFloat(price)).Round(2).InexactFloat64()
+}
+
+// quantityPrecision is the number of decimal places allowed in a quantity,
+// capped at what the quantity column stores.
+func quantityPrecision() int {
+	return min(max(config.AppConfig.QuantityPrecision, 0), 6)
+}
 
-	switch invoice.TaxType {
+// normalizeUnit accepts a unit by name, such as "hour", or by its UN/ECE
+// code, such as "HUR", and returns the code.
+func normalizeUnit(unit string) (string, error) {
+	unit = strings.TrimSpace(unit)
+	if unit == "" {
+		return "", nil
+	}
+	for _, known := range models.UnitsOfMeasure {
+		if strings.EqualFold(unit, known.Name) || strings.EqualFold(unit, known.Code) {
+			return known.Code, nil
+		}
+	}
+	if !unitCodePattern.MatchString(unit) {
+		return "", ErrInvalidUnit
+	}
+	return unit, nil
+}
+
+var unitCodePattern = regexp.MustCompile(`^[A-Z0-9]{2,3}$`)
+
+// unitSymbol is how a unit is printed next to a quantity.
+func unitSymbol(code string) string {
+	for _, known := range models.UnitsOfMeasure {
+		if known.Code == code {
+			return known.Symbol
+		}
+	}
+	return code
+}
+
+func rateAmount(kind models.DiscountType, value, base float64) float64 {
+	switch kind {
 	case models.Fixed:
-		taxAmount = invoice.Tax
+		return value
 	case models.Percentage:
-		taxAmount = invoice.Tax * invoice.SubTotal / 100
+		return value * base / 100
+	}
+	return 0
+}
+
+func invoiceDiscountAmount(invoice *models.Invoice) float64 {
+	return rateAmount(invoice.DiscountType, invoice.Discount, invoice.SubTotal)
+}
+
+func invoiceTaxAmount(invoice *models.Invoice) float64 {
+	return rateAmount(invoice.TaxType, invoice.Tax, invoice.SubTotal)
+}
+
+// resolveExchangeRate returns the rate that converts an amount in currency to
+// the base currency. Invoices in the base currency always use 1.
+func resolveExchangeRate(currency string, rate float64) float64 {
+	if rate <= 0 || strings.EqualFold(currency, config.AppConfig.BaseCurrency) {
+		return 1
 	}
+	return rate
+}
 
-	invoice.Total = invoice.SubTotal + taxAmount - discountAmount
+// snapshotAddress copies the customer's billing address onto an invoice when
+// it is issued, so later edits to the customer do not rewrite sent invoices.
+func snapshotAddress(address *models.Address) *models.Address {
+	if address == nil {
+		return nil
+	}
+	snapshot := *address
+	return &snapshot
 }
 
-func (s *InvoiceService) CreateInvoice(payload dto.CreateInvoiceDto) (*models.Invoice, error) {
+// invoiceBillingAddress is the address printed on the invoice: the snapshot
+// once it has been issued, the customer's current address while it is a draft.
+// An issued invoice without a snapshot was issued to a customer without an
+// address and keeps printing none.
+func invoiceBillingAddress(invoice *models.Invoice) *models.Address {
+	if invoice.Status == models.Draft {
+		return invoice.Customer.BillingAddress
+	}
+	if invoice.BillingAddress == nil || *invoice.BillingAddress == (models.Address{}) {
+		return nil
+	}
+	return invoice.BillingAddress
+}
+
+func (s *InvoiceService) CreateInvoice(userId string, payload dto.CreateInvoiceDto) (*models.Invoice, error) {
 	customerService := NewCustomerService(s.database)
-	if _, err := customerService.FindCustomerById(payload.CustomerID); err != nil {
+	customer, err := customerService.FindCustomerById(payload.CustomerID)
+	if err != nil {
 		return nil, err
 	}
+	if customer.ArchivedAt.Valid {
+		return nil, ErrCustomerArchived
+	}
 
 	existingInvoice, _ := s.FindInvoiceByTitle(payload.Title)
 	if existingInvoice != nil {
@@ -74,6 +320,7 @@ func (s *InvoiceService) CreateInvoice(payload dto.CreateInvoiceDto) (*models.In
 		DateDue:      payload.DateDue,
 		Discount:     payload.Discount,
 		DiscountType: models.DiscountType(payload.DiscountType),
+		ExchangeRate: resolveExchangeRate(payload.Currency, payload.ExchangeRate),
 		Note:         payload.Note,
 		Tax:          payload.Tax,
 		TaxType:      models.DiscountType(payload.TaxType),
@@ -81,33 +328,70 @@ func (s *InvoiceService) CreateInvoice(payload dto.CreateInvoiceDto) (*models.In
 		Status:       status,
 	}
 
-	invoice.Items = make([]models.InvoiceItem, 0, len(payload.Items))
-	for _, item := range payload.Items {
-		invoice.Items = append(invoice.Items, models.InvoiceItem{
-			Description: item.Description,
-			Quantity:    item.Quantity,
-			Price:       item.Price,
-		})
+	if invoice.Status != models.Draft {
+		invoice.BillingAddress = snapshotAddress(customer.BillingAddress)
+	}
+
+	term, err := NewPaymentTermService(s.database).resolvePaymentTerm(userId, payload.PaymentTermID, customer)
+	if err != nil {
+		return nil, err
 	}
+	applyPaymentTerm(invoice, term, time.Now(), !payload.DateDue.IsZero())
 
+	customFields := NewCustomFieldService(s.database)
+	if invoice.CustomFields, err = customFields.ApplyValues(userId, models.CustomFieldInvoice, nil, payload.CustomFields); err != nil {
+		return nil, err
+	}
+	if invoice.Items, err = s.buildInvoiceItems(userId, payload.Items); err != nil {
+		return nil, err
+	}
+	if invoice.Installments, err = buildInstallments(payload.Installments); err != nil {
+		return nil, err
+	}
+	if !payload.DateDue.IsZero() {
+		if err := checkScheduleDueDate(payload.DateDue, invoice.Installments); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := checkInvoiceTax(invoice, invoice.Items); err != nil {
+		return nil, err
+	}
 	s.calculateInvoiceTotals(invoice)
+	if err := scheduleInstallments(invoice, invoice.Installments); err != nil {
+		return nil, err
+	}
 
-	if err := s.database.Create(invoice).Error; err != nil {
+	err = s.database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(invoice).Error; err != nil {
+			return err
+		}
+		return recordInvoiceEvent(tx, invoice.ID, models.InvoiceCreated, userId, models.EventData{
+			"status": invoice.Status,
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	if err := s.database.Preload("Customer").Preload("Items").First(invoice, invoice.ID).Error; err != nil {
+	if err := s.database.Preload("Customer").Preload("Items").Preload("Installments", orderInstallments).First(invoice, invoice.ID).Error; err != nil {
 		return nil, err
 	}
 
 	return invoice, nil
 }
 
-func (s *InvoiceService) UpdateInvoice(id string, payload dto.UpdateInvoiceDto) (*models.Invoice, error) {
+func (s *InvoiceService) UpdateInvoice(id, userId string, version int, payload dto.UpdateInvoiceDto) (*models.Invoice, error) {
 	invoice, err := s.FindInvoiceById(id)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkVersion(invoice.Version, version); err != nil {
+		return nil, err
+	}
+	if invoice.ArchivedAt.Valid {
+		return nil, ErrInvoiceArchived
+	}
 
 	if payload.Title != nil && !strings.EqualFold(*payload.Title, invoice.Title) {
 		existingInvoice, _ := s.FindInvoiceByTitle(*payload.Title)
@@ -116,8 +400,17 @@ func (s *InvoiceService) UpdateInvoice(id string, payload dto.UpdateInvoiceDto)
 		}
 	}
 
+	previousStatus := invoice.Status
 	if payload.Status != nil {
-		invoice.Status = models.InvoiceStatus(*payload.Status)
+		newStatus := models.InvoiceStatus(*payload.Status)
+		if invoice.Status == models.Draft && newStatus != models.Draft {
+			customer, err := NewCustomerService(s.database).FindCustomerById(invoice.CustomerID.String())
+			if err != nil {
+				return nil, err
+			}
+			invoice.BillingAddress = snapshotAddress(customer.BillingAddress)
+		}
+		invoice.Status = newStatus
 	}
 	if payload.Currency != nil {
 		invoice.Currency = *payload.Currency
@@ -125,12 +418,24 @@ func (s *InvoiceService) UpdateInvoice(id string, payload dto.UpdateInvoiceDto)
 	if payload.DateDue != nil {
 		invoice.DateDue = *payload.DateDue
 	}
+	if payload.PaymentTermID != nil {
+		var term *models.PaymentTerm
+		if *payload.PaymentTermID != "" {
+			if term, err = NewPaymentTermService(s.database).FindPaymentTermById(userId, *payload.PaymentTermID); err != nil {
+				return nil, err
+			}
+		}
+		applyPaymentTerm(invoice, term, invoice.DateIssued, payload.DateDue != nil)
+	}
 	if payload.Discount != nil {
 		invoice.Discount = *payload.Discount
 	}
 	if payload.DiscountType != nil {
 		invoice.DiscountType = models.DiscountType(*payload.DiscountType)
 	}
+	if payload.ExchangeRate != nil {
+		invoice.ExchangeRate = resolveExchangeRate(invoice.Currency, *payload.ExchangeRate)
+	}
 	if payload.Note != nil {
 		invoice.Note = *payload.Note
 	}
@@ -143,30 +448,83 @@ func (s *InvoiceService) UpdateInvoice(id string, payload dto.UpdateInvoiceDto)
 	if payload.Title != nil {
 		invoice.Title = *payload.Title
 	}
+	if payload.CustomFields != nil {
+		customFields := NewCustomFieldService(s.database)
+		invoice.CustomFields, err = customFields.ApplyValues(userId, models.CustomFieldInvoice, invoice.CustomFields, payload.CustomFields)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var items []models.InvoiceItem
+	if payload.Items != nil {
+		if items, err = s.buildInvoiceItems(userId, payload.Items); err != nil {
+			return nil, err
+		}
+		err = checkInvoiceTax(invoice, items)
+	} else {
+		err = checkInvoiceTax(invoice, invoice.Items)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A new schedule replaces the old one; otherwise the existing installments
+	// are rescheduled against the new total.
+	var installments []models.InvoiceInstallment
+	if payload.Installments != nil {
+		installments, err = buildInstallments(payload.Installments)
+	} else {
+		installments, err = findInstallments(s.database, invoice.ID.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if payload.DateDue != nil {
+		if err := checkScheduleDueDate(*payload.DateDue, installments); err != nil {
+			return nil, err
+		}
+	}
 
 	err = s.database.Transaction(func(tx *gorm.DB) error {
 		if payload.Items != nil {
+			if err = linkExpenseLines(tx, invoice.ID, payload.Items, items); err != nil {
+				return err
+			}
+			if err = linkTimeLines(tx, invoice.ID, payload.Items, items); err != nil {
+				return err
+			}
 			if err = tx.Where("invoice_id = ?", invoice.ID).Delete(&models.InvoiceItem{}).Error; err != nil {
 				return err
 			}
 
-			invoice.Items = make([]models.InvoiceItem, len(payload.Items))
-			for i, item := range payload.Items {
-				invoice.Items[i] = models.InvoiceItem{
-					InvoiceID:   invoice.ID,
-					Description: item.Description,
-					Quantity:    item.Quantity,
-					Price:       item.Price,
-				}
+			for i := range items {
+				items[i].InvoiceID = invoice.ID
 			}
+			invoice.Items = items
 		}
 
 		s.calculateInvoiceTotals(invoice)
+		if err = scheduleInstallments(invoice, installments); err != nil {
+			return err
+		}
 
-		if err = tx.Save(invoice).Error; err != nil {
+		if err = saveVersioned(tx, invoice, &invoice.Version); err != nil {
 			return err
 		}
 
+		if payload.Installments != nil {
+			if err = tx.Where("invoice_id = ?", invoice.ID).Delete(&models.InvoiceInstallment{}).Error; err != nil {
+				return err
+			}
+		}
+		for i := range installments {
+			installments[i].InvoiceID = invoice.ID
+			if err = tx.Save(&installments[i]).Error; err != nil {
+				return err
+			}
+		}
+
 		if payload.Items != nil {
 			for i := range invoice.Items {
 				if err = tx.Create(&invoice.Items[i]).Error; err != nil {
@@ -175,6 +533,12 @@ func (s *InvoiceService) UpdateInvoice(id string, payload dto.UpdateInvoiceDto)
 			}
 		}
 
+		if invoice.Status != previousStatus {
+			return recordInvoiceEvent(tx, invoice.ID, models.InvoiceStatusChanged, userId, models.EventData{
+				"from": previousStatus,
+				"to":   invoice.Status,
+			})
+		}
 		return nil
 	})
 
@@ -182,25 +546,138 @@ func (s *InvoiceService) UpdateInvoice(id string, payload dto.UpdateInvoiceDto)
 		return nil, err
 	}
 
-	if err := s.database.Preload("Customer").Preload("Items").First(invoice, invoice.ID).Error; err != nil {
+	if err := s.database.Preload("Customer").Preload("Items").Preload("Installments", orderInstallments).First(invoice, invoice.ID).Error; err != nil {
 		return nil, err
 	}
 
 	return invoice, nil
 }
 
-func (s *InvoiceService) DeleteInvoice(id string) error {
+// buildInvoiceItems turns the submitted line items into models, validating
+// their custom field values against the user's line item fields.
+func (s *InvoiceService) buildInvoiceItems(userId string, payload []dto.CreateInvoiceItemDto) ([]models.InvoiceItem, error) {
+	definitions, err := NewCustomFieldService(s.database).definitionsFor(userId, models.CustomFieldLineItem)
+	if err != nil {
+		return nil, err
+	}
+
+	taxRates := NewTaxRateService(s.database)
+	items := make([]models.InvoiceItem, 0, len(payload))
+	for _, item := range payload {
+		customFields, err := applyCustomFields(definitions, nil, item.CustomFields)
+		if err != nil {
+			return nil, err
+		}
+
+		discountType := models.DiscountType(item.DiscountType)
+		if item.Discount < 0 || (item.Discount != 0 && discountType != models.Fixed && discountType != models.Percentage) ||
+			(discountType == models.Percentage && item.Discount > 100) {
+			return nil, ErrInvalidItemDiscount
+		}
+
+		taxes, err := taxRates.resolveItemTaxes(userId, item.Taxes)
+		if err != nil {
+			return nil, err
+		}
+
+		if !item.Quantity.Equal(item.Quantity.Truncate(int32(quantityPrecision()))) {
+			return nil, fmt.Errorf("%w: at most %d allowed", ErrInvalidQuantity, quantityPrecision())
+		}
+		unit, err := normalizeUnit(item.Unit)
+		if err != nil {
+			return nil, err
+		}
+		if discountType == models.Fixed && item.Discount > lineAmount(item.Quantity, item.Price) {
+			return nil, ErrInvalidItemDiscount
+		}
+
+		items = append(items, models.InvoiceItem{
+			CustomFields: customFields,
+			Description:  item.Description,
+			Discount:     item.Discount,
+			DiscountType: discountType,
+			Quantity:     item.Quantity,
+			Price:        item.Price,
+			Taxes:        taxes,
+			Unit:         unit,
+		})
+	}
+	return items, nil
+}
+
+// checkInvoiceTax rejects an invoice level tax when any line carries its own
+// taxes, since the lines would then be taxed twice.
+func checkInvoiceTax(invoice *models.Invoice, items []models.InvoiceItem) error {
+	if invoice.Tax == 0 {
+		return nil
+	}
+	for _, item := range items {
+		if len(item.Taxes) > 0 {
+			return ErrInvoiceTaxWithItems
+		}
+	}
+	return nil
+}
+
+// ArchiveInvoice hides the invoice from lists, reports and statements until
+// it is restored or purged by the retention job.
+func (s *InvoiceService) ArchiveInvoice(id, userId string) error {
 	invoice, err := s.FindInvoiceById(id)
 	if err != nil {
 		return err
 	}
+	if invoice.ArchivedAt.Valid {
+		return ErrInvoiceArchived
+	}
 
 	return s.database.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("invoice_id = ?", invoice.ID).Delete(&models.InvoiceItem{}).Error; err != nil {
+		err := tx.Model(invoice).Updates(map[string]interface{}{
+			"archived_at": time.Now(),
+			"version":     nextVersion,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return recordInvoiceEvent(tx, invoice.ID, models.InvoiceArchived, userId, nil)
+	})
+}
+
+func (s *InvoiceService) RestoreInvoice(id, userId string) (*models.Invoice, error) {
+	invoice, err := s.GetInvoice(id)
+	if err != nil {
+		return nil, err
+	}
+	if !invoice.ArchivedAt.Valid {
+		return nil, ErrInvoiceNotArchived
+	}
+	if invoice.Customer.ArchivedAt.Valid {
+		return nil, ErrCustomerArchived
+	}
+
+	err = s.database.Transaction(func(tx *gorm.DB) error {
+		if err := checkExpenseLines(tx, invoice); err != nil {
+			return err
+		}
+		if err := checkTimeLines(tx, invoice); err != nil {
 			return err
 		}
-		return tx.Delete(invoice).Error
+
+		err := tx.Model(invoice).Updates(map[string]interface{}{
+			"archived_at": nil,
+			"version":     nextVersion,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return recordInvoiceEvent(tx, invoice.ID, models.InvoiceRestored, userId, nil)
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	invoice.ArchivedAt = sql.NullTime{}
+	invoice.Version++
+	return invoice, nil
 }
 
 func (s *InvoiceService) GetInvoices(params dto.InvoicePagination) (*dto.PaginatedResponse[models.Invoice], error) {
@@ -217,13 +694,14 @@ func (s *InvoiceService) GetInvoices(params dto.InvoicePagination) (*dto.Paginat
 	var invoices []models.Invoice
 	var totalItems int64
 
-	query := s.database.Model(&models.Invoice{})
+	sort, err := invoiceSort(params.SortBy, params.SortOrder)
+	if err != nil {
+		return nil, err
+	}
 
-	if params.Query != nil && strings.TrimSpace(*params.Query) != "" {
-		search := "%" + strings.ToLower(strings.TrimSpace(*params.Query)) + "%"
-		query = query.Joins("JOIN customers ON customers.id = invoices.customer_id").
-			Where("LOWER(invoices.reference_no) LIKE ? OR LOWER(invoices.title) LIKE ? OR LOWER(invoices.status) LIKE ? OR LOWER(customers.name) LIKE ?",
-				search, search, search, search)
+	query, err := s.filterInvoices(params)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := query.Count(&totalItems).Error; err != nil {
@@ -241,8 +719,9 @@ func (s *InvoiceService) GetInvoices(params dto.InvoicePagination) (*dto.Paginat
 	if err := query.Offset(offset).
 		Preload("Customer").
 		Preload("Items").
+		Preload("Tags").
 		Limit(params.Limit).
-		Order("created_at DESC").
+		Order(sort.order()).
 		Find(&invoices).Error; err != nil {
 		return nil, err
 	}
@@ -261,14 +740,194 @@ func (s *InvoiceService) GetInvoices(params dto.InvoicePagination) (*dto.Paginat
 	}, nil
 }
 
+// filterInvoices builds the invoice list query from the pagination filters.
+// Customers are always joined so that search and sorting can use their
+// details.
+func (s *InvoiceService) filterInvoices(params dto.InvoicePagination) (*gorm.DB, error) {
+	query := s.database.Model(&models.Invoice{}).
+		Joins("JOIN customers ON customers.id = invoices.customer_id")
+
+	if !params.IncludeArchived {
+		query = query.Where("invoices.archived_at IS NULL")
+	}
+
+	if params.Query != nil {
+		if search := prefixSearchQuery(*params.Query); search != "" {
+			query = query.Where("invoices.search_vector @@ to_tsquery('simple', ?) OR customers.search_vector @@ to_tsquery('simple', ?)",
+				search, search)
+		}
+	}
+
+	statuses, err := parseInvoiceStatuses(params.Status)
+	if err != nil {
+		return nil, err
+	}
+	if len(statuses) > 0 {
+		query = query.Where("invoices.status IN ?", statuses)
+	}
+
+	if params.CustomerID != nil && *params.CustomerID != "" {
+		if _, err := uuid.Parse(*params.CustomerID); err != nil {
+			return nil, ErrInvalidCustomerId
+		}
+		query = query.Where("invoices.customer_id = ?", *params.CustomerID)
+	}
+	if params.Currency != nil && *params.Currency != "" {
+		query = query.Where("UPPER(invoices.currency) = ?", strings.ToUpper(*params.Currency))
+	}
+	if params.IssuedFrom != nil {
+		query = query.Where("invoices.date_issued >= ?", *params.IssuedFrom)
+	}
+	if params.IssuedTo != nil {
+		query = query.Where("invoices.date_issued <= ?", endOfDay(*params.IssuedTo))
+	}
+	if params.DueFrom != nil {
+		query = query.Where("invoices.date_due >= ?", *params.DueFrom)
+	}
+	if params.DueTo != nil {
+		query = query.Where("invoices.date_due <= ?", endOfDay(*params.DueTo))
+	}
+	if params.TotalMin != nil {
+		query = query.Where("invoices.total >= ?", *params.TotalMin)
+	}
+	if params.TotalMax != nil {
+		query = query.Where("invoices.total <= ?", *params.TotalMax)
+	}
+	if params.Overdue {
+		query = query.Where("invoices.status = @overdue OR (invoices.status = @pending AND ("+installmentOverdueCondition+"))",
+			sql.Named("overdue", models.Overdue),
+			sql.Named("pending", models.Pending),
+			sql.Named("now", time.Now()),
+			sql.Named("tolerance", balanceTolerance))
+	}
+
+	query = filterByCustomFields(query, "invoices.custom_fields", params.CustomFields)
+
+	return filterByTags(query, params.UserID, "invoices.id", "invoice_tags", "invoice_id", params.Tags, params.TagMatch)
+}
+
+// parseInvoiceStatuses accepts repeated and comma separated status values.
+func parseInvoiceStatuses(values []string) ([]models.InvoiceStatus, error) {
+	statuses := []models.InvoiceStatus{}
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			status := models.InvoiceStatus(strings.ToLower(strings.TrimSpace(part)))
+			if status == "" {
+				continue
+			}
+			switch status {
+			case models.Draft, models.Pending, models.Paid, models.Overdue:
+				statuses = append(statuses, status)
+			default:
+				return nil, fmt.Errorf("%w: %s", ErrInvalidInvoiceStatus, status)
+			}
+		}
+	}
+	return statuses, nil
+}
+
+func invoiceSort(sortBy, sortOrder string) (keysetSort, error) {
+	sort := keysetSort{key: sortBy, idColumn: "invoices.id"}
+
+	switch strings.ToLower(sortOrder) {
+	case "", "desc":
+	case "asc":
+		sort.ascending = true
+	default:
+		return sort, ErrInvalidSortOrder
+	}
+
+	if sort.key == "" {
+		sort.key = "createdAt"
+	}
+
+	column, ok := invoiceSortColumns[sort.key]
+	if !ok {
+		return sort, fmt.Errorf("%w: %s", ErrInvalidSortField, sortBy)
+	}
+	sort.column = column
+
+	return sort, nil
+}
+
+func invoiceSortValue(invoice models.Invoice, sortBy string) interface{} {
+	switch sortBy {
+	case "customerName":
+		return invoice.Customer.Name
+	case "dateDue":
+		return invoice.DateDue
+	case "dateIssued":
+		return invoice.DateIssued
+	case "referenceNo":
+		return invoice.ReferenceNo
+	case "total":
+		return invoice.Total
+	}
+	return invoice.CreatedAt.Time
+}
+
+// GetInvoicesByCursor pages through invoices by seeking past the sort key of
+// the last row seen, so rows are neither skipped nor repeated while invoices
+// are added or removed. The total is only counted when asked for.
+func (s *InvoiceService) GetInvoicesByCursor(params dto.InvoicePagination) (*dto.CursorPaginatedResponse[models.Invoice], error) {
+	limit := normalizeLimit(params.Limit)
+
+	sort, err := invoiceSort(params.SortBy, params.SortOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, value, err := sort.decodeCursor(params.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := s.filterInvoices(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var totalItems *int
+	if params.IncludeTotal {
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return nil, err
+		}
+		total := int(count)
+		totalItems = &total
+	}
+
+	var invoices []models.Invoice
+	if err := applyKeyset(query, sort, cursor, value, limit).
+		Preload("Customer").
+		Preload("Items").
+		Preload("Tags").
+		Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+
+	page, err := keysetPage(invoices, sort, cursor, limit, func(invoice models.Invoice) (interface{}, string) {
+		return invoiceSortValue(invoice, sort.key), invoice.ID.String()
+	})
+	if err != nil {
+		return nil, err
+	}
+	page.TotalItems = totalItems
+
+	return page, nil
+}
+
 func (s *InvoiceService) GetInvoice(id string) (*models.Invoice, error) {
 	invoice := &models.Invoice{}
-	if err := s.database.Preload("Customer").Preload("Items").Where("id = ?", id).First(invoice).Error; err != nil {
+	if err := s.database.Preload("Customer").Preload("Items").Preload("Payments").Preload("Tags").
+		Preload("Installments", orderInstallments).Where("id = ?", id).First(invoice).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrInvoiceNotFound
 		}
 		return nil, err
 	}
+
+	allocateInstallmentPayments(invoice.Installments, paidAmount(invoice.Payments), time.Now())
 	return invoice, nil
 }
 
@@ -293,3 +952,101 @@ func (s *InvoiceService) FindInvoiceByTitle(title string) (*models.Invoice, erro
 	}
 	return invoice, nil
 }
+
+// SendInvoice emails the invoice PDF to the customer's billing contacts and
+// copies in the rest of its contacts.
+func (s *InvoiceService) SendInvoice(id string, seller *models.User) error {
+	invoice, err := s.GetInvoice(id)
+	if err != nil {
+		return err
+	}
+	if invoice.Status == models.Draft {
+		return ErrInvoiceIsDraft
+	}
+	if invoice.ArchivedAt.Valid {
+		return ErrInvoiceArchived
+	}
+
+	return s.emailInvoice(invoice, seller, "invoice", models.InvoiceSent,
+		fmt.Sprintf("Invoice %s from %s", invoice.ReferenceNo, sellerName(seller)))
+}
+
+// SendReminder emails a payment reminder for an unpaid invoice, with the
+// invoice PDF attached, to the same recipients as SendInvoice.
+func (s *InvoiceService) SendReminder(id string, seller *models.User) error {
+	invoice, err := s.GetInvoice(id)
+	if err != nil {
+		return err
+	}
+	switch invoice.Status {
+	case models.Draft:
+		return ErrInvoiceIsDraft
+	case models.Paid:
+		return ErrInvoiceAlreadyPaid
+	}
+	if invoice.ArchivedAt.Valid {
+		return ErrInvoiceArchived
+	}
+
+	return s.emailInvoice(invoice, seller, "reminder", models.InvoiceReminded,
+		fmt.Sprintf("Payment reminder: invoice %s from %s", invoice.ReferenceNo, sellerName(seller)))
+}
+
+// emailInvoice sends the invoice email and records it on the invoice
+// timeline as the given event.
+func (s *InvoiceService) emailInvoice(invoice *models.Invoice, seller *models.User, template string, event models.InvoiceEventType, subject string) error {
+	to, cc, err := NewContactService(s.database).Recipients(&invoice.Customer)
+	if err != nil {
+		return err
+	}
+
+	fields, err := NewCustomFieldService(s.database).PdfFields(seller.ID.String())
+	if err != nil {
+		return err
+	}
+
+	content, err := RenderInvoicePdf(invoice, seller, fields, false)
+	if err != nil {
+		return err
+	}
+
+	paid := paidAmount(invoice.Payments)
+	data := map[string]interface{}{
+		"name":      invoice.Customer.Name,
+		"company":   sellerName(seller),
+		"reference": invoice.ReferenceNo,
+		"title":     invoice.Title,
+		"dueDate":   formatPdfDate(invoice.DateDue),
+		"total":     invoice.Currency + " " + formatAmount(invoice.Total),
+		"balance":   invoice.Currency + " " + formatAmount(roundAmount(invoice.Total-paid)),
+		"overdue":   invoice.Status == models.Overdue || invoiceOverdue(invoice, invoice.Installments, paid, time.Now()),
+	}
+	// With a payment schedule the email is about the next unpaid installment
+	// rather than the invoice as a whole.
+	if next := nextInstallment(invoice.Installments); next != nil {
+		data["installment"] = installmentLabel(*next)
+		data["installmentDue"] = formatPdfDate(next.DueDate)
+		data["installmentAmount"] = invoice.Currency + " " + formatAmount(next.Amount-next.AmountPaid)
+		data["overdue"] = next.Status == models.Overdue
+	}
+
+	err = lib.SendEmail(lib.EmailDto{
+		To:       to,
+		Cc:       cc,
+		Subject:  subject,
+		Template: template,
+		Data:     data,
+		Attachments: []lib.EmailAttachment{{
+			Filename: invoice.ReferenceNo + ".pdf",
+			Content:  content,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrEmailSendFailed, err)
+	}
+
+	return recordInvoiceEvent(s.database, invoice.ID, event, seller.ID.String(), models.EventData{
+		"to": to,
+		"cc": cc,
+	})
+}
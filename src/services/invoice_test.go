@@ -0,0 +1,226 @@
+package services
+
+import (
+	"invoicer-go/m/src/models"
+	"math"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func amountsEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestLineAmount(t *testing.T) {
+	tests := []struct {
+		name     string
+		quantity string
+		price    float64
+		want     float64
+	}{
+		{"whole quantity", "3", 0.1, 0.3},
+		{"fractional quantity", "0.75", 19.99, 14.99},
+		{"half cent rounds away from zero", "0.5", 0.05, 0.03},
+		{"zero quantity", "0", 12.5, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lineAmount(decimal.RequireFromString(tt.quantity), tt.price)
+			if !amountsEqual(got, tt.want) {
+				t.Errorf("lineAmount(%s, %v) = %v, want %v", tt.quantity, tt.price, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineTaxes(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      float64
+		taxes       models.ItemTaxes
+		wantNet     float64
+		wantBases   []float64
+		wantAmounts []float64
+	}{
+		{
+			name:        "no taxes",
+			amount:      100,
+			wantNet:     100,
+			wantBases:   []float64{},
+			wantAmounts: []float64{},
+		},
+		{
+			name:        "simple tax on the net amount",
+			amount:      100,
+			taxes:       models.ItemTaxes{{Name: "VAT", Rate: 7.5}},
+			wantNet:     100,
+			wantBases:   []float64{100},
+			wantAmounts: []float64{7.5},
+		},
+		{
+			name:        "inclusive tax backed out of the price",
+			amount:      120,
+			taxes:       models.ItemTaxes{{Name: "VAT", Rate: 20, Inclusive: true}},
+			wantNet:     100,
+			wantBases:   []float64{100},
+			wantAmounts: []float64{20},
+		},
+		{
+			name:   "inclusive taxes give the cent remainder to the last one",
+			amount: 100,
+			taxes: models.ItemTaxes{
+				{Name: "VAT", Rate: 10, Inclusive: true},
+				{Name: "Levy", Rate: 5, Inclusive: true},
+			},
+			wantNet:     86.96,
+			wantBases:   []float64{86.96, 86.96},
+			wantAmounts: []float64{8.70, 4.34},
+		},
+		{
+			name:   "compound taxes in order on the amount plus earlier taxes",
+			amount: 100,
+			taxes: models.ItemTaxes{
+				{Name: "Excise", Rate: 5, Compound: true},
+				{Name: "VAT", Rate: 10},
+				{Name: "Surcharge", Rate: 2, Compound: true},
+			},
+			wantNet:     100,
+			wantBases:   []float64{110, 100, 115.5},
+			wantAmounts: []float64{5.5, 10, 2.31},
+		},
+		{
+			name:   "withholding is charged on the net amount and not compounded",
+			amount: 100,
+			taxes: models.ItemTaxes{
+				{Name: "VAT", Rate: 7.5},
+				{Name: "WHT", Rate: 5, Withholding: true},
+				{Name: "Levy", Rate: 2, Compound: true},
+			},
+			wantNet:     100,
+			wantBases:   []float64{100, 100, 107.5},
+			wantAmounts: []float64{7.5, 5, 2.15},
+		},
+		{
+			name:   "inclusive and compound taxes together",
+			amount: 107.5,
+			taxes: models.ItemTaxes{
+				{Name: "VAT", Rate: 7.5, Inclusive: true},
+				{Name: "Levy", Rate: 2, Compound: true},
+			},
+			wantNet:     100,
+			wantBases:   []float64{100, 107.5},
+			wantAmounts: []float64{7.5, 2.15},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			net, bases, amounts := lineTaxes(tt.amount, tt.taxes)
+			if !amountsEqual(net, tt.wantNet) {
+				t.Errorf("net = %v, want %v", net, tt.wantNet)
+			}
+			if len(bases) != len(tt.wantBases) || len(amounts) != len(tt.wantAmounts) {
+				t.Fatalf("got %d bases and %d amounts, want %d and %d", len(bases), len(amounts), len(tt.wantBases), len(tt.wantAmounts))
+			}
+			for i := range tt.wantBases {
+				if !amountsEqual(bases[i], tt.wantBases[i]) {
+					t.Errorf("base of %s = %v, want %v", tt.taxes[i].Name, bases[i], tt.wantBases[i])
+				}
+				if !amountsEqual(amounts[i], tt.wantAmounts[i]) {
+					t.Errorf("amount of %s = %v, want %v", tt.taxes[i].Name, amounts[i], tt.wantAmounts[i])
+				}
+			}
+
+			inclusive := 0.0
+			for i, tax := range tt.taxes {
+				if tax.Inclusive {
+					inclusive += amounts[i]
+				}
+			}
+			if !amountsEqual(roundAmount(net+inclusive), tt.amount) {
+				t.Errorf("net %v and inclusive taxes %v do not add up to %v", net, inclusive, tt.amount)
+			}
+		})
+	}
+}
+
+func TestCalculateInvoiceTotals(t *testing.T) {
+	tests := []struct {
+		name             string
+		invoice          models.Invoice
+		wantSubTotal     float64
+		wantTaxTotal     float64
+		wantWithholding  float64
+		wantTotal        float64
+		wantBreakdownLen int
+	}{
+		{
+			name: "line discount, tax and withholding",
+			invoice: models.Invoice{Items: []models.InvoiceItem{{
+				Price:        100,
+				Quantity:     decimal.NewFromInt(2),
+				Discount:     10,
+				DiscountType: models.Percentage,
+				Taxes: models.ItemTaxes{
+					{Name: "VAT", Rate: 7.5},
+					{Name: "WHT", Rate: 5, Withholding: true},
+				},
+			}}},
+			wantSubTotal:     180,
+			wantTaxTotal:     13.5,
+			wantWithholding:  9,
+			wantTotal:        184.5,
+			wantBreakdownLen: 2,
+		},
+		{
+			name: "lines at the same rate share a breakdown line",
+			invoice: models.Invoice{Items: []models.InvoiceItem{
+				{Price: 10, Quantity: decimal.NewFromInt(1), Taxes: models.ItemTaxes{{Name: "VAT", Rate: 7.5}}},
+				{Price: 0.99, Quantity: decimal.NewFromInt(3), Taxes: models.ItemTaxes{{Name: "VAT", Rate: 7.5}}},
+			}},
+			wantSubTotal:     12.97,
+			wantTaxTotal:     0.97,
+			wantTotal:        13.94,
+			wantBreakdownLen: 1,
+		},
+		{
+			name: "invoice level tax and discount",
+			invoice: models.Invoice{
+				Discount:     5,
+				DiscountType: models.Fixed,
+				Tax:          10,
+				TaxType:      models.Percentage,
+				Items:        []models.InvoiceItem{{Price: 50, Quantity: decimal.NewFromInt(2)}},
+			},
+			wantSubTotal:     100,
+			wantTaxTotal:     10,
+			wantTotal:        105,
+			wantBreakdownLen: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := tt.invoice
+			NewInvoiceService(nil).calculateInvoiceTotals(&invoice)
+
+			if !amountsEqual(invoice.SubTotal, tt.wantSubTotal) {
+				t.Errorf("SubTotal = %v, want %v", invoice.SubTotal, tt.wantSubTotal)
+			}
+			if !amountsEqual(invoice.TaxTotal, tt.wantTaxTotal) {
+				t.Errorf("TaxTotal = %v, want %v", invoice.TaxTotal, tt.wantTaxTotal)
+			}
+			if !amountsEqual(invoice.WithholdingTotal, tt.wantWithholding) {
+				t.Errorf("WithholdingTotal = %v, want %v", invoice.WithholdingTotal, tt.wantWithholding)
+			}
+			if !amountsEqual(roundAmount(invoice.Total), tt.wantTotal) {
+				t.Errorf("Total = %v, want %v", invoice.Total, tt.wantTotal)
+			}
+			if len(invoice.TaxBreakdown) != tt.wantBreakdownLen {
+				t.Errorf("TaxBreakdown has %d lines, want %d", len(invoice.TaxBreakdown), tt.wantBreakdownLen)
+			}
+		})
+	}
+}
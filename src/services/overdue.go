@@ -0,0 +1,93 @@
+package services
+
+import (
+	"database/sql"
+	"invoicer-go/m/src/models"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OverdueService struct {
+	database *gorm.DB
+}
+
+func NewOverdueService(database *gorm.DB) *OverdueService {
+	return &OverdueService{
+		database: database,
+	}
+}
+
+const overdueBatchSize = 100
+
+// Run marks invoices that have fallen overdue straight away and then on
+// every interval. It blocks, so start it on its own goroutine.
+func (s *OverdueService) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if marked, err := s.MarkOverdue(time.Now()); err != nil {
+			log.Printf("Failed to mark overdue invoices: %v", err)
+		} else if marked > 0 {
+			log.Printf("Marked %d invoices as overdue", marked)
+		}
+
+		<-ticker.C
+	}
+}
+
+// MarkOverdue moves live pending invoices that are past their due date, or
+// behind on their installments, to overdue. Each change is recorded as a
+// status change with no actor, which queues the invoice.overdue webhook for
+// the invoice's owner. Invoices locked by another request are left for the
+// next run.
+func (s *OverdueService) MarkOverdue(now time.Time) (int, error) {
+	marked := 0
+	for {
+		var batch int
+		err := s.database.Transaction(func(tx *gorm.DB) error {
+			var invoices []models.Invoice
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("invoices.status = @pending AND invoices.archived_at IS NULL AND ("+installmentOverdueCondition+")",
+					sql.Named("pending", models.Pending),
+					sql.Named("now", now),
+					sql.Named("tolerance", balanceTolerance)).
+				Order("invoices.date_due ASC").
+				Limit(overdueBatchSize).
+				Find(&invoices).Error
+			if err != nil {
+				return err
+			}
+
+			for _, invoice := range invoices {
+				err := tx.Model(&models.Invoice{}).Where("id = ?", invoice.ID).Updates(map[string]interface{}{
+					"status":  models.Overdue,
+					"version": nextVersion,
+				}).Error
+				if err != nil {
+					return err
+				}
+				err = recordInvoiceEvent(tx, invoice.ID, models.InvoiceStatusChanged, "", models.EventData{
+					"from": models.Pending,
+					"to":   models.Overdue,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			batch = len(invoices)
+			return nil
+		})
+		if err != nil {
+			return marked, err
+		}
+
+		marked += batch
+		if batch < overdueBatchSize {
+			return marked, nil
+		}
+	}
+}
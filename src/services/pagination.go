@@ -0,0 +1,188 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/lib"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageLimit = 10
+	maxPageLimit     = 100
+)
+
+type sortKind int
+
+const (
+	sortByTime sortKind = iota
+	sortByNumber
+	sortByText
+)
+
+// sortColumn is a column a list can be ordered and keyset paginated by. The
+// kind tells how to decode the column value stored in a cursor.
+type sortColumn struct {
+	column string
+	kind   sortKind
+}
+
+// keysetSort is a resolved list ordering. The row id is always the tie
+// breaker, so (column, id) is unique and safe to seek on.
+type keysetSort struct {
+	key       string
+	column    sortColumn
+	idColumn  string
+	ascending bool
+}
+
+func normalizeLimit(limit int) int {
+	if limit <= 0 {
+		return defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		return maxPageLimit
+	}
+	return limit
+}
+
+func (k keysetSort) order() string {
+	return k.orderFor(k.ascending)
+}
+
+func (k keysetSort) orderFor(ascending bool) string {
+	direction := "DESC"
+	if ascending {
+		direction = "ASC"
+	}
+	return fmt.Sprintf("%s %s, %s %s", k.column.column, direction, k.idColumn, direction)
+}
+
+func (k keysetSort) sortOrder() string {
+	if k.ascending {
+		return "asc"
+	}
+	return "desc"
+}
+
+// decodeCursor parses an opaque cursor and checks that it was issued for this
+// ordering.
+func (k keysetSort) decodeCursor(encoded string) (*lib.Cursor, interface{}, error) {
+	if encoded == "" {
+		return nil, nil, nil
+	}
+
+	cursor, err := lib.DecodeCursor(encoded)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cursor.SortBy != k.key || cursor.SortOrder != k.sortOrder() {
+		return nil, nil, lib.ErrInvalidCursor
+	}
+
+	var value interface{}
+	switch k.column.kind {
+	case sortByTime:
+		var t time.Time
+		err = json.Unmarshal(cursor.Value, &t)
+		value = t
+	case sortByNumber:
+		var n float64
+		err = json.Unmarshal(cursor.Value, &n)
+		value = n
+	default:
+		var s string
+		err = json.Unmarshal(cursor.Value, &s)
+		value = s
+	}
+	if err != nil {
+		return nil, nil, lib.ErrInvalidCursor
+	}
+
+	return cursor, value, nil
+}
+
+// applyKeyset seeks past the cursor and fetches one row more than the limit so
+// the caller can tell whether another page exists. Walking backwards flips the
+// ordering; keysetPage puts the rows back in list order.
+func applyKeyset(query *gorm.DB, sort keysetSort, cursor *lib.Cursor, value interface{}, limit int) *gorm.DB {
+	ascending := sort.ascending
+	if cursor != nil && cursor.Direction == lib.CursorPrev {
+		ascending = !ascending
+	}
+
+	if cursor != nil {
+		operator := "<"
+		if ascending {
+			operator = ">"
+		}
+		query = query.Where(
+			fmt.Sprintf("(%s, %s) %s (?, ?)", sort.column.column, sort.idColumn, operator),
+			value, cursor.ID,
+		)
+	}
+
+	return query.Order(sort.orderFor(ascending)).Limit(limit + 1)
+}
+
+// keysetPage trims the look-ahead row, restores list order and issues the
+// cursors for the neighbouring pages.
+func keysetPage[T any](rows []T, sort keysetSort, cursor *lib.Cursor, limit int, position func(T) (interface{}, string)) (*dto.CursorPaginatedResponse[T], error) {
+	backwards := cursor != nil && cursor.Direction == lib.CursorPrev
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+	if backwards {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	response := &dto.CursorPaginatedResponse[T]{
+		Data:  rows,
+		Limit: limit,
+	}
+	if len(rows) == 0 {
+		return response, nil
+	}
+
+	hasNext := hasMore || backwards
+	hasPrev := (hasMore && backwards) || (cursor != nil && !backwards)
+
+	if hasNext {
+		next, err := encodeKeysetCursor(sort, lib.CursorNext, rows[len(rows)-1], position)
+		if err != nil {
+			return nil, err
+		}
+		response.NextCursor = &next
+	}
+	if hasPrev {
+		prev, err := encodeKeysetCursor(sort, lib.CursorPrev, rows[0], position)
+		if err != nil {
+			return nil, err
+		}
+		response.PrevCursor = &prev
+	}
+
+	return response, nil
+}
+
+func encodeKeysetCursor[T any](sort keysetSort, direction string, row T, position func(T) (interface{}, string)) (string, error) {
+	value, id := position(row)
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return lib.EncodeCursor(lib.Cursor{
+		Direction: direction,
+		ID:        id,
+		SortBy:    sort.key,
+		SortOrder: sort.sortOrder(),
+		Value:     raw,
+	})
+}
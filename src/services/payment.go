@@ -0,0 +1,202 @@
+package services
+
+import (
+	"errors"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PaymentService struct {
+	database *gorm.DB
+}
+
+func NewPaymentService(database *gorm.DB) *PaymentService {
+	return &PaymentService{
+		database: database,
+	}
+}
+
+var (
+	ErrInvalidPaymentAmount  = errors.New("payment amount must be greater than zero")
+	ErrInvalidPaymentKind    = errors.New("payment kind must be either payment or credit")
+	ErrPaymentExceedsBalance = errors.New("payment exceeds the outstanding balance")
+	ErrPaymentNotFound       = errors.New("payment not found")
+)
+
+// balanceTolerance absorbs floating point noise when comparing money amounts.
+const balanceTolerance = 0.005
+
+func (s *PaymentService) RecordPayment(invoiceId, userId string, payload dto.CreatePaymentDto) (*models.Payment, error) {
+	if payload.Amount <= 0 {
+		return nil, ErrInvalidPaymentAmount
+	}
+
+	kind := models.PaymentKind(payload.Kind)
+	if kind == "" {
+		kind = models.PaymentReceived
+	}
+	if kind != models.PaymentReceived && kind != models.CreditApplied {
+		return nil, ErrInvalidPaymentKind
+	}
+
+	invoice, err := NewInvoiceService(s.database).FindInvoiceById(invoiceId)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.ArchivedAt.Valid {
+		return nil, ErrInvoiceArchived
+	}
+
+	payment := &models.Payment{
+		Amount:    payload.Amount,
+		InvoiceID: invoice.ID,
+		Kind:      kind,
+		Method:    payload.Method,
+		Note:      payload.Note,
+		Reference: payload.Reference,
+	}
+	if payload.PaidAt != nil {
+		payment.PaidAt = *payload.PaidAt
+	}
+
+	err = s.database.Transaction(func(tx *gorm.DB) error {
+		paid, err := sumPayments(tx, invoice.ID.String())
+		if err != nil {
+			return err
+		}
+
+		if payment.Amount > invoice.Total-paid+balanceTolerance {
+			return ErrPaymentExceedsBalance
+		}
+
+		if err := tx.Create(payment).Error; err != nil {
+			return err
+		}
+
+		err = recordInvoiceEvent(tx, invoice.ID, models.InvoicePaymentAdded, userId, models.EventData{
+			"amount":    payment.Amount,
+			"kind":      payment.Kind,
+			"method":    payment.Method,
+			"paymentId": payment.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return syncPaymentStatus(tx, invoice, userId, paid+payment.Amount)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+func (s *PaymentService) GetPayments(invoiceId string) ([]models.Payment, error) {
+	if _, err := NewInvoiceService(s.database).FindInvoiceById(invoiceId); err != nil {
+		return nil, err
+	}
+
+	var payments []models.Payment
+	if err := s.database.Where("invoice_id = ?", invoiceId).Order("paid_at ASC").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+func (s *PaymentService) DeletePayment(invoiceId, paymentId, userId string) error {
+	invoice, err := NewInvoiceService(s.database).FindInvoiceById(invoiceId)
+	if err != nil {
+		return err
+	}
+
+	return s.database.Transaction(func(tx *gorm.DB) error {
+		var payment models.Payment
+		err := tx.Where("id = ? AND invoice_id = ?", paymentId, invoiceId).First(&payment).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPaymentNotFound
+			}
+			return err
+		}
+		if err := tx.Delete(&payment).Error; err != nil {
+			return err
+		}
+
+		err = recordInvoiceEvent(tx, invoice.ID, models.InvoicePaymentVoided, userId, models.EventData{
+			"amount":    payment.Amount,
+			"kind":      payment.Kind,
+			"paymentId": payment.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		paid, err := sumPayments(tx, invoiceId)
+		if err != nil {
+			return err
+		}
+
+		return syncPaymentStatus(tx, invoice, userId, paid)
+	})
+}
+
+func paidAmount(payments []models.Payment) float64 {
+	paid := 0.0
+	for _, payment := range payments {
+		paid += payment.Amount
+	}
+	return paid
+}
+
+func sumPayments(tx *gorm.DB, invoiceId string) (float64, error) {
+	var paid float64
+	err := tx.Model(&models.Payment{}).
+		Where("invoice_id = ?", invoiceId).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&paid).Error
+	return paid, err
+}
+
+// syncPaymentStatus marks the invoice as paid once its payments cover the
+// total, and moves it back to pending or overdue when they no longer do. An
+// overdue invoice returns to pending once it has caught up with its
+// installments.
+func syncPaymentStatus(tx *gorm.DB, invoice *models.Invoice, userId string, paid float64) error {
+	status := invoice.Status
+	if paid >= invoice.Total-balanceTolerance {
+		status = models.Paid
+	} else if invoice.Status == models.Paid || invoice.Status == models.Overdue {
+		installments, err := findInstallments(tx, invoice.ID.String())
+		if err != nil {
+			return err
+		}
+		status = models.Pending
+		if invoiceOverdue(invoice, installments, paid, time.Now()) {
+			status = models.Overdue
+		}
+	}
+
+	if status == invoice.Status {
+		return nil
+	}
+
+	previous := invoice.Status
+	invoice.Status = status
+	err := tx.Model(&models.Invoice{}).Where("id = ?", invoice.ID).Updates(map[string]interface{}{
+		"status":  status,
+		"version": nextVersion,
+	}).Error
+	if err != nil {
+		return err
+	}
+	invoice.Version++
+	return recordInvoiceEvent(tx, invoice.ID, models.InvoiceStatusChanged, userId, models.EventData{
+		"from": previous,
+		"to":   status,
+	})
+}
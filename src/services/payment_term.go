@@ -0,0 +1,265 @@
+package services
+
+import (
+	"errors"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentTermService struct {
+	database *gorm.DB
+}
+
+func NewPaymentTermService(database *gorm.DB) *PaymentTermService {
+	return &PaymentTermService{
+		database: database,
+	}
+}
+
+var (
+	ErrInvalidPaymentTerm     = errors.New("payment term needs a name and a type of net or end_of_month")
+	ErrInvalidPaymentTermDays = errors.New("payment term days must be between 0 and 365")
+	ErrPaymentTermExists      = errors.New("a payment term with this name already exists")
+	ErrPaymentTermNotFound    = errors.New("payment term not found")
+)
+
+// standardPaymentTerms are given to an account when it is created, so there
+// is something to pick from before any are created.
+var standardPaymentTerms = []models.PaymentTerm{
+	{Name: "Due on receipt", Type: models.NetTerm, Days: 0},
+	{Name: "Net 15", Type: models.NetTerm, Days: 15},
+	{Name: "Net 30", Type: models.NetTerm, Days: 30, IsDefault: true},
+	{Name: "End of month", Type: models.EndOfMonthTerm, Days: 0},
+}
+
+func (s *PaymentTermService) CreatePaymentTerm(userId string, payload dto.CreatePaymentTermDto) (*models.PaymentTerm, error) {
+	term := &models.PaymentTerm{
+		Days:      payload.Days,
+		IsDefault: payload.IsDefault,
+		Name:      strings.TrimSpace(payload.Name),
+		Type:      models.PaymentTermType(strings.ToLower(strings.TrimSpace(payload.Type))),
+		UserID:    uuid.MustParse(userId),
+	}
+	if err := validatePaymentTerm(term); err != nil {
+		return nil, err
+	}
+	if err := s.ensureUniqueName(userId, term.Name, ""); err != nil {
+		return nil, err
+	}
+
+	err := s.database.Transaction(func(tx *gorm.DB) error {
+		if term.IsDefault {
+			if err := clearDefaultPaymentTerm(tx, userId); err != nil {
+				return err
+			}
+		}
+		return tx.Create(term).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return term, nil
+}
+
+func (s *PaymentTermService) GetPaymentTerms(userId string) ([]models.PaymentTerm, error) {
+	var terms []models.PaymentTerm
+	if err := s.database.Where("user_id = ?", userId).Order("days ASC, name ASC").Find(&terms).Error; err != nil {
+		return nil, err
+	}
+	return terms, nil
+}
+
+func (s *PaymentTermService) UpdatePaymentTerm(userId, id string, payload dto.UpdatePaymentTermDto) (*models.PaymentTerm, error) {
+	term, err := s.FindPaymentTermById(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Days != nil {
+		term.Days = *payload.Days
+	}
+	if payload.IsDefault != nil {
+		term.IsDefault = *payload.IsDefault
+	}
+	if payload.Name != nil {
+		term.Name = strings.TrimSpace(*payload.Name)
+	}
+	if payload.Type != nil {
+		term.Type = models.PaymentTermType(strings.ToLower(strings.TrimSpace(*payload.Type)))
+	}
+	if err := validatePaymentTerm(term); err != nil {
+		return nil, err
+	}
+	if err := s.ensureUniqueName(userId, term.Name, id); err != nil {
+		return nil, err
+	}
+
+	err = s.database.Transaction(func(tx *gorm.DB) error {
+		if term.IsDefault {
+			if err := clearDefaultPaymentTerm(tx, userId); err != nil {
+				return err
+			}
+		}
+		return tx.Save(term).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return term, nil
+}
+
+// DeletePaymentTerm removes the term from the customers that default to it.
+// Invoices keep the terms text they were issued with.
+func (s *PaymentTermService) DeletePaymentTerm(userId, id string) error {
+	term, err := s.FindPaymentTermById(userId, id)
+	if err != nil {
+		return err
+	}
+
+	return s.database.Transaction(func(tx *gorm.DB) error {
+		unset := map[string]interface{}{"payment_term_id": nil, "version": nextVersion}
+		if err := tx.Model(&models.Customer{}).Where("payment_term_id = ?", term.ID).Updates(unset).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Invoice{}).Where("payment_term_id = ?", term.ID).Updates(unset).Error; err != nil {
+			return err
+		}
+		return tx.Delete(term).Error
+	})
+}
+
+func (s *PaymentTermService) FindPaymentTermById(userId, id string) (*models.PaymentTerm, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrPaymentTermNotFound
+	}
+
+	term := &models.PaymentTerm{}
+	if err := s.database.Where("id = ? AND user_id = ?", id, userId).First(term).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPaymentTermNotFound
+		}
+		return nil, err
+	}
+	return term, nil
+}
+
+// resolvePaymentTerm picks the term for a new invoice: the one asked for,
+// then the customer's default, then the account's default. It returns nil
+// when none of them is set.
+func (s *PaymentTermService) resolvePaymentTerm(userId, termId string, customer *models.Customer) (*models.PaymentTerm, error) {
+	if termId != "" {
+		return s.FindPaymentTermById(userId, termId)
+	}
+
+	if customer.PaymentTermID != nil {
+		term, err := s.FindPaymentTermById(userId, customer.PaymentTermID.String())
+		if err == nil {
+			return term, nil
+		}
+		// The customer's term may belong to another account.
+		if !errors.Is(err, ErrPaymentTermNotFound) {
+			return nil, err
+		}
+	}
+
+	term := &models.PaymentTerm{}
+	err := s.database.Where("user_id = ? AND is_default = ?", userId, true).First(term).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return term, nil
+}
+
+func (s *PaymentTermService) ensureUniqueName(userId, name, exceptId string) error {
+	query := s.database.Model(&models.PaymentTerm{}).Where("user_id = ? AND LOWER(name) = LOWER(?)", userId, name)
+	if exceptId != "" {
+		query = query.Where("id <> ?", exceptId)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrPaymentTermExists
+	}
+	return nil
+}
+
+// SeedMissingPaymentTerms gives the standard terms to accounts that have no
+// terms at all, such as those created before terms were seeded at sign-up.
+// It is safe to run on every start.
+func (s *PaymentTermService) SeedMissingPaymentTerms() error {
+	var userIds []uuid.UUID
+	err := s.database.Model(&models.User{}).
+		Where("NOT EXISTS (SELECT 1 FROM payment_terms WHERE payment_terms.user_id = users.id)").
+		Pluck("id", &userIds).Error
+	if err != nil {
+		return err
+	}
+
+	for _, userId := range userIds {
+		if err := createStandardPaymentTerms(s.database, userId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createStandardPaymentTerms skips terms the account already has, so two
+// servers seeding at once do not fail on the unique name index.
+func createStandardPaymentTerms(tx *gorm.DB, userId uuid.UUID) error {
+	terms := make([]models.PaymentTerm, len(standardPaymentTerms))
+	copy(terms, standardPaymentTerms)
+	for i := range terms {
+		terms[i].UserID = userId
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&terms).Error
+}
+
+func clearDefaultPaymentTerm(tx *gorm.DB, userId string) error {
+	return tx.Model(&models.PaymentTerm{}).
+		Where("user_id = ? AND is_default = ?", userId, true).
+		Update("is_default", false).Error
+}
+
+func validatePaymentTerm(term *models.PaymentTerm) error {
+	if term.Name == "" || len([]rune(term.Name)) > 100 {
+		return ErrInvalidPaymentTerm
+	}
+	if term.Type != models.NetTerm && term.Type != models.EndOfMonthTerm {
+		return ErrInvalidPaymentTerm
+	}
+	if term.Days < 0 || term.Days > 365 {
+		return ErrInvalidPaymentTermDays
+	}
+	return nil
+}
+
+// applyPaymentTerm records the term and its printed text on the invoice and,
+// unless a due date was given, works out the due date from it.
+func applyPaymentTerm(invoice *models.Invoice, term *models.PaymentTerm, issued time.Time, keepDueDate bool) {
+	if term == nil {
+		invoice.PaymentTermID = nil
+		invoice.PaymentTerms = ""
+		return
+	}
+
+	id := term.ID
+	invoice.PaymentTermID = &id
+	invoice.PaymentTerms = term.Text()
+	if !keepDueDate {
+		invoice.DateDue = term.DueDate(issued)
+	}
+}
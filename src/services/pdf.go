@@ -0,0 +1,503 @@
+package services
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	pdfDateLayout   = "02 Jan 2006"
+	pdfMargin       = 15.0
+	pdfContentWidth = 180.0
+	pdfLineHeight   = 6.0
+	pdfFont         = "DejaVu"
+)
+
+// The invoice fonts are embedded in every PDF, as PDF/A requires and the
+// standard Helvetica cannot be. DejaVu is free to redistribute and covers far
+// more scripts than Windows-1252.
+var (
+	//go:embed fonts/DejaVuSansCondensed.ttf
+	dejaVuSans []byte
+	//go:embed fonts/DejaVuSansCondensed-Bold.ttf
+	dejaVuSansBold []byte
+)
+
+// RenderInvoicePdf lays out the invoice as an A4 PDF. When facturX is set the
+// result is a PDF/A-3b Factur-X invoice with the CII XML produced by
+// BuildFacturXDocument embedded as factur-x.xml, so the same file can be read
+// by people and ingested by ERPs. Values of the given custom fields are
+// printed next to the invoice, customer or line item they belong to.
+func RenderInvoicePdf(invoice *models.Invoice, seller *models.User, fields []models.CustomFieldDefinition, facturX bool) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.SetTitle("Invoice "+invoice.ReferenceNo, true)
+	pdf.SetAuthor(sellerName(seller), true)
+	pdf.SetCreator("Invoicer", true)
+	pdf.SetCreationDate(invoice.DateIssued)
+	pdf.AddUTF8FontFromBytes(pdfFont, "", dejaVuSans)
+	pdf.AddUTF8FontFromBytes(pdfFont, "B", dejaVuSansBold)
+
+	var xmlContent []byte
+	if facturX {
+		var err error
+		if xmlContent, err = BuildFacturXDocument(invoice, seller); err != nil {
+			return nil, err
+		}
+	}
+
+	pdf.AddPage()
+	writeInvoiceHeader(pdf, invoice, seller, fields)
+	writeInvoiceParties(pdf, invoice, seller, fields)
+	writeInvoiceItems(pdf, invoice, fields)
+	writeInvoiceTotals(pdf, invoice)
+	writeInvoiceTaxSummary(pdf, invoice)
+	writeInvoiceSchedule(pdf, invoice)
+	writeInvoiceFooter(pdf, invoice, seller)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	if !facturX {
+		return buf.Bytes(), nil
+	}
+
+	return makePdfA3(buf.Bytes(), pdfA3Document{
+		attachment: xmlContent,
+		author:     sellerName(seller),
+		created:    invoice.DateIssued,
+		title:      "Invoice " + invoice.ReferenceNo,
+	})
+}
+
+func writeInvoiceHeader(pdf *gofpdf.Fpdf, invoice *models.Invoice, seller *models.User, fields []models.CustomFieldDefinition) {
+	pdf.SetFont(pdfFont, "B", 18)
+	pdf.CellFormat(pdfContentWidth/2, 10, sellerName(seller), "", 0, "L", false, 0, "")
+	pdf.CellFormat(pdfContentWidth/2, 10, "INVOICE", "", 1, "R", false, 0, "")
+
+	pdf.SetFont(pdfFont, "", 10)
+	details := [][2]string{
+		{"Reference", invoice.ReferenceNo},
+		{"Date issued", formatPdfDate(invoice.DateIssued)},
+		{"Date due", formatPdfDate(invoice.DateDue)},
+	}
+	if invoice.PaymentTerms != "" {
+		details = append(details, [2]string{"Terms", invoice.PaymentTerms})
+	}
+	details = append(details, [2]string{"Status", strings.ToUpper(string(invoice.Status))})
+	details = append(details, customFieldLines(fields, models.CustomFieldInvoice, invoice.CustomFields)...)
+	for _, detail := range details {
+		pdf.CellFormat(pdfContentWidth-70, pdfLineHeight, "", "", 0, "L", false, 0, "")
+		pdf.CellFormat(35, pdfLineHeight, detail[0], "", 0, "L", false, 0, "")
+		pdf.CellFormat(35, pdfLineHeight, detail[1], "", 1, "R", false, 0, "")
+	}
+
+	if invoice.Title != "" {
+		pdf.Ln(4)
+		pdf.SetFont(pdfFont, "B", 12)
+		pdf.MultiCell(pdfContentWidth, pdfLineHeight, invoice.Title, "", "L", false)
+	}
+	pdf.Ln(4)
+}
+
+func writeInvoiceParties(pdf *gofpdf.Fpdf, invoice *models.Invoice, seller *models.User, fields []models.CustomFieldDefinition) {
+	from := []string{sellerName(seller)}
+	from = append(from, addressLines(seller.Address)...)
+	from = append(from, seller.Email, seller.Phone)
+	if seller.TaxId != "" {
+		from = append(from, "Tax ID: "+seller.TaxId)
+	}
+	if seller.RcNumber != "" {
+		from = append(from, "RC: "+seller.RcNumber)
+	}
+	to := []string{invoice.Customer.Name}
+	to = append(to, addressLines(invoiceBillingAddress(invoice))...)
+	to = append(to, invoice.Customer.Email, invoice.Customer.Phone)
+	for _, line := range customFieldLines(fields, models.CustomFieldCustomer, invoice.Customer.CustomFields) {
+		to = append(to, line[0]+": "+line[1])
+	}
+
+	pdf.SetFont(pdfFont, "B", 10)
+	pdf.CellFormat(pdfContentWidth/2, pdfLineHeight, "From", "", 0, "L", false, 0, "")
+	pdf.CellFormat(pdfContentWidth/2, pdfLineHeight, "Bill to", "", 1, "L", false, 0, "")
+
+	pdf.SetFont(pdfFont, "", 10)
+	rows := len(from)
+	if len(to) > rows {
+		rows = len(to)
+	}
+	for i := 0; i < rows; i++ {
+		pdf.CellFormat(pdfContentWidth/2, 5, lineAt(from, i), "", 0, "L", false, 0, "")
+		pdf.CellFormat(pdfContentWidth/2, 5, lineAt(to, i), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(6)
+}
+
+func writeInvoiceItems(pdf *gofpdf.Fpdf, invoice *models.Invoice, fields []models.CustomFieldDefinition) {
+	widths := []float64{65, 25, 25, 30, 35}
+	headers := []string{"Description", "Qty", "Price", "Tax", "Amount"}
+	aligns := []string{"L", "R", "R", "R", "R"}
+
+	pdf.SetFont(pdfFont, "B", 10)
+	pdf.SetFillColor(240, 240, 240)
+	for i, header := range headers {
+		pdf.CellFormat(widths[i], 8, header, "B", 0, aligns[i], true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont(pdfFont, "", 10)
+	for _, item := range invoice.Items {
+		rates := make([]string, 0, len(item.Taxes))
+		for _, tax := range item.Taxes {
+			rates = append(rates, formatDecimal(tax.Rate)+"%")
+		}
+		cells := []string{
+			item.Description,
+			formatQuantity(item),
+			formatAmount(item.Price),
+			strings.Join(rates, ", "),
+			formatAmount(item.LineTotal),
+		}
+		for i, cell := range cells {
+			pdf.CellFormat(widths[i], 7, cell, "B", 0, aligns[i], false, 0, "")
+		}
+		pdf.Ln(-1)
+
+		lines := customFieldLines(fields, models.CustomFieldLineItem, item.CustomFields)
+		if item.DiscountAmount != 0 {
+			lines = append([][2]string{{
+				"Discount" + rateSuffix(item.DiscountType, item.Discount),
+				"-" + formatAmount(item.DiscountAmount),
+			}}, lines...)
+		}
+		if len(lines) > 0 {
+			pdf.SetFont(pdfFont, "", 8)
+			for _, line := range lines {
+				pdf.CellFormat(widths[0], 5, line[0]+": "+line[1], "", 1, "L", false, 0, "")
+			}
+			pdf.SetFont(pdfFont, "", 10)
+		}
+	}
+	pdf.Ln(4)
+}
+
+func writeInvoiceTotals(pdf *gofpdf.Fpdf, invoice *models.Invoice) {
+	rows := [][2]string{{"Subtotal", formatAmount(invoice.SubTotal)}}
+	if discount := invoiceDiscountAmount(invoice); discount != 0 {
+		rows = append(rows, [2]string{"Discount" + rateSuffix(invoice.DiscountType, invoice.Discount), "-" + formatAmount(discount)})
+	}
+	// Each tax is itemised in the tax summary; the totals only show what is
+	// added and what is withheld.
+	tax, withheld := 0.0, 0.0
+	for _, line := range invoiceTaxLines(invoice) {
+		if line.Withholding {
+			withheld += line.Amount
+		} else {
+			tax += line.Amount
+		}
+	}
+	if tax != 0 {
+		rows = append(rows, [2]string{"Tax", formatAmount(tax)})
+	}
+	if withheld != 0 {
+		rows = append(rows, [2]string{"Tax withheld", "-" + formatAmount(withheld)})
+	}
+
+	pdf.SetFont(pdfFont, "", 10)
+	for _, row := range rows {
+		pdf.CellFormat(pdfContentWidth-65, pdfLineHeight, "", "", 0, "L", false, 0, "")
+		pdf.CellFormat(30, pdfLineHeight, row[0], "", 0, "L", false, 0, "")
+		pdf.CellFormat(35, pdfLineHeight, row[1], "", 1, "R", false, 0, "")
+	}
+
+	pdf.SetFont(pdfFont, "B", 11)
+	pdf.CellFormat(pdfContentWidth-65, 8, "", "", 0, "L", false, 0, "")
+	pdf.CellFormat(30, 8, "Total", "T", 0, "L", false, 0, "")
+	pdf.CellFormat(35, 8, invoice.Currency+" "+formatAmount(invoice.Total), "T", 1, "R", false, 0, "")
+	pdf.Ln(6)
+}
+
+// writeInvoiceTaxSummary prints the invoice's tax summary as stored, with
+// the amount each tax was charged on.
+func writeInvoiceTaxSummary(pdf *gofpdf.Fpdf, invoice *models.Invoice) {
+	lines := invoiceTaxLines(invoice)
+	if len(lines) == 0 {
+		return
+	}
+
+	widths := []float64{70, 20, 45, 45}
+	headers := []string{"Tax", "Rate", "Taxable amount", "Tax amount"}
+	aligns := []string{"L", "R", "R", "R"}
+
+	pdf.SetFont(pdfFont, "B", 10)
+	pdf.CellFormat(pdfContentWidth, pdfLineHeight, "Tax summary", "", 1, "L", false, 0, "")
+	pdf.SetFillColor(240, 240, 240)
+	for i, header := range headers {
+		pdf.CellFormat(widths[i], 8, header, "B", 0, aligns[i], true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont(pdfFont, "", 10)
+	for _, line := range lines {
+		rate := ""
+		if line.Rate != 0 {
+			rate = formatDecimal(line.Rate) + "%"
+		}
+		amount := formatAmount(line.Amount)
+		if line.Withholding {
+			amount = "-" + amount
+		}
+		cells := []string{taxLineLabel(line), rate, formatAmount(line.Base), amount}
+		for i, cell := range cells {
+			pdf.CellFormat(widths[i], 7, cell, "B", 0, aligns[i], false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	pdf.Ln(6)
+}
+
+// taxLineLabel names a tax summary line, noting how the tax was applied.
+func taxLineLabel(line models.TaxLine) string {
+	label := line.Name
+	if line.Rate != 0 {
+		label += rateSuffix(models.Percentage, line.Rate)
+	}
+	switch {
+	case line.Compound:
+		label += ", compound"
+	case line.Inclusive:
+		label += ", included"
+	case line.Withholding:
+		label += ", withheld"
+	}
+	return label
+}
+
+func writeInvoiceSchedule(pdf *gofpdf.Fpdf, invoice *models.Invoice) {
+	if len(invoice.Installments) == 0 {
+		return
+	}
+
+	widths := []float64{60, 30, 20, 25, 45}
+	headers := []string{"Installment", "Due date", "Share", "Status", "Amount"}
+	aligns := []string{"L", "L", "R", "L", "R"}
+	statuses := map[models.InvoiceStatus]string{models.Paid: "Paid", models.Overdue: "Overdue", models.Pending: "Due"}
+
+	pdf.SetFont(pdfFont, "B", 10)
+	pdf.CellFormat(pdfContentWidth, pdfLineHeight, "Payment schedule", "", 1, "L", false, 0, "")
+	pdf.SetFillColor(240, 240, 240)
+	for i, header := range headers {
+		pdf.CellFormat(widths[i], 8, header, "B", 0, aligns[i], true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont(pdfFont, "", 10)
+	for _, installment := range invoice.Installments {
+		share := ""
+		if installment.Percentage != nil {
+			share = strconv.FormatFloat(*installment.Percentage, 'f', -1, 64) + "%"
+		}
+		cells := []string{
+			installmentLabel(installment),
+			formatPdfDate(installment.DueDate),
+			share,
+			statuses[installment.Status],
+			invoice.Currency + " " + formatAmount(installment.Amount),
+		}
+		for i, cell := range cells {
+			pdf.CellFormat(widths[i], 7, cell, "B", 0, aligns[i], false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	pdf.Ln(6)
+}
+
+func writeInvoiceFooter(pdf *gofpdf.Fpdf, invoice *models.Invoice, seller *models.User) {
+	if invoice.Note != "" {
+		pdf.SetFont(pdfFont, "B", 10)
+		pdf.CellFormat(pdfContentWidth, pdfLineHeight, "Note", "", 1, "L", false, 0, "")
+		pdf.SetFont(pdfFont, "", 10)
+		pdf.MultiCell(pdfContentWidth, 5, invoice.Note, "", "L", false)
+		pdf.Ln(4)
+	}
+
+	bank := seller.BankInformation
+	if bank == nil || (bank.AccountNumber == "" && bank.Iban == "") {
+		return
+	}
+
+	lines := []string{}
+	for _, field := range [][2]string{
+		{"Bank", bank.BankName},
+		{"Account name", bank.AccountName},
+		{"Account number", bank.AccountNumber},
+		{"IBAN", bank.Iban},
+		{"SWIFT", bank.BankSwiftCode},
+		{"Routing number", bank.RoutingNumber},
+	} {
+		if field[1] != "" {
+			lines = append(lines, field[0]+": "+field[1])
+		}
+	}
+
+	pdf.SetFont(pdfFont, "B", 10)
+	pdf.CellFormat(pdfContentWidth, pdfLineHeight, "Payment details", "", 1, "L", false, 0, "")
+	pdf.SetFont(pdfFont, "", 10)
+	for _, line := range lines {
+		pdf.CellFormat(pdfContentWidth, 5, line, "", 1, "L", false, 0, "")
+	}
+}
+
+// RenderStatementPdf lays out a statement of account with one row per
+// document and the running balance after it.
+func RenderStatementPdf(statement *dto.Statement, seller *models.User) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.SetTitle("Statement of account "+statement.CustomerName, true)
+	pdf.SetAuthor(sellerName(seller), true)
+	pdf.SetCreator("Invoicer", true)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(pdfContentWidth/2, 10, tr(sellerName(seller)), "", 0, "L", false, 0, "")
+	pdf.CellFormat(pdfContentWidth/2, 10, "STATEMENT", "", 1, "R", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(pdfContentWidth/2, pdfLineHeight, tr(statement.CustomerName), "", 0, "L", false, 0, "")
+	pdf.CellFormat(pdfContentWidth/2, pdfLineHeight, tr(formatPdfDate(statement.From)+" - "+formatPdfDate(statement.To)), "", 1, "R", false, 0, "")
+	pdf.CellFormat(pdfContentWidth/2, pdfLineHeight, tr(statement.CustomerEmail), "", 0, "L", false, 0, "")
+	pdf.CellFormat(pdfContentWidth/2, pdfLineHeight, "Amounts in "+statement.Currency, "", 1, "R", false, 0, "")
+	pdf.Ln(6)
+
+	widths := []float64{25, 22, 53, 27, 27, 26}
+	headers := []string{"Date", "Type", "Reference", "Debit", "Credit", "Balance"}
+	aligns := []string{"L", "L", "L", "R", "R", "R"}
+
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(240, 240, 240)
+	for i, header := range headers {
+		pdf.CellFormat(widths[i], 8, header, "B", 0, aligns[i], true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 9)
+	writeRow := func(cells []string) {
+		for i, cell := range cells {
+			pdf.CellFormat(widths[i], 7, tr(cell), "B", 0, aligns[i], false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	writeRow([]string{formatPdfDate(statement.From), "", "Opening balance", "", "", formatAmount(statement.OpeningBalance)})
+	for _, entry := range statement.Entries {
+		reference := entry.Reference
+		if entry.Description != "" {
+			reference = strings.TrimSpace(reference + " " + entry.Description)
+		}
+		writeRow([]string{
+			formatPdfDate(entry.Date),
+			statementEntryType(entry.Type),
+			reference,
+			statementAmount(entry.Debit),
+			statementAmount(entry.Credit),
+			formatAmount(entry.Balance),
+		})
+	}
+
+	pdf.SetFont("Helvetica", "B", 10)
+	writeRow([]string{
+		formatPdfDate(statement.To),
+		"",
+		"Closing balance",
+		formatAmount(statement.TotalDebits),
+		formatAmount(statement.TotalCredits),
+		formatAmount(statement.ClosingBalance),
+	})
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func statementEntryType(kind string) string {
+	if kind == "" {
+		return ""
+	}
+	return strings.ToUpper(kind[:1]) + kind[1:]
+}
+
+func statementAmount(value float64) string {
+	if value == 0 {
+		return ""
+	}
+	return formatAmount(value)
+}
+
+func addressLines(address *models.Address) []string {
+	if address == nil {
+		return nil
+	}
+
+	lines := []string{}
+	for _, line := range []string{
+		address.Line1,
+		address.Line2,
+		strings.TrimSpace(strings.Join([]string{address.PostalCode, address.City}, " ")),
+		strings.Trim(strings.Join([]string{address.State, address.Country}, ", "), ", "),
+	} {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func formatQuantity(item models.InvoiceItem) string {
+	if item.Unit == "" {
+		return item.Quantity.String()
+	}
+	return item.Quantity.String() + " " + unitSymbol(item.Unit)
+}
+
+func sellerName(seller *models.User) string {
+	if seller.CompanyName != "" {
+		return seller.CompanyName
+	}
+	return seller.Name
+}
+
+func rateSuffix(kind models.DiscountType, value float64) string {
+	if kind == models.Percentage {
+		return fmt.Sprintf(" (%s%%)", formatDecimal(value))
+	}
+	return ""
+}
+
+func formatPdfDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(pdfDateLayout)
+}
+
+func lineAt(lines []string, index int) string {
+	if index < len(lines) {
+		return lines[index]
+	}
+	return ""
+}
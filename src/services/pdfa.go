@@ -0,0 +1,412 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+var ErrInvalidPdfOutput = errors.New("rendered pdf could not be read to make it PDF/A-3")
+
+// pdfA3Header replaces the header gofpdf writes. PDF/A wants a comment of
+// binary characters on the second line so the file is treated as binary.
+const pdfA3Header = "%PDF-1.7\n%\xe2\xe3\xcf\xd3\n"
+
+const pdfProducer = "Invoicer"
+
+var (
+	pdfRootPattern  = regexp.MustCompile(`/Root (\d+) 0 R`)
+	pdfPagesPattern = regexp.MustCompile(`/Pages (\d+) 0 R`)
+)
+
+// pdfA3Document is what makePdfA3 adds to a rendered invoice.
+type pdfA3Document struct {
+	attachment []byte
+	author     string
+	created    time.Time
+	title      string
+}
+
+// makePdfA3 turns a PDF rendered by gofpdf into a PDF/A-3b Factur-X invoice.
+// gofpdf cannot write the entries PDF/A needs, so the file is rewritten with
+// a binary header comment, an sRGB output intent, XMP metadata declaring
+// PDF/A-3b and the Factur-X extension schema, the CII XML as an associated
+// file, and a matching document information dictionary and file ID. The
+// catalog gofpdf wrote is left in the file unreferenced. The fonts must
+// already be embedded.
+func makePdfA3(raw []byte, doc pdfA3Document) ([]byte, error) {
+	headerEnd := bytes.IndexByte(raw, '\n') + 1
+	if headerEnd == 0 || !bytes.HasPrefix(raw, []byte("%PDF-1.")) {
+		return nil, ErrInvalidPdfOutput
+	}
+	xrefStart, err := pdfStartXref(raw)
+	if err != nil {
+		return nil, err
+	}
+	offsets, trailer, err := pdfXrefTable(raw, xrefStart)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := pdfPagesRef(raw, offsets, trailer)
+	if err != nil {
+		return nil, err
+	}
+
+	shift := len(pdfA3Header) - headerEnd
+	for i := 1; i < len(offsets); i++ {
+		offsets[i] += shift
+	}
+	w := &pdfObjectWriter{offsets: offsets}
+	w.buf.WriteString(pdfA3Header)
+	w.buf.Write(raw[headerEnd:xrefStart])
+
+	created := doc.created.UTC()
+	profile := deflate(srgbProfile)
+	iccId := w.stream(fmt.Sprintf("<< /N 3 /Filter /FlateDecode /Length %d >>", len(profile)), profile)
+	intentId := w.object(fmt.Sprintf(
+		"<< /Type /OutputIntent /S /GTS_PDFA1 /OutputConditionIdentifier (sRGB IEC61966-2.1) /Info (sRGB IEC61966-2.1) /DestOutputProfile %d 0 R >>",
+		iccId))
+	metadata := facturXMetadata(doc, created)
+	metadataId := w.stream(fmt.Sprintf("<< /Type /Metadata /Subtype /XML /Length %d >>", len(metadata)), metadata)
+
+	content := deflate(doc.attachment)
+	fileId := w.stream(fmt.Sprintf(
+		"<< /Type /EmbeddedFile /Subtype /text#2Fxml /Filter /FlateDecode /Params << /ModDate %s /Size %d /CheckSum <%x> >> /Length %d >>",
+		pdfDate(created), len(doc.attachment), md5.Sum(doc.attachment), len(content)), content)
+	specId := w.object(fmt.Sprintf(
+		"<< /Type /Filespec /F (%s) /UF %s /Desc %s /AFRelationship /Data /EF << /F %d 0 R /UF %d 0 R >> >>",
+		facturXFilename, pdfTextString(facturXFilename), pdfTextString(facturXProfile+" CII invoice data"), fileId, fileId))
+
+	info := fmt.Sprintf("<< /Title %s /Creator %s /Producer %s /CreationDate %s /ModDate %s",
+		pdfTextString(doc.title), pdfTextString(pdfProducer), pdfTextString(pdfProducer), pdfDate(created), pdfDate(created))
+	if doc.author != "" {
+		info += " /Author " + pdfTextString(doc.author)
+	}
+	infoId := w.object(info + " >>")
+	catalogId := w.object(fmt.Sprintf(
+		"<< /Type /Catalog /Pages %d 0 R /Metadata %d 0 R /OutputIntents [%d 0 R] /AF [%d 0 R] /Names << /EmbeddedFiles << /Names [(%s) %d 0 R] >> >> /PageMode /UseAttachments >>",
+		pages, metadataId, intentId, specId, facturXFilename, specId))
+
+	id := md5.Sum(w.buf.Bytes())
+	xrefAt := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets))
+	for _, offset := range w.offsets[1:] {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R /ID [<%x> <%x>] >>\nstartxref\n%d\n%%%%EOF\n",
+		len(w.offsets), catalogId, infoId, id, id, xrefAt)
+
+	return w.buf.Bytes(), nil
+}
+
+// pdfObjectWriter appends numbered objects and records where each starts.
+type pdfObjectWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (w *pdfObjectWriter) object(body string) int {
+	id := len(w.offsets)
+	w.offsets = append(w.offsets, w.buf.Len())
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+	return id
+}
+
+func (w *pdfObjectWriter) stream(dict string, data []byte) int {
+	id := len(w.offsets)
+	w.offsets = append(w.offsets, w.buf.Len())
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nstream\n", id, dict)
+	w.buf.Write(data)
+	w.buf.WriteString("\nendstream\nendobj\n")
+	return id
+}
+
+func pdfStartXref(raw []byte) (int, error) {
+	at := bytes.LastIndex(raw, []byte("startxref"))
+	if at < 0 {
+		return 0, ErrInvalidPdfOutput
+	}
+	fields := strings.Fields(string(raw[at+len("startxref"):]))
+	if len(fields) == 0 {
+		return 0, ErrInvalidPdfOutput
+	}
+	offset, err := strconv.Atoi(fields[0])
+	if err != nil || offset <= 0 || offset >= at {
+		return 0, ErrInvalidPdfOutput
+	}
+	return offset, nil
+}
+
+// pdfXrefTable reads the single cross-reference section gofpdf writes and
+// returns the object offsets, indexed by object number, and the trailer.
+func pdfXrefTable(raw []byte, start int) ([]int, []byte, error) {
+	var first, count int
+	if _, err := fmt.Sscanf(string(raw[start:]), "xref\n%d %d\n", &first, &count); err != nil || first != 0 || count < 2 {
+		return nil, nil, ErrInvalidPdfOutput
+	}
+
+	entries := start + bytes.IndexByte(raw[start+len("xref\n"):], '\n') + len("xref\n") + 1
+	if entries+count*20 > len(raw) {
+		return nil, nil, ErrInvalidPdfOutput
+	}
+	offsets := make([]int, count)
+	for i := 1; i < count; i++ {
+		entry := raw[entries+i*20 : entries+(i+1)*20]
+		offset, err := strconv.Atoi(string(entry[:10]))
+		if err != nil || entry[17] != 'n' || offset >= start {
+			return nil, nil, ErrInvalidPdfOutput
+		}
+		offsets[i] = offset
+	}
+	return offsets, raw[entries+count*20:], nil
+}
+
+// pdfPagesRef finds the page tree the original catalog points to.
+func pdfPagesRef(raw []byte, offsets []int, trailer []byte) (int, error) {
+	root := pdfRootPattern.FindSubmatch(trailer)
+	if root == nil {
+		return 0, ErrInvalidPdfOutput
+	}
+	rootId, _ := strconv.Atoi(string(root[1]))
+	if rootId <= 0 || rootId >= len(offsets) {
+		return 0, ErrInvalidPdfOutput
+	}
+
+	object := raw[offsets[rootId]:]
+	if end := bytes.Index(object, []byte("endobj")); end >= 0 {
+		object = object[:end]
+	}
+	pages := pdfPagesPattern.FindSubmatch(object)
+	if pages == nil {
+		return 0, ErrInvalidPdfOutput
+	}
+	return strconv.Atoi(string(pages[1]))
+}
+
+// pdfTextString encodes text as a UTF-16BE hex string, which holds any
+// character a seller or invoice title may contain.
+func pdfTextString(text string) string {
+	encoded := utf16.Encode([]rune(text))
+	raw := make([]byte, 2+2*len(encoded))
+	raw[0], raw[1] = 0xfe, 0xff
+	for i, unit := range encoded {
+		binary.BigEndian.PutUint16(raw[2+2*i:], unit)
+	}
+	return "<" + strings.ToUpper(hex.EncodeToString(raw)) + ">"
+}
+
+// pdfDate and xmpDate write the same instant in the two forms PDF/A checks
+// against each other.
+func pdfDate(t time.Time) string {
+	return "(D:" + t.UTC().Format("20060102150405") + "+00'00')"
+}
+
+func xmpDate(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05") + "+00:00"
+}
+
+func xmlText(text string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
+
+func deflate(data []byte) []byte {
+	var buf bytes.Buffer
+	writer := zlib.NewWriter(&buf)
+	writer.Write(data)
+	writer.Close()
+	return buf.Bytes()
+}
+
+// facturXMetadata declares PDF/A-3b conformance and describes the document
+// with the same values as its information dictionary. The Factur-X extension
+// schema tells readers which attachment carries the invoice.
+func facturXMetadata(doc pdfA3Document, created time.Time) []byte {
+	creator := ""
+	if doc.author != "" {
+		creator = fmt.Sprintf(`
+      <dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>`, xmlText(doc.author))
+	}
+
+	return []byte(fmt.Sprintf(`<?xpacket begin="%s" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+  <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+    <rdf:Description rdf:about="" xmlns:pdfaid="http://www.aiim.org/pdfa/ns/id/">
+      <pdfaid:part>3</pdfaid:part>
+      <pdfaid:conformance>B</pdfaid:conformance>
+    </rdf:Description>
+    <rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">
+      <dc:format>application/pdf</dc:format>
+      <dc:title><rdf:Alt><rdf:li xml:lang="x-default">%s</rdf:li></rdf:Alt></dc:title>%s
+    </rdf:Description>
+    <rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/">
+      <xmp:CreateDate>%s</xmp:CreateDate>
+      <xmp:ModifyDate>%s</xmp:ModifyDate>
+      <xmp:CreatorTool>%s</xmp:CreatorTool>
+    </rdf:Description>
+    <rdf:Description rdf:about="" xmlns:pdf="http://ns.adobe.com/pdf/1.3/">
+      <pdf:Producer>%s</pdf:Producer>
+    </rdf:Description>
+    <rdf:Description rdf:about="" xmlns:fx="urn:factur-x:pdfa:CrossIndustryDocument:invoice:1p0#">
+      <fx:DocumentType>INVOICE</fx:DocumentType>
+      <fx:DocumentFileName>%s</fx:DocumentFileName>
+      <fx:Version>1.0</fx:Version>
+      <fx:ConformanceLevel>%s</fx:ConformanceLevel>
+    </rdf:Description>
+    <rdf:Description rdf:about=""
+        xmlns:pdfaExtension="http://www.aiim.org/pdfa/ns/extension/"
+        xmlns:pdfaSchema="http://www.aiim.org/pdfa/ns/schema#"
+        xmlns:pdfaProperty="http://www.aiim.org/pdfa/ns/property#">
+      <pdfaExtension:schemas>
+        <rdf:Bag>
+          <rdf:li rdf:parseType="Resource">
+            <pdfaSchema:schema>Factur-X PDFA Extension Schema</pdfaSchema:schema>
+            <pdfaSchema:namespaceURI>urn:factur-x:pdfa:CrossIndustryDocument:invoice:1p0#</pdfaSchema:namespaceURI>
+            <pdfaSchema:prefix>fx</pdfaSchema:prefix>
+            <pdfaSchema:property>
+              <rdf:Seq>
+                <rdf:li rdf:parseType="Resource">
+                  <pdfaProperty:name>DocumentFileName</pdfaProperty:name>
+                  <pdfaProperty:valueType>Text</pdfaProperty:valueType>
+                  <pdfaProperty:category>external</pdfaProperty:category>
+                  <pdfaProperty:description>The name of the embedded XML document</pdfaProperty:description>
+                </rdf:li>
+                <rdf:li rdf:parseType="Resource">
+                  <pdfaProperty:name>DocumentType</pdfaProperty:name>
+                  <pdfaProperty:valueType>Text</pdfaProperty:valueType>
+                  <pdfaProperty:category>external</pdfaProperty:category>
+                  <pdfaProperty:description>The type of the hybrid document in capital letters, e.g. INVOICE or ORDER</pdfaProperty:description>
+                </rdf:li>
+                <rdf:li rdf:parseType="Resource">
+                  <pdfaProperty:name>Version</pdfaProperty:name>
+                  <pdfaProperty:valueType>Text</pdfaProperty:valueType>
+                  <pdfaProperty:category>external</pdfaProperty:category>
+                  <pdfaProperty:description>The actual version of the standard applying to the embedded XML document</pdfaProperty:description>
+                </rdf:li>
+                <rdf:li rdf:parseType="Resource">
+                  <pdfaProperty:name>ConformanceLevel</pdfaProperty:name>
+                  <pdfaProperty:valueType>Text</pdfaProperty:valueType>
+                  <pdfaProperty:category>external</pdfaProperty:category>
+                  <pdfaProperty:description>The conformance level of the embedded XML document</pdfaProperty:description>
+                </rdf:li>
+              </rdf:Seq>
+            </pdfaSchema:property>
+          </rdf:li>
+        </rdf:Bag>
+      </pdfaExtension:schemas>
+    </rdf:Description>
+  </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`,
+		"\ufeff",
+		xmlText(doc.title),
+		creator,
+		xmpDate(created),
+		xmpDate(created),
+		pdfProducer,
+		pdfProducer,
+		facturXFilename,
+		facturXProfile,
+	))
+}
+
+// srgbProfile is a version 2 ICC profile for sRGB, built from the standard's
+// primaries adapted to D50 and its transfer curve, for the output intent.
+var srgbProfile = buildSRGBProfile()
+
+func buildSRGBProfile() []byte {
+	s15Fixed16 := func(buf *bytes.Buffer, values ...float64) {
+		for _, value := range values {
+			binary.Write(buf, binary.BigEndian, int32(math.Round(value*65536)))
+		}
+	}
+	xyz := func(x, y, z float64) []byte {
+		var buf bytes.Buffer
+		buf.WriteString("XYZ \x00\x00\x00\x00")
+		s15Fixed16(&buf, x, y, z)
+		return buf.Bytes()
+	}
+
+	name := "sRGB IEC61966-2.1"
+	var desc bytes.Buffer
+	desc.WriteString("desc\x00\x00\x00\x00")
+	binary.Write(&desc, binary.BigEndian, uint32(len(name)+1))
+	desc.WriteString(name + "\x00")
+	desc.Write(make([]byte, 4+4+2+1+67))
+
+	var curve bytes.Buffer
+	curve.WriteString("curv\x00\x00\x00\x00")
+	binary.Write(&curve, binary.BigEndian, uint32(1024))
+	for i := 0; i < 1024; i++ {
+		v := float64(i) / 1023
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		binary.Write(&curve, binary.BigEndian, uint16(math.Round(v*65535)))
+	}
+
+	tags := []struct {
+		signature string
+		data      []byte
+	}{
+		{"desc", desc.Bytes()},
+		{"cprt", []byte("text\x00\x00\x00\x00No copyright, use freely\x00")},
+		{"wtpt", xyz(0.9642, 1.0, 0.8249)},
+		{"rXYZ", xyz(0.4360747, 0.2225045, 0.0139322)},
+		{"gXYZ", xyz(0.3850649, 0.7168786, 0.0971045)},
+		{"bXYZ", xyz(0.1430804, 0.0606169, 0.7141733)},
+		{"rTRC", curve.Bytes()},
+		{"gTRC", nil},
+		{"bTRC", nil},
+	}
+
+	var table, data bytes.Buffer
+	binary.Write(&table, binary.BigEndian, uint32(len(tags)))
+	start := 128 + 4 + 12*len(tags)
+	var curveAt, curveSize int
+	for _, tag := range tags {
+		at, size := start+data.Len(), len(tag.data)
+		if tag.data == nil {
+			// The green and blue channels share the red transfer curve.
+			at, size = curveAt, curveSize
+		} else {
+			if tag.signature == "rTRC" {
+				curveAt, curveSize = at, size
+			}
+			data.Write(tag.data)
+			data.Write(make([]byte, (4-size%4)%4))
+		}
+		table.WriteString(tag.signature)
+		binary.Write(&table, binary.BigEndian, uint32(at))
+		binary.Write(&table, binary.BigEndian, uint32(size))
+	}
+
+	var header bytes.Buffer
+	binary.Write(&header, binary.BigEndian, uint32(start+data.Len()))
+	header.Write(make([]byte, 4))
+	binary.Write(&header, binary.BigEndian, uint32(0x02100000))
+	header.WriteString("mntrRGB XYZ ")
+	for _, part := range []uint16{2026, 1, 1, 0, 0, 0} {
+		binary.Write(&header, binary.BigEndian, part)
+	}
+	header.WriteString("acsp")
+	header.Write(make([]byte, 4+4+4+4+8+4))
+	s15Fixed16(&header, 0.9642, 1.0, 0.8249)
+	header.Write(make([]byte, 128-header.Len()))
+
+	return append(append(header.Bytes(), table.Bytes()...), data.Bytes()...)
+}
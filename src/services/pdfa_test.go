@@ -0,0 +1,112 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"invoicer-go/m/src/models"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRenderInvoicePdfFacturX(t *testing.T) {
+	address := &models.Address{Line1: "1 Rue de la Paix", City: "Paris", PostalCode: "75002", Country: "FR"}
+	invoice := &models.Invoice{
+		Currency:       "EUR",
+		Customer:       models.Customer{Name: "Société Générale", BillingAddress: address},
+		BillingAddress: address,
+		DateIssued:     time.Date(2026, time.March, 2, 10, 30, 0, 0, time.UTC),
+		DateDue:        time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC),
+		ReferenceNo:    "INV-0042",
+		Status:         models.Pending,
+		Title:          "Conseil – mars",
+		Items: []models.InvoiceItem{{
+			Description: "Consulting",
+			Price:       100,
+			Quantity:    decimal.NewFromInt(3),
+			Taxes:       models.ItemTaxes{{Name: "TVA", Rate: 20}},
+		}},
+	}
+	(&InvoiceService{}).calculateInvoiceTotals(invoice)
+	seller := &models.User{Name: "Zoë Dupont", Email: "zoe@example.com", Address: address}
+
+	raw, err := RenderInvoicePdf(invoice, seller, nil, true)
+	if err != nil {
+		t.Fatalf("RenderInvoicePdf: %v", err)
+	}
+
+	if !bytes.HasPrefix(raw, []byte(pdfA3Header)) {
+		t.Errorf("file starts with %q, want the PDF/A header", raw[:15])
+	}
+	if bytes.Contains(raw, []byte("/Helvetica")) {
+		t.Error("file uses a standard font that is not embedded")
+	}
+
+	start, err := pdfStartXref(raw)
+	if err != nil {
+		t.Fatalf("startxref: %v", err)
+	}
+	offsets, trailer, err := pdfXrefTable(raw, start)
+	if err != nil {
+		t.Fatalf("xref: %v", err)
+	}
+	for id := 1; id < len(offsets); id++ {
+		if want := fmt.Sprintf("%d 0 obj\n", id); !bytes.HasPrefix(raw[offsets[id]:], []byte(want)) {
+			t.Fatalf("xref entry %d points at %q", id, raw[offsets[id]:offsets[id]+10])
+		}
+	}
+	if !bytes.Contains(trailer, []byte("/ID [<")) {
+		t.Error("trailer has no file ID")
+	}
+
+	root := pdfRootPattern.FindSubmatch(trailer)
+	if root == nil {
+		t.Fatal("trailer has no root")
+	}
+	var rootId int
+	fmt.Sscanf(string(root[1]), "%d", &rootId)
+	catalog := raw[offsets[rootId]:]
+	catalog = catalog[:bytes.Index(catalog, []byte("endobj"))]
+	for _, entry := range []string{"/Metadata ", "/OutputIntents [", "/AF [", "/EmbeddedFiles"} {
+		if !bytes.Contains(catalog, []byte(entry)) {
+			t.Errorf("catalog has no %s entry", entry)
+		}
+	}
+
+	for _, want := range []string{
+		"/AFRelationship /Data",
+		"/Subtype /text#2Fxml",
+		"/S /GTS_PDFA1",
+		"<pdfaid:part>3</pdfaid:part>",
+		"<fx:ConformanceLevel>EN 16931</fx:ConformanceLevel>",
+		"<xmp:CreateDate>2026-03-02T10:30:00+00:00</xmp:CreateDate>",
+		"/CreationDate (D:20260302103000+00'00')",
+	} {
+		if !bytes.Contains(raw, []byte(want)) {
+			t.Errorf("file does not contain %q", want)
+		}
+	}
+}
+
+func TestSRGBProfile(t *testing.T) {
+	if size := binary.BigEndian.Uint32(srgbProfile); int(size) != len(srgbProfile) {
+		t.Errorf("profile declares %d bytes, has %d", size, len(srgbProfile))
+	}
+	if got := string(srgbProfile[12:24]); got != "mntrRGB XYZ " {
+		t.Errorf("profile class and spaces = %q", got)
+	}
+	if got := string(srgbProfile[36:40]); got != "acsp" {
+		t.Errorf("profile signature = %q", got)
+	}
+
+	count := int(binary.BigEndian.Uint32(srgbProfile[128:]))
+	for i := 0; i < count; i++ {
+		entry := srgbProfile[132+12*i:]
+		at, size := binary.BigEndian.Uint32(entry[4:]), binary.BigEndian.Uint32(entry[8:])
+		if at%4 != 0 || int(at+size) > len(srgbProfile) {
+			t.Errorf("tag %s at %d+%d lies outside the profile", entry[:4], at, size)
+		}
+	}
+}
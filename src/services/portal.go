@@ -0,0 +1,271 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"invoicer-go/m/src/config"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/models"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PortalService struct {
+	database *gorm.DB
+}
+
+func NewPortalService(database *gorm.DB) *PortalService {
+	return &PortalService{
+		database: database,
+	}
+}
+
+var ErrInvalidPortalLink = errors.New("login link is invalid or has expired")
+
+// portalOutstandingQuery is the customer's unpaid balance across its issued
+// invoices, in the base currency.
+const portalOutstandingQuery = `
+SELECT COALESCE(SUM((i.total - COALESCE(p.paid, 0)) * i.exchange_rate), 0)
+FROM invoices i
+LEFT JOIN (
+	SELECT invoice_id, SUM(amount) AS paid FROM payments GROUP BY invoice_id
+) p ON p.invoice_id = i.id
+WHERE i.customer_id = ? AND i.status IN ? AND i.archived_at IS NULL`
+
+// InviteCustomer emails the customer a magic link to the portal. Inviting a
+// customer is also what allows it to request new links later on.
+func (s *PortalService) InviteCustomer(customerId string, issuer *models.User) error {
+	customer, err := NewCustomerService(s.database).FindCustomerById(customerId)
+	if err != nil {
+		return err
+	}
+
+	return s.sendLoginLink(customer, issuer, customer.Email)
+}
+
+// RequestLoginLink emails a fresh magic link to a customer or contact email
+// address, one for each active customer the address belongs to. Unknown
+// addresses, archived or merged customers and customers that were never
+// invited are ignored, so the response does not reveal who has portal access.
+func (s *PortalService) RequestLoginLink(email string) error {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil
+	}
+
+	var customers []models.Customer
+	err := s.database.
+		Where(s.database.
+			Where("LOWER(email) = LOWER(?)", email).
+			Or("id IN (?)", s.database.Model(&models.CustomerContact{}).Select("customer_id").Where("LOWER(email) = LOWER(?)", email))).
+		Where("archived_at IS NULL AND merged_into_id IS NULL").
+		Order("created_at ASC, id ASC").
+		Find(&customers).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range customers {
+		if err := s.sendRequestedLink(&customers[i], email); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendRequestedLink sends a link issued by whoever last invited the customer.
+func (s *PortalService) sendRequestedLink(customer *models.Customer, email string) error {
+	var invite models.PortalToken
+	err := s.database.Where("customer_id = ?", customer.ID).Order("created_at DESC").First(&invite).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	issuer, err := NewAuthService(s.database).FindUserById(invite.IssuerID.String())
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return s.sendLoginLink(customer, issuer, email)
+}
+
+// Verify redeems a magic link and returns a portal session token. Each link
+// can only be used once.
+func (s *PortalService) Verify(token string) (*dto.PortalSession, error) {
+	var link models.PortalToken
+	err := s.database.Where("token_hash = ?", hashPortalToken(token)).First(&link).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidPortalLink
+		}
+		return nil, err
+	}
+	if link.UsedAt.Valid || time.Now().After(link.ExpiresAt) {
+		return nil, ErrInvalidPortalLink
+	}
+
+	result := s.database.Model(&models.PortalToken{}).
+		Where("id = ? AND used_at IS NULL", link.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidPortalLink
+	}
+
+	expiresIn := config.AppConfig.PortalSessionExpiresIn
+	session, err := lib.GenerateCustomerToken(link.CustomerID, link.IssuerID, expiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.PortalSession{
+		ExpiresAt: time.Now().Add(expiresIn),
+		Token:     session,
+	}, nil
+}
+
+func (s *PortalService) GetProfile(customer *models.Customer, issuer *models.User) (*dto.PortalProfile, error) {
+	var outstanding float64
+	err := s.database.Raw(portalOutstandingQuery, customer.ID, []models.InvoiceStatus{models.Pending, models.Overdue}).
+		Scan(&outstanding).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.PortalProfile{
+		Currency:    config.AppConfig.BaseCurrency,
+		CustomerID:  customer.ID.String(),
+		Email:       customer.Email,
+		Issuer:      sellerName(issuer),
+		IssuerEmail: issuer.Email,
+		Name:        customer.Name,
+		Outstanding: roundAmount(outstanding),
+	}, nil
+}
+
+// GetInvoices lists the customer's issued invoices. Drafts are never shown in
+// the portal.
+func (s *PortalService) GetInvoices(customerId string, params dto.InvoicePagination) (interface{}, error) {
+	statuses, err := portalStatuses(params.Status)
+	if err != nil {
+		return nil, err
+	}
+	params.Status = statuses
+	params.CustomerID = &customerId
+	params.IncludeArchived = false
+	// Tags are the account's own labels and are not shown in the portal.
+	params.Tags = nil
+
+	invoices := NewInvoiceService(s.database)
+	if params.UsesCursor() {
+		return invoices.GetInvoicesByCursor(params)
+	}
+	return invoices.GetInvoices(params)
+}
+
+func (s *PortalService) GetInvoice(customerId, invoiceId string) (*models.Invoice, error) {
+	invoice, err := NewInvoiceService(s.database).GetInvoice(invoiceId)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.CustomerID.String() != customerId || invoice.Status == models.Draft || invoice.ArchivedAt.Valid {
+		return nil, ErrInvoiceNotFound
+	}
+
+	// Portal views have no user behind them; the customer is the actor.
+	err = recordInvoiceEvent(s.database, invoice.ID, models.InvoiceViewed, "", models.EventData{
+		"customerId": customerId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+func (s *PortalService) sendLoginLink(customer *models.Customer, issuer *models.User, email string) error {
+	token, err := generatePortalToken()
+	if err != nil {
+		return err
+	}
+
+	expiresIn := config.AppConfig.PortalLinkExpiresIn
+	link := &models.PortalToken{
+		CustomerID: customer.ID,
+		ExpiresAt:  time.Now().Add(expiresIn),
+		IssuerID:   issuer.ID,
+		TokenHash:  hashPortalToken(token),
+	}
+	if err := s.database.Create(link).Error; err != nil {
+		return err
+	}
+
+	err = lib.SendEmail(lib.EmailDto{
+		To:       []string{email},
+		Subject:  fmt.Sprintf("Your %s customer portal link", sellerName(issuer)),
+		Template: "portal",
+		Data: map[string]interface{}{
+			"name":    customer.Name,
+			"company": sellerName(issuer),
+			"link":    fmt.Sprintf("%s/portal/verify?token=%s", config.AppConfig.ClientUrl, url.QueryEscape(token)),
+			"minutes": int(expiresIn.Minutes()),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrEmailSendFailed, err)
+	}
+
+	return nil
+}
+
+// portalStatuses keeps drafts out of portal listings, whether or not the
+// customer filtered by status.
+func portalStatuses(values []string) ([]string, error) {
+	statuses, err := parseInvoiceStatuses(values)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := []string{}
+	for _, status := range statuses {
+		if status != models.Draft {
+			visible = append(visible, string(status))
+		}
+	}
+	if len(statuses) > 0 && len(visible) == 0 {
+		return nil, ErrInvalidInvoiceStatus
+	}
+	if len(visible) == 0 {
+		visible = []string{string(models.Pending), string(models.Overdue), string(models.Paid)}
+	}
+
+	return visible, nil
+}
+
+func generatePortalToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func hashPortalToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
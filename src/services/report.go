@@ -0,0 +1,402 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"invoicer-go/m/src/config"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/models"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidReportPeriod = errors.New("period must be either month or quarter")
+	ErrInvalidReportRange  = errors.New("from must be before to")
+	ErrInvalidTagSource    = errors.New("source must be either invoice or customer")
+)
+
+type ReportService struct {
+	database *gorm.DB
+}
+
+func NewReportService(database *gorm.DB) *ReportService {
+	return &ReportService{
+		database: database,
+	}
+}
+
+// agingQuery buckets each invoice's outstanding balance, converted to the base
+// currency, by the number of days it is past due as of @asOf. Invoices issued
+// and payments received after @asOf are ignored so past reports can be rebuilt.
+const agingQuery = `
+SELECT
+	c.id AS customer_id,
+	c.name AS customer_name,
+	COUNT(*) AS invoice_count,
+	SUM(CASE WHEN b.days_overdue <= 0 THEN b.outstanding ELSE 0 END) AS current,
+	SUM(CASE WHEN b.days_overdue BETWEEN 1 AND 30 THEN b.outstanding ELSE 0 END) AS days_1_to_30,
+	SUM(CASE WHEN b.days_overdue BETWEEN 31 AND 60 THEN b.outstanding ELSE 0 END) AS days_31_to_60,
+	SUM(CASE WHEN b.days_overdue BETWEEN 61 AND 90 THEN b.outstanding ELSE 0 END) AS days_61_to_90,
+	SUM(CASE WHEN b.days_overdue > 90 THEN b.outstanding ELSE 0 END) AS over_90,
+	SUM(b.outstanding) AS total
+FROM (
+	SELECT
+		i.customer_id,
+		(i.total - COALESCE(p.paid, 0)) * i.exchange_rate AS outstanding,
+		CAST(@asOf AS date) - CAST(i.date_due AS date) AS days_overdue
+	FROM invoices i
+	LEFT JOIN (
+		SELECT invoice_id, SUM(amount) AS paid
+		FROM payments
+		WHERE paid_at <= @asOf
+		GROUP BY invoice_id
+	) p ON p.invoice_id = i.id
+	WHERE i.status <> @draft AND i.archived_at IS NULL AND i.date_issued <= @asOf
+) b
+JOIN customers c ON c.id = b.customer_id
+WHERE b.outstanding > @tolerance
+GROUP BY c.id, c.name
+ORDER BY total DESC`
+
+func (s *ReportService) GetAgingReport(params dto.AgingReportParams) (*dto.AgingReport, error) {
+	asOf := time.Now()
+	if params.AsOf != nil {
+		asOf = endOfDay(*params.AsOf)
+	}
+
+	customers := []dto.CustomerAging{}
+	err := s.database.Raw(agingQuery,
+		sql.Named("asOf", asOf),
+		sql.Named("draft", models.Draft),
+		sql.Named("tolerance", balanceTolerance),
+	).Scan(&customers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	report := &dto.AgingReport{
+		AsOf:      asOf,
+		Currency:  config.AppConfig.BaseCurrency,
+		Customers: customers,
+	}
+
+	for i := range customers {
+		row := &customers[i].AgingBuckets
+		roundBuckets(row)
+		report.Totals.Current += row.Current
+		report.Totals.Days1To30 += row.Days1To30
+		report.Totals.Days31To60 += row.Days31To60
+		report.Totals.Days61To90 += row.Days61To90
+		report.Totals.Over90 += row.Over90
+		report.Totals.Total += row.Total
+	}
+	roundBuckets(&report.Totals)
+
+	return report, nil
+}
+
+func roundBuckets(buckets *dto.AgingBuckets) {
+	buckets.Current = roundAmount(buckets.Current)
+	buckets.Days1To30 = roundAmount(buckets.Days1To30)
+	buckets.Days31To60 = roundAmount(buckets.Days31To60)
+	buckets.Days61To90 = roundAmount(buckets.Days61To90)
+	buckets.Over90 = roundAmount(buckets.Over90)
+	buckets.Total = roundAmount(buckets.Total)
+}
+
+func endOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 23, 59, 59, 0, t.Location())
+}
+
+// summaryInvoices converts every non-draft invoice issued in the range to the
+// base currency, along with what had been paid against it by the end of it.
+const summaryInvoices = `
+WITH inv AS (
+	SELECT
+		i.id,
+		i.customer_id,
+		i.date_issued,
+		i.total * i.exchange_rate AS total,
+		COALESCE(p.paid, 0) * i.exchange_rate AS paid,
+		p.last_paid_at
+	FROM invoices i
+	LEFT JOIN (
+		SELECT invoice_id, SUM(amount) AS paid, MAX(paid_at) AS last_paid_at
+		FROM payments
+		WHERE paid_at <= @to
+		GROUP BY invoice_id
+	) p ON p.invoice_id = i.id
+	WHERE i.status <> @draft AND i.archived_at IS NULL AND i.date_issued BETWEEN @from AND @to
+)`
+
+const summaryTotalsQuery = summaryInvoices + `
+SELECT
+	COUNT(*) AS invoice_count,
+	COALESCE(SUM(total), 0) AS invoiced,
+	COALESCE(SUM(GREATEST(total - paid, 0)), 0) AS outstanding,
+	AVG(EXTRACT(EPOCH FROM (last_paid_at - date_issued)) / 86400)
+		FILTER (WHERE last_paid_at IS NOT NULL AND paid >= total - @tolerance) AS average_days_to_pay
+FROM inv`
+
+const summaryTopCustomersQuery = summaryInvoices + `
+SELECT
+	c.id AS customer_id,
+	c.name AS customer_name,
+	COUNT(*) AS invoice_count,
+	SUM(inv.total) AS invoiced,
+	SUM(inv.paid) AS collected,
+	SUM(GREATEST(inv.total - inv.paid, 0)) AS outstanding
+FROM inv
+JOIN customers c ON c.id = inv.customer_id
+GROUP BY c.id, c.name
+ORDER BY invoiced DESC
+LIMIT @limit`
+
+const summaryCollectedQuery = `
+SELECT COALESCE(SUM(p.amount * i.exchange_rate), 0)
+FROM payments p
+JOIN invoices i ON i.id = p.invoice_id
+WHERE i.archived_at IS NULL AND p.paid_at BETWEEN @from AND @to`
+
+const summaryStatusQuery = `
+SELECT status, COUNT(*) AS count, COALESCE(SUM(total * exchange_rate), 0) AS amount
+FROM invoices
+WHERE archived_at IS NULL AND date_issued BETWEEN @from AND @to
+GROUP BY status
+ORDER BY status`
+
+const summaryInvoicedByPeriodQuery = `
+SELECT date_trunc(@period, date_issued) AS period, SUM(total * exchange_rate) AS invoiced
+FROM invoices
+WHERE status <> @draft AND archived_at IS NULL AND date_issued BETWEEN @from AND @to
+GROUP BY 1
+ORDER BY 1`
+
+const summaryCollectedByPeriodQuery = `
+SELECT date_trunc(@period, p.paid_at) AS period, SUM(p.amount * i.exchange_rate) AS collected
+FROM payments p
+JOIN invoices i ON i.id = p.invoice_id
+WHERE i.archived_at IS NULL AND p.paid_at BETWEEN @from AND @to
+GROUP BY 1
+ORDER BY 1`
+
+func (s *ReportService) GetSummaryReport(params dto.SummaryReportParams) (*dto.SummaryReport, error) {
+	now := time.Now()
+	from := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+	to := now
+	if params.From != nil {
+		from = *params.From
+	}
+	if params.To != nil {
+		to = endOfDay(*params.To)
+	}
+	if !from.Before(to) {
+		return nil, ErrInvalidReportRange
+	}
+
+	if params.Period == "" {
+		params.Period = "month"
+	}
+	if params.Period != "month" && params.Period != "quarter" {
+		return nil, ErrInvalidReportPeriod
+	}
+
+	if params.Limit <= 0 {
+		params.Limit = 5
+	}
+	if params.Limit > 50 {
+		params.Limit = 50
+	}
+
+	args := []interface{}{
+		sql.Named("from", from),
+		sql.Named("to", to),
+		sql.Named("draft", models.Draft),
+		sql.Named("tolerance", balanceTolerance),
+		sql.Named("period", params.Period),
+		sql.Named("limit", params.Limit),
+	}
+
+	var totals struct {
+		InvoiceCount     int
+		Invoiced         float64
+		Outstanding      float64
+		AverageDaysToPay *float64
+	}
+	if err := s.database.Raw(summaryTotalsQuery, args...).Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+
+	var collected float64
+	if err := s.database.Raw(summaryCollectedQuery, args...).Scan(&collected).Error; err != nil {
+		return nil, err
+	}
+
+	report := &dto.SummaryReport{
+		Collected:    roundAmount(collected),
+		Currency:     config.AppConfig.BaseCurrency,
+		From:         from,
+		InvoiceCount: totals.InvoiceCount,
+		Invoiced:     roundAmount(totals.Invoiced),
+		Outstanding:  roundAmount(totals.Outstanding),
+		Period:       params.Period,
+		Revenue:      []dto.RevenuePeriod{},
+		StatusCounts: []dto.StatusCount{},
+		To:           to,
+		TopCustomers: []dto.CustomerRevenue{},
+	}
+	if totals.AverageDaysToPay != nil {
+		days := roundAmount(*totals.AverageDaysToPay)
+		report.AverageDaysToPay = &days
+	}
+
+	if err := s.database.Raw(summaryStatusQuery, args...).Scan(&report.StatusCounts).Error; err != nil {
+		return nil, err
+	}
+	for i := range report.StatusCounts {
+		report.StatusCounts[i].Amount = roundAmount(report.StatusCounts[i].Amount)
+	}
+
+	if err := s.database.Raw(summaryTopCustomersQuery, args...).Scan(&report.TopCustomers).Error; err != nil {
+		return nil, err
+	}
+	for i := range report.TopCustomers {
+		customer := &report.TopCustomers[i]
+		customer.Invoiced = roundAmount(customer.Invoiced)
+		customer.Collected = roundAmount(customer.Collected)
+		customer.Outstanding = roundAmount(customer.Outstanding)
+	}
+
+	revenue, err := s.revenueByPeriod(args)
+	if err != nil {
+		return nil, err
+	}
+	report.Revenue = revenue
+
+	return report, nil
+}
+
+// revenueByPeriod merges what was invoiced and what was collected in each
+// period. The two series come from different dates, so a period may only
+// appear in one of them.
+func (s *ReportService) revenueByPeriod(args []interface{}) ([]dto.RevenuePeriod, error) {
+	var invoiced, collected []dto.RevenuePeriod
+	if err := s.database.Raw(summaryInvoicedByPeriodQuery, args...).Scan(&invoiced).Error; err != nil {
+		return nil, err
+	}
+	if err := s.database.Raw(summaryCollectedByPeriodQuery, args...).Scan(&collected).Error; err != nil {
+		return nil, err
+	}
+
+	periods := map[int64]dto.RevenuePeriod{}
+	for _, row := range invoiced {
+		entry := periods[row.Period.Unix()]
+		entry.Period = row.Period
+		entry.Invoiced = roundAmount(row.Invoiced)
+		periods[row.Period.Unix()] = entry
+	}
+	for _, row := range collected {
+		entry := periods[row.Period.Unix()]
+		entry.Period = row.Period
+		entry.Collected = roundAmount(row.Collected)
+		periods[row.Period.Unix()] = entry
+	}
+
+	revenue := make([]dto.RevenuePeriod, 0, len(periods))
+	for _, entry := range periods {
+		revenue = append(revenue, entry)
+	}
+	sort.Slice(revenue, func(i, j int) bool { return revenue[i].Period.Before(revenue[j].Period) })
+
+	return revenue, nil
+}
+
+// tagReportQueries group the summary invoices by the user's tags, either those
+// on the invoices themselves or those on their customers. An invoice with
+// several tags counts towards each of them.
+var tagReportQueries = map[string]string{
+	"invoice": summaryInvoices + `
+SELECT
+	t.id AS tag_id,
+	t.name AS tag_name,
+	t.color AS tag_color,
+	COUNT(*) AS invoice_count,
+	SUM(inv.total) AS invoiced,
+	SUM(inv.paid) AS collected,
+	SUM(GREATEST(inv.total - inv.paid, 0)) AS outstanding
+FROM inv
+JOIN invoice_tags it ON it.invoice_id = inv.id
+JOIN tags t ON t.id = it.tag_id
+WHERE t.user_id = @user
+GROUP BY t.id, t.name, t.color
+ORDER BY invoiced DESC`,
+	"customer": summaryInvoices + `
+SELECT
+	t.id AS tag_id,
+	t.name AS tag_name,
+	t.color AS tag_color,
+	COUNT(*) AS invoice_count,
+	SUM(inv.total) AS invoiced,
+	SUM(inv.paid) AS collected,
+	SUM(GREATEST(inv.total - inv.paid, 0)) AS outstanding
+FROM inv
+JOIN customer_tags ct ON ct.customer_id = inv.customer_id
+JOIN tags t ON t.id = ct.tag_id
+WHERE t.user_id = @user
+GROUP BY t.id, t.name, t.color
+ORDER BY invoiced DESC`,
+}
+
+func (s *ReportService) GetTagReport(userId string, params dto.TagReportParams) (*dto.TagReport, error) {
+	now := time.Now()
+	from := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+	to := now
+	if params.From != nil {
+		from = *params.From
+	}
+	if params.To != nil {
+		to = endOfDay(*params.To)
+	}
+	if !from.Before(to) {
+		return nil, ErrInvalidReportRange
+	}
+
+	if params.Source == "" {
+		params.Source = "invoice"
+	}
+	query, ok := tagReportQueries[params.Source]
+	if !ok {
+		return nil, ErrInvalidTagSource
+	}
+
+	report := &dto.TagReport{
+		Currency: config.AppConfig.BaseCurrency,
+		From:     from,
+		Source:   params.Source,
+		Tags:     []dto.TagRevenue{},
+		To:       to,
+	}
+
+	err := s.database.Raw(query,
+		sql.Named("from", from),
+		sql.Named("to", to),
+		sql.Named("draft", models.Draft),
+		sql.Named("user", userId),
+	).Scan(&report.Tags).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range report.Tags {
+		tag := &report.Tags[i]
+		tag.Invoiced = roundAmount(tag.Invoiced)
+		tag.Collected = roundAmount(tag.Collected)
+		tag.Outstanding = roundAmount(tag.Outstanding)
+	}
+
+	return report, nil
+}
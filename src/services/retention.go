@@ -0,0 +1,120 @@
+package services
+
+import (
+	"invoicer-go/m/src/models"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RetentionService struct {
+	database *gorm.DB
+}
+
+func NewRetentionService(database *gorm.DB) *RetentionService {
+	return &RetentionService{
+		database: database,
+	}
+}
+
+// Run purges expired archives and idempotency keys straight away and then on
+// every interval. It blocks, so start it on its own goroutine.
+func (s *RetentionService) Run(interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		invoices, customers, err := s.PurgeArchived(time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Failed to purge archived records: %v", err)
+		} else if invoices > 0 || customers > 0 {
+			log.Printf("Purged %d archived invoices and %d archived customers", invoices, customers)
+		}
+		if _, err := NewIdempotencyService(s.database).PurgeExpired(time.Now()); err != nil {
+			log.Printf("Failed to purge expired idempotency keys: %v", err)
+		}
+
+		<-ticker.C
+	}
+}
+
+// PurgeArchived permanently deletes invoices and customers archived before
+// the cutoff. A customer is only purged once none of its invoices are left.
+// Time and expenses billed on a purged invoice become unbilled again.
+func (s *RetentionService) PurgeArchived(before time.Time) (invoices int64, customers int64, err error) {
+	err = s.database.Transaction(func(tx *gorm.DB) error {
+		expired := tx.Model(&models.Invoice{}).Select("id").Where("archived_at < ?", before)
+
+		if err := tx.Where("invoice_id IN (?)", expired).Delete(&models.InvoiceItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("invoice_id IN (?)", expired).Delete(&models.Payment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("invoice_id IN (?)", expired).Delete(&models.InvoiceInstallment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("invoice_id IN (?)", expired).Delete(&models.InvoiceEvent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("invoice_id IN (?)", expired).Delete(&models.InvoiceComment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM invoice_tags WHERE invoice_id IN (?)", expired).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&models.TimeEntry{}).Where("invoice_id IN (?)", expired).Updates(map[string]interface{}{
+			"billed_at":  nil,
+			"invoice_id": nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&models.Expense{}).Where("invoice_id IN (?)", expired).Updates(map[string]interface{}{
+			"billed_at":  nil,
+			"invoice_id": nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+		result := tx.Where("archived_at < ?", before).Delete(&models.Invoice{})
+		if result.Error != nil {
+			return result.Error
+		}
+		invoices = result.RowsAffected
+
+		purgeable := tx.Model(&models.Customer{}).Select("id").
+			Where("archived_at < ?", before).
+			Where("NOT EXISTS (SELECT 1 FROM invoices WHERE invoices.customer_id = customers.id)")
+
+		if err := tx.Where("customer_id IN (?)", purgeable).Delete(&models.CustomerContact{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("customer_id IN (?)", purgeable).Delete(&models.PortalToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("customer_id IN (?)", purgeable).Delete(&models.TimeEntry{}).Error; err != nil {
+			return err
+		}
+		err = tx.Model(&models.Expense{}).Where("customer_id IN (?)", purgeable).Updates(map[string]interface{}{
+			"billable":    false,
+			"customer_id": nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM customer_tags WHERE customer_id IN (?)", purgeable).Error; err != nil {
+			return err
+		}
+		result = tx.Where("id IN (?)", purgeable).Delete(&models.Customer{})
+		if result.Error != nil {
+			return result.Error
+		}
+		customers = result.RowsAffected
+
+		return nil
+	})
+
+	return invoices, customers, err
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"invoicer-go/m/src/dto"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+type SearchService struct {
+	database *gorm.DB
+}
+
+func NewSearchService(database *gorm.DB) *SearchService {
+	return &SearchService{
+		database: database,
+	}
+}
+
+var (
+	ErrEmptySearchQuery  = errors.New("search query is required")
+	ErrInvalidSearchType = errors.New("search type must be invoice or customer")
+)
+
+const (
+	searchTypeCustomer = "customer"
+	searchTypeInvoice  = "invoice"
+)
+
+var searchQueries = map[string]string{
+	searchTypeInvoice: `
+SELECT
+	'invoice' AS type,
+	i.id,
+	COALESCE(NULLIF(i.title, ''), i.reference_no) AS title,
+	i.reference_no || ' · ' || c.name AS subtitle,
+	ts_rank(i.search_vector, to_tsquery('simple', @query)) AS rank
+FROM invoices i
+JOIN customers c ON c.id = i.customer_id
+WHERE i.search_vector @@ to_tsquery('simple', @query) AND i.archived_at IS NULL`,
+	searchTypeCustomer: `
+SELECT
+	'customer' AS type,
+	c.id,
+	c.name AS title,
+	c.email AS subtitle,
+	ts_rank(c.search_vector, to_tsquery('simple', @query)) AS rank
+FROM customers c
+WHERE c.search_vector @@ to_tsquery('simple', @query) AND c.archived_at IS NULL`,
+}
+
+// Search looks up invoices and customers in one ranked list. Every word in
+// the query must match, and the last characters typed may be the start of a
+// word.
+func (s *SearchService) Search(params dto.SearchParams) ([]dto.SearchResult, error) {
+	query := prefixSearchQuery(params.Query)
+	if query == "" {
+		return nil, ErrEmptySearchQuery
+	}
+
+	types := []string{searchTypeInvoice, searchTypeCustomer}
+	if strings.TrimSpace(params.Types) != "" {
+		types = []string{}
+		for _, kind := range strings.Split(params.Types, ",") {
+			kind = strings.ToLower(strings.TrimSpace(kind))
+			if _, ok := searchQueries[kind]; !ok {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidSearchType, kind)
+			}
+			types = append(types, kind)
+		}
+	}
+
+	parts := make([]string, 0, len(types))
+	for _, kind := range types {
+		parts = append(parts, searchQueries[kind])
+	}
+	statement := strings.Join(parts, "\nUNION ALL\n") + "\nORDER BY rank DESC, title ASC\nLIMIT @limit"
+
+	results := []dto.SearchResult{}
+	err := s.database.Raw(statement,
+		sql.Named("query", query),
+		sql.Named("limit", normalizeLimit(params.Limit)),
+	).Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// prefixSearchQuery turns free text into a tsquery that requires every word
+// and lets each one match as a prefix. Characters with a meaning in tsquery
+// syntax are dropped, so user input can never produce a syntax error.
+func prefixSearchQuery(input string) string {
+	terms := []string{}
+	for _, word := range strings.Fields(input) {
+		word = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("@.-_+", r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, word)
+		word = strings.Trim(word, ".-_+@")
+		if word != "" {
+			terms = append(terms, "'"+word+"':*")
+		}
+	}
+	return strings.Join(terms, " & ")
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"invoicer-go/m/src/config"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/lib"
+	"invoicer-go/m/src/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type StatementService struct {
+	database *gorm.DB
+}
+
+func NewStatementService(database *gorm.DB) *StatementService {
+	return &StatementService{
+		database: database,
+	}
+}
+
+// statementOpeningQuery is everything invoiced to the customer before @from
+// less everything paid or credited before it, in the base currency.
+const statementOpeningQuery = `
+SELECT
+	COALESCE((
+		SELECT SUM(total * exchange_rate)
+		FROM invoices
+		WHERE customer_id = @customer AND status <> @draft AND archived_at IS NULL AND date_issued < @from
+	), 0) - COALESCE((
+		SELECT SUM(p.amount * i.exchange_rate)
+		FROM payments p
+		JOIN invoices i ON i.id = p.invoice_id
+		WHERE i.customer_id = @customer AND i.status <> @draft AND i.archived_at IS NULL AND p.paid_at < @from
+	), 0)`
+
+const statementEntriesQuery = `
+SELECT
+	'invoice' AS type,
+	0 AS sort_order,
+	i.id AS document_id,
+	i.reference_no AS reference,
+	i.title AS description,
+	i.date_issued AS date,
+	i.currency,
+	i.total AS original_amount,
+	i.total * i.exchange_rate AS debit,
+	0 AS credit
+FROM invoices i
+WHERE i.customer_id = @customer AND i.status <> @draft AND i.archived_at IS NULL AND i.date_issued BETWEEN @from AND @to
+UNION ALL
+SELECT
+	p.kind AS type,
+	1 AS sort_order,
+	p.id AS document_id,
+	p.reference,
+	i.reference_no AS description,
+	p.paid_at AS date,
+	i.currency,
+	p.amount AS original_amount,
+	0 AS debit,
+	p.amount * i.exchange_rate AS credit
+FROM payments p
+JOIN invoices i ON i.id = p.invoice_id
+WHERE i.customer_id = @customer AND i.status <> @draft AND i.archived_at IS NULL AND p.paid_at BETWEEN @from AND @to
+ORDER BY date, sort_order`
+
+func (s *StatementService) GetStatement(customerId string, params dto.StatementParams) (*dto.Statement, error) {
+	customer, err := NewCustomerService(s.database).FindCustomerById(customerId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	to := now
+	if params.From != nil {
+		from = *params.From
+	}
+	if params.To != nil {
+		to = endOfDay(*params.To)
+	}
+	if !from.Before(to) {
+		return nil, ErrInvalidReportRange
+	}
+
+	args := []interface{}{
+		sql.Named("customer", customer.ID),
+		sql.Named("draft", models.Draft),
+		sql.Named("from", from),
+		sql.Named("to", to),
+	}
+
+	var opening float64
+	if err := s.database.Raw(statementOpeningQuery, args...).Scan(&opening).Error; err != nil {
+		return nil, err
+	}
+
+	entries := []dto.StatementEntry{}
+	if err := s.database.Raw(statementEntriesQuery, args...).Scan(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	statement := &dto.Statement{
+		Currency:       config.AppConfig.BaseCurrency,
+		CustomerEmail:  customer.Email,
+		CustomerID:     customer.ID.String(),
+		CustomerName:   customer.Name,
+		Entries:        entries,
+		From:           from,
+		OpeningBalance: roundAmount(opening),
+		To:             to,
+	}
+
+	balance := statement.OpeningBalance
+	for i := range entries {
+		entry := &entries[i]
+		entry.Debit = roundAmount(entry.Debit)
+		entry.Credit = roundAmount(entry.Credit)
+		balance = roundAmount(balance + entry.Debit - entry.Credit)
+		entry.Balance = balance
+		statement.TotalDebits += entry.Debit
+		statement.TotalCredits += entry.Credit
+	}
+	statement.TotalDebits = roundAmount(statement.TotalDebits)
+	statement.TotalCredits = roundAmount(statement.TotalCredits)
+	statement.ClosingBalance = balance
+
+	return statement, nil
+}
+
+func (s *StatementService) SendStatement(customerId string, params dto.StatementParams, seller *models.User) error {
+	statement, err := s.GetStatement(customerId, params)
+	if err != nil {
+		return err
+	}
+
+	customer, err := NewCustomerService(s.database).FindCustomerById(customerId)
+	if err != nil {
+		return err
+	}
+	to, cc, err := NewContactService(s.database).Recipients(customer)
+	if err != nil {
+		return err
+	}
+
+	content, err := RenderStatementPdf(statement, seller)
+	if err != nil {
+		return err
+	}
+
+	err = lib.SendEmail(lib.EmailDto{
+		To:       to,
+		Cc:       cc,
+		Subject:  fmt.Sprintf("Statement of account from %s", sellerName(seller)),
+		Template: "statement",
+		Data: map[string]interface{}{
+			"name":           statement.CustomerName,
+			"company":        sellerName(seller),
+			"from":           formatPdfDate(statement.From),
+			"to":             formatPdfDate(statement.To),
+			"closingBalance": statement.Currency + " " + formatAmount(statement.ClosingBalance),
+		},
+		Attachments: []lib.EmailAttachment{{
+			Filename: StatementFilename(statement),
+			Content:  content,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrEmailSendFailed, err)
+	}
+
+	return nil
+}
+
+func StatementFilename(statement *dto.Statement) string {
+	return fmt.Sprintf("statement-%s-%s.pdf", lib.ToKebabCase(statement.CustomerName), statement.To.Format("2006-01-02"))
+}
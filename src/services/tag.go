@@ -0,0 +1,280 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/models"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TagService struct {
+	database *gorm.DB
+}
+
+func NewTagService(database *gorm.DB) *TagService {
+	return &TagService{
+		database: database,
+	}
+}
+
+var (
+	ErrInvalidTagColor = errors.New("tag color must be a hex color such as #1e88e5")
+	ErrInvalidTagId    = errors.New("tags must be given by their ids")
+	ErrInvalidTagMatch = errors.New("tag match must be either any or all")
+	ErrInvalidTagName  = errors.New("tag name is required and must be at most 50 characters")
+	ErrTagExists       = errors.New("a tag with this name already exists")
+	ErrTagNotFound     = errors.New("tag not found")
+)
+
+const defaultTagColor = "#6b7280"
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+func (s *TagService) CreateTag(userId string, payload dto.CreateTagDto) (*models.Tag, error) {
+	name, color, err := normalizeTag(payload.Name, payload.Color)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureUniqueName(userId, name, ""); err != nil {
+		return nil, err
+	}
+
+	tag := &models.Tag{Color: color, Name: name, UserID: uuid.MustParse(userId)}
+	if err := s.database.Create(tag).Error; err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+func (s *TagService) GetTags(userId string) ([]models.Tag, error) {
+	var tags []models.Tag
+	if err := s.database.Where("user_id = ?", userId).Order("name ASC").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (s *TagService) UpdateTag(userId, id string, payload dto.UpdateTagDto) (*models.Tag, error) {
+	tag, err := s.FindTagById(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	name, color := tag.Name, tag.Color
+	if payload.Name != nil {
+		name = *payload.Name
+	}
+	if payload.Color != nil {
+		color = *payload.Color
+	}
+	if tag.Name, tag.Color, err = normalizeTag(name, color); err != nil {
+		return nil, err
+	}
+	if err := s.ensureUniqueName(userId, tag.Name, id); err != nil {
+		return nil, err
+	}
+
+	if err := s.database.Save(tag).Error; err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+// DeleteTag removes the tag from every invoice and customer it is on before
+// deleting it.
+func (s *TagService) DeleteTag(userId, id string) error {
+	tag, err := s.FindTagById(userId, id)
+	if err != nil {
+		return err
+	}
+
+	return s.database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM invoice_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM customer_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(tag).Error
+	})
+}
+
+func (s *TagService) FindTagById(userId, id string) (*models.Tag, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrTagNotFound
+	}
+
+	tag := &models.Tag{}
+	if err := s.database.Where("id = ? AND user_id = ?", id, userId).First(tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTagNotFound
+		}
+		return nil, err
+	}
+	return tag, nil
+}
+
+func (s *TagService) AddInvoiceTags(userId, invoiceId string, payload dto.TagIdsDto) ([]models.Tag, error) {
+	invoice, err := NewInvoiceService(s.database).FindInvoiceById(invoiceId)
+	if err != nil {
+		return nil, err
+	}
+	return s.addTags(userId, invoice, payload.TagIDs)
+}
+
+func (s *TagService) RemoveInvoiceTag(userId, invoiceId, tagId string) ([]models.Tag, error) {
+	invoice, err := NewInvoiceService(s.database).FindInvoiceById(invoiceId)
+	if err != nil {
+		return nil, err
+	}
+	return s.removeTag(userId, invoice, tagId)
+}
+
+func (s *TagService) AddCustomerTags(userId, customerId string, payload dto.TagIdsDto) ([]models.Tag, error) {
+	customer, err := NewCustomerService(s.database).FindCustomerById(customerId)
+	if err != nil {
+		return nil, err
+	}
+	return s.addTags(userId, customer, payload.TagIDs)
+}
+
+func (s *TagService) RemoveCustomerTag(userId, customerId, tagId string) ([]models.Tag, error) {
+	customer, err := NewCustomerService(s.database).FindCustomerById(customerId)
+	if err != nil {
+		return nil, err
+	}
+	return s.removeTag(userId, customer, tagId)
+}
+
+// addTags attaches the user's tags to an invoice or customer and returns all
+// of the record's tags. Tags that are already attached are left alone.
+func (s *TagService) addTags(userId string, owner interface{}, ids []string) ([]models.Tag, error) {
+	ids = splitList(ids)
+	if len(ids) == 0 {
+		return nil, ErrTagNotFound
+	}
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, ErrTagNotFound
+		}
+	}
+
+	var tags []models.Tag
+	if err := s.database.Where("id IN ? AND user_id = ?", ids, userId).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	if len(tags) != len(ids) {
+		return nil, ErrTagNotFound
+	}
+
+	if err := s.database.Model(owner).Association("Tags").Append(tags); err != nil {
+		return nil, err
+	}
+
+	return s.ownerTags(userId, owner)
+}
+
+func (s *TagService) removeTag(userId string, owner interface{}, tagId string) ([]models.Tag, error) {
+	tag, err := s.FindTagById(userId, tagId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.database.Model(owner).Association("Tags").Delete(tag); err != nil {
+		return nil, err
+	}
+
+	return s.ownerTags(userId, owner)
+}
+
+func (s *TagService) ownerTags(userId string, owner interface{}) ([]models.Tag, error) {
+	tags := []models.Tag{}
+	err := s.database.Model(owner).Where("tags.user_id = ?", userId).Order("tags.name ASC").Association("Tags").Find(&tags)
+	return tags, err
+}
+
+func (s *TagService) ensureUniqueName(userId, name, exceptId string) error {
+	query := s.database.Model(&models.Tag{}).Where("user_id = ? AND LOWER(name) = LOWER(?)", userId, name)
+	if exceptId != "" {
+		query = query.Where("id <> ?", exceptId)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrTagExists
+	}
+	return nil
+}
+
+func normalizeTag(name, color string) (string, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > 50 {
+		return "", "", ErrInvalidTagName
+	}
+
+	color = strings.ToLower(strings.TrimSpace(color))
+	if color == "" {
+		color = defaultTagColor
+	}
+	if !tagColorPattern.MatchString(color) {
+		return "", "", ErrInvalidTagColor
+	}
+
+	return name, color, nil
+}
+
+// filterByTags keeps the rows tagged with any, or all, of the given tags of
+// the user. joinTable links the rows to tags through ownerColumn.
+func filterByTags(query *gorm.DB, userId, idColumn, joinTable, ownerColumn string, values []string, match string) (*gorm.DB, error) {
+	ids := splitList(values)
+	if len(ids) == 0 {
+		return query, nil
+	}
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, ErrInvalidTagId
+		}
+	}
+
+	switch strings.ToLower(match) {
+	case "", "any":
+		return query.Where(
+			fmt.Sprintf("%s IN (SELECT %s FROM %s WHERE tag_id IN ? AND tag_id IN (SELECT id FROM tags WHERE user_id = ?))", idColumn, ownerColumn, joinTable),
+			ids, userId,
+		), nil
+	case "all":
+		return query.Where(
+			fmt.Sprintf("(SELECT COUNT(DISTINCT tag_id) FROM %s WHERE %s = %s AND tag_id IN ? AND tag_id IN (SELECT id FROM tags WHERE user_id = ?)) = ?",
+				joinTable, ownerColumn, idColumn),
+			ids, userId, len(ids),
+		), nil
+	default:
+		return nil, ErrInvalidTagMatch
+	}
+}
+
+// splitList accepts repeated and comma separated values and drops
+// duplicates.
+func splitList(values []string) []string {
+	items := []string{}
+	seen := map[string]bool{}
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" && !seen[part] {
+				seen[part] = true
+				items = append(items, part)
+			}
+		}
+	}
+	return items
+}
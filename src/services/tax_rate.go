@@ -0,0 +1,194 @@
+package services
+
+import (
+	"errors"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/models"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TaxRateService struct {
+	database *gorm.DB
+}
+
+func NewTaxRateService(database *gorm.DB) *TaxRateService {
+	return &TaxRateService{
+		database: database,
+	}
+}
+
+var (
+	ErrInvalidTaxRate     = errors.New("tax needs a name of at most 50 characters and a rate between 0 and 100")
+	ErrInvalidTaxRateKind = errors.New("a tax can only be one of compound, inclusive or withholding")
+	ErrTaxRateExists      = errors.New("a tax rate with this name already exists")
+	ErrTaxRateNotFound    = errors.New("tax rate not found")
+)
+
+func (s *TaxRateService) CreateTaxRate(userId string, payload dto.CreateTaxRateDto) (*models.TaxRate, error) {
+	rate := &models.TaxRate{
+		Compound:    payload.Compound,
+		Description: strings.TrimSpace(payload.Description),
+		Inclusive:   payload.Inclusive,
+		Name:        strings.TrimSpace(payload.Name),
+		Rate:        payload.Rate,
+		UserID:      uuid.MustParse(userId),
+		Withholding: payload.Withholding,
+	}
+	if err := validateItemTax(rateTax(rate)); err != nil {
+		return nil, err
+	}
+	if err := s.ensureUniqueName(userId, rate.Name, ""); err != nil {
+		return nil, err
+	}
+
+	if err := s.database.Create(rate).Error; err != nil {
+		return nil, err
+	}
+
+	return rate, nil
+}
+
+func (s *TaxRateService) GetTaxRates(userId string) ([]models.TaxRate, error) {
+	var rates []models.TaxRate
+	if err := s.database.Where("user_id = ?", userId).Order("name ASC").Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// UpdateTaxRate changes a catalog rate. Invoices keep the copy of the rate
+// they were created with.
+func (s *TaxRateService) UpdateTaxRate(userId, id string, payload dto.UpdateTaxRateDto) (*models.TaxRate, error) {
+	rate, err := s.FindTaxRateById(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Compound != nil {
+		rate.Compound = *payload.Compound
+	}
+	if payload.Description != nil {
+		rate.Description = strings.TrimSpace(*payload.Description)
+	}
+	if payload.Inclusive != nil {
+		rate.Inclusive = *payload.Inclusive
+	}
+	if payload.Name != nil {
+		rate.Name = strings.TrimSpace(*payload.Name)
+	}
+	if payload.Rate != nil {
+		rate.Rate = *payload.Rate
+	}
+	if payload.Withholding != nil {
+		rate.Withholding = *payload.Withholding
+	}
+	if err := validateItemTax(rateTax(rate)); err != nil {
+		return nil, err
+	}
+	if err := s.ensureUniqueName(userId, rate.Name, id); err != nil {
+		return nil, err
+	}
+
+	if err := s.database.Save(rate).Error; err != nil {
+		return nil, err
+	}
+
+	return rate, nil
+}
+
+func (s *TaxRateService) DeleteTaxRate(userId, id string) error {
+	rate, err := s.FindTaxRateById(userId, id)
+	if err != nil {
+		return err
+	}
+	return s.database.Delete(rate).Error
+}
+
+func (s *TaxRateService) FindTaxRateById(userId, id string) (*models.TaxRate, error) {
+	rate := &models.TaxRate{}
+	if err := s.database.Where("id = ? AND user_id = ?", id, userId).First(rate).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaxRateNotFound
+		}
+		return nil, err
+	}
+	return rate, nil
+}
+
+// resolveItemTaxes turns submitted item taxes into the copies stored on the
+// item, looking up catalog references in the user's catalog.
+func (s *TaxRateService) resolveItemTaxes(userId string, payload []dto.ItemTaxDto) (models.ItemTaxes, error) {
+	taxes := models.ItemTaxes{}
+	for _, item := range payload {
+		tax := models.ItemTax{
+			Compound:    item.Compound,
+			Inclusive:   item.Inclusive,
+			Name:        strings.TrimSpace(item.Name),
+			Rate:        item.Rate,
+			Withholding: item.Withholding,
+		}
+		if item.TaxRateID != "" {
+			if _, err := uuid.Parse(item.TaxRateID); err != nil {
+				return nil, ErrTaxRateNotFound
+			}
+			rate, err := s.FindTaxRateById(userId, item.TaxRateID)
+			if err != nil {
+				return nil, err
+			}
+			tax = rateTax(rate)
+		}
+		if err := validateItemTax(tax); err != nil {
+			return nil, err
+		}
+		taxes = append(taxes, tax)
+	}
+	return taxes, nil
+}
+
+func (s *TaxRateService) ensureUniqueName(userId, name, exceptId string) error {
+	query := s.database.Model(&models.TaxRate{}).Where("user_id = ? AND LOWER(name) = LOWER(?)", userId, name)
+	if exceptId != "" {
+		query = query.Where("id <> ?", exceptId)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrTaxRateExists
+	}
+	return nil
+}
+
+func rateTax(rate *models.TaxRate) models.ItemTax {
+	id := rate.ID
+	return models.ItemTax{
+		Compound:    rate.Compound,
+		Inclusive:   rate.Inclusive,
+		Name:        rate.Name,
+		Rate:        rate.Rate,
+		TaxRateID:   &id,
+		Withholding: rate.Withholding,
+	}
+}
+
+func validateItemTax(tax models.ItemTax) error {
+	if tax.Name == "" || len([]rune(tax.Name)) > 50 || tax.Rate < 0 || tax.Rate > 100 {
+		return ErrInvalidTaxRate
+	}
+
+	kinds := 0
+	for _, set := range []bool{tax.Compound, tax.Inclusive, tax.Withholding} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds > 1 {
+		return ErrInvalidTaxRateKind
+	}
+	return nil
+}
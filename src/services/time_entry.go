@@ -0,0 +1,550 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/models"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TimeEntryService struct {
+	database *gorm.DB
+}
+
+func NewTimeEntryService(database *gorm.DB) *TimeEntryService {
+	return &TimeEntryService{
+		database: database,
+	}
+}
+
+var (
+	ErrInvalidTimeEntry    = errors.New("time entry needs a duration of 1 to 1440 minutes, a rate of at least 0 and a project and task of at most 100 characters")
+	ErrInvalidTimeGrouping = errors.New("time can only be grouped by day, project or task")
+	ErrNoTimerRunning      = errors.New("no timer is running")
+	ErrNoUnbilledTime      = errors.New("no unbilled time for this customer in the period")
+	ErrTimeEntryBilled     = errors.New("time entry is already on an invoice")
+	ErrTimeEntryNotBilled  = errors.New("time entries can only stay on the invoice they were billed on, on one line each")
+	ErrTimeEntryNotFound   = errors.New("time entry not found")
+	ErrTimeEntryRebilled   = errors.New("some of the invoice's time entries have since been billed on another invoice or deleted")
+	ErrTimerRunning        = errors.New("a timer is already running")
+)
+
+// unbilledTimeCondition matches time entries that are not on an invoice, or
+// only on one that has since been archived.
+const unbilledTimeCondition = "(time_entries.invoice_id IS NULL OR time_entries.invoice_id IN (SELECT id FROM invoices WHERE archived_at IS NOT NULL))"
+
+const (
+	groupTimeByDay     = "day"
+	groupTimeByProject = "project"
+	groupTimeByTask    = "task"
+)
+
+func (s *TimeEntryService) CreateTimeEntry(userId string, payload dto.CreateTimeEntryDto) (*models.TimeEntry, error) {
+	customer, err := findActiveCustomer(s.database, payload.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &models.TimeEntry{
+		Billable:    payload.Billable == nil || *payload.Billable,
+		CustomerID:  customer.ID,
+		Date:        entryDate(payload.Date),
+		Description: strings.TrimSpace(payload.Description),
+		Duration:    payload.Duration,
+		HourlyRate:  payload.HourlyRate,
+		Project:     strings.TrimSpace(payload.Project),
+		Task:        strings.TrimSpace(payload.Task),
+		UserID:      uuid.MustParse(userId),
+	}
+	if err := validateTimeEntry(entry); err != nil {
+		return nil, err
+	}
+
+	if err := s.database.Create(entry).Error; err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (s *TimeEntryService) GetTimeEntries(userId string, filter dto.TimeEntryFilter) ([]models.TimeEntry, error) {
+	query := s.database.Where("user_id = ?", userId)
+	if filter.CustomerID != "" {
+		query = query.Where("customer_id = ?", filter.CustomerID)
+	}
+	if filter.Project != "" {
+		query = query.Where("project = ?", filter.Project)
+	}
+	if filter.From != nil {
+		query = query.Where("date >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("date <= ?", *filter.To)
+	}
+	if filter.Billed != nil {
+		if *filter.Billed {
+			query = query.Where("NOT " + unbilledTimeCondition)
+		} else {
+			query = query.Where(unbilledTimeCondition)
+		}
+	}
+
+	var entries []models.TimeEntry
+	if err := query.Order("date DESC, created_at DESC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (s *TimeEntryService) UpdateTimeEntry(userId, id string, payload dto.UpdateTimeEntryDto) (*models.TimeEntry, error) {
+	entry, err := s.findUnbilledTimeEntry(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Billable != nil {
+		entry.Billable = *payload.Billable
+	}
+	if payload.CustomerID != nil {
+		customer, err := findActiveCustomer(s.database, *payload.CustomerID)
+		if err != nil {
+			return nil, err
+		}
+		entry.CustomerID = customer.ID
+	}
+	if payload.Date != nil {
+		entry.Date = entryDate(*payload.Date)
+	}
+	if payload.Description != nil {
+		entry.Description = strings.TrimSpace(*payload.Description)
+	}
+	if payload.Duration != nil {
+		if entry.StartedAt.Valid {
+			return nil, ErrTimerRunning
+		}
+		entry.Duration = *payload.Duration
+	}
+	if payload.HourlyRate != nil {
+		entry.HourlyRate = *payload.HourlyRate
+	}
+	if payload.Project != nil {
+		entry.Project = strings.TrimSpace(*payload.Project)
+	}
+	if payload.Task != nil {
+		entry.Task = strings.TrimSpace(*payload.Task)
+	}
+	if err := validateTimeEntry(entry); err != nil {
+		return nil, err
+	}
+
+	if err := s.database.Save(entry).Error; err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (s *TimeEntryService) DeleteTimeEntry(userId, id string) error {
+	entry, err := s.findUnbilledTimeEntry(userId, id)
+	if err != nil {
+		return err
+	}
+
+	return s.database.Delete(entry).Error
+}
+
+func (s *TimeEntryService) FindTimeEntryById(userId, id string) (*models.TimeEntry, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrTimeEntryNotFound
+	}
+
+	entry := &models.TimeEntry{}
+	if err := s.database.Where("id = ? AND user_id = ?", id, userId).First(entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTimeEntryNotFound
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// GetTimer returns the user's running timer, or nil when none is running.
+func (s *TimeEntryService) GetTimer(userId string) (*models.TimeEntry, error) {
+	entry, err := s.findTimer(userId)
+	if errors.Is(err, ErrNoTimerRunning) {
+		return nil, nil
+	}
+	return entry, err
+}
+
+// StartTimer starts a time entry for today. A user has at most one timer
+// running at a time, which a partial unique index enforces against
+// concurrent starts.
+func (s *TimeEntryService) StartTimer(userId string, payload dto.StartTimerDto) (*models.TimeEntry, error) {
+	if _, err := s.findTimer(userId); !errors.Is(err, ErrNoTimerRunning) {
+		if err == nil {
+			return nil, ErrTimerRunning
+		}
+		return nil, err
+	}
+
+	customer, err := findActiveCustomer(s.database, payload.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entry := &models.TimeEntry{
+		Billable:    payload.Billable == nil || *payload.Billable,
+		CustomerID:  customer.ID,
+		Date:        entryDate(now),
+		Description: strings.TrimSpace(payload.Description),
+		HourlyRate:  payload.HourlyRate,
+		Project:     strings.TrimSpace(payload.Project),
+		StartedAt:   sql.NullTime{Time: now, Valid: true},
+		Task:        strings.TrimSpace(payload.Task),
+		UserID:      uuid.MustParse(userId),
+	}
+	if err := validateTimeEntry(entry); err != nil {
+		return nil, err
+	}
+
+	result := s.database.Clauses(clause.OnConflict{DoNothing: true}).Create(entry)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrTimerRunning
+	}
+
+	return entry, nil
+}
+
+// StopTimer stops the running timer, rounding the time up to whole minutes.
+func (s *TimeEntryService) StopTimer(userId string) (*models.TimeEntry, error) {
+	entry, err := s.findTimer(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	minutes := int(math.Ceil(time.Since(entry.StartedAt.Time).Minutes()))
+	entry.Duration = min(max(minutes, 1), 24*60)
+	entry.StartedAt = sql.NullTime{}
+
+	if err := s.database.Save(entry).Error; err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// InvoiceTime puts a customer's unbilled, billable time in the period on a
+// new draft invoice, one line per group and hourly rate, and marks the
+// entries as billed. Running timers are left out.
+func (s *TimeEntryService) InvoiceTime(userId string, payload dto.InvoiceTimeDto) (*models.Invoice, error) {
+	groupBy := strings.ToLower(strings.TrimSpace(payload.GroupBy))
+	if groupBy == "" {
+		groupBy = groupTimeByProject
+	}
+	if groupBy != groupTimeByDay && groupBy != groupTimeByProject && groupBy != groupTimeByTask {
+		return nil, ErrInvalidTimeGrouping
+	}
+	if !payload.From.IsZero() && !payload.To.IsZero() && payload.To.Before(payload.From) {
+		return nil, ErrInvalidReportRange
+	}
+
+	customer, err := findActiveCustomer(s.database, payload.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var invoice *models.Invoice
+	err = s.database.Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND customer_id = ?", userId, customer.ID).
+			Where("billable = ? AND started_at IS NULL AND duration > 0", true).
+			Where(unbilledTimeCondition)
+		if !payload.From.IsZero() {
+			query = query.Where("date >= ?", entryDate(payload.From))
+		}
+		if !payload.To.IsZero() {
+			query = query.Where("date <= ?", entryDate(payload.To))
+		}
+
+		var entries []models.TimeEntry
+		if err := query.Order("date ASC, created_at ASC").Find(&entries).Error; err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return ErrNoUnbilledTime
+		}
+
+		title := strings.TrimSpace(payload.Title)
+		if title == "" {
+			title = fmt.Sprintf("Time for %s, %s to %s", customer.Name,
+				entries[0].Date.Format("Jan 2, 2006"), entries[len(entries)-1].Date.Format("Jan 2, 2006"))
+		}
+
+		lines := timeInvoiceItems(entries, groupBy)
+		var err error
+		invoice, err = NewInvoiceService(tx).CreateInvoice(userId, dto.CreateInvoiceDto{
+			Currency:   payload.Currency,
+			CustomerID: customer.ID.String(),
+			IsDraft:    true,
+			Items:      lines,
+			Note:       payload.Note,
+			Title:      title,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Lines are unique by description and rate, which ties each created
+		// item back to the entries it bills.
+		for i := range invoice.Items {
+			item := &invoice.Items[i]
+			for _, line := range lines {
+				if line.Description != item.Description || line.Price != item.Price {
+					continue
+				}
+				item.TimeEntryIDs = line.TimeEntryIDs
+				if err := tx.Model(item).Update("time_entry_ids", item.TimeEntryIDs).Error; err != nil {
+					return err
+				}
+				break
+			}
+		}
+
+		ids := make([]uuid.UUID, len(entries))
+		for i, entry := range entries {
+			ids[i] = entry.ID
+		}
+		return tx.Model(&models.TimeEntry{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"billed_at":  time.Now(),
+			"invoice_id": invoice.ID,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invoice, nil
+}
+
+// findUnbilledTimeEntry finds a time entry that can still be changed, that
+// is one not on a live invoice.
+func (s *TimeEntryService) findUnbilledTimeEntry(userId, id string) (*models.TimeEntry, error) {
+	entry, err := s.FindTimeEntryById(userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.InvoiceID == nil {
+		return entry, nil
+	}
+
+	var billed int64
+	err = s.database.Model(&models.Invoice{}).
+		Where("id = ? AND archived_at IS NULL", *entry.InvoiceID).
+		Count(&billed).Error
+	if err != nil {
+		return nil, err
+	}
+	if billed > 0 {
+		return nil, ErrTimeEntryBilled
+	}
+	return entry, nil
+}
+
+// linkTimeLines carries the time entry links over to an invoice's new lines.
+// A submitted line keeps billing its time by sending back the timeEntryIds
+// of the line it replaces; the entries whose lines are dropped become
+// unbilled again.
+func linkTimeLines(tx *gorm.DB, invoiceId uuid.UUID, payload []dto.CreateInvoiceItemDto, items []models.InvoiceItem) error {
+	kept := []string{}
+	for i, item := range payload {
+		if len(item.TimeEntryIDs) == 0 {
+			continue
+		}
+		ids := models.JSONStrings{}
+		for _, raw := range item.TimeEntryIDs {
+			id, err := uuid.Parse(raw)
+			if err != nil || slices.Contains(kept, id.String()) {
+				return ErrTimeEntryNotBilled
+			}
+			ids = append(ids, id.String())
+			kept = append(kept, id.String())
+		}
+		items[i].TimeEntryIDs = ids
+	}
+
+	if len(kept) > 0 {
+		var linked int64
+		err := tx.Model(&models.TimeEntry{}).Where("id IN ? AND invoice_id = ?", kept, invoiceId).Count(&linked).Error
+		if err != nil {
+			return err
+		}
+		if int(linked) != len(kept) {
+			return ErrTimeEntryNotBilled
+		}
+	}
+
+	released := tx.Model(&models.TimeEntry{}).Where("invoice_id = ?", invoiceId)
+	if len(kept) > 0 {
+		released = released.Where("id NOT IN ?", kept)
+	}
+	return released.Updates(map[string]interface{}{
+		"billed_at":  nil,
+		"invoice_id": nil,
+	}).Error
+}
+
+// checkTimeLines makes sure all the time billed on an archived invoice is
+// still linked to it, so restoring the invoice cannot bill time that was
+// released and billed again in the meantime.
+func checkTimeLines(tx *gorm.DB, invoice *models.Invoice) error {
+	ids := []string{}
+	for _, item := range invoice.Items {
+		for _, id := range item.TimeEntryIDs {
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var entries []models.TimeEntry
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ? AND invoice_id = ?", ids, invoice.ID).
+		Find(&entries).Error
+	if err != nil {
+		return err
+	}
+	if len(entries) != len(ids) {
+		return ErrTimeEntryRebilled
+	}
+	return nil
+}
+
+func (s *TimeEntryService) findTimer(userId string) (*models.TimeEntry, error) {
+	entry := &models.TimeEntry{}
+	if err := s.database.Where("user_id = ? AND started_at IS NOT NULL", userId).First(entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoTimerRunning
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+func findActiveCustomer(database *gorm.DB, id string) (*models.Customer, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrCustomerNotFound
+	}
+
+	customer, err := NewCustomerService(database).FindCustomerById(id)
+	if err != nil {
+		return nil, err
+	}
+	if customer.ArchivedAt.Valid {
+		return nil, ErrCustomerArchived
+	}
+	return customer, nil
+}
+
+func validateTimeEntry(entry *models.TimeEntry) error {
+	if !entry.StartedAt.Valid && (entry.Duration < 1 || entry.Duration > 24*60) {
+		return ErrInvalidTimeEntry
+	}
+	if entry.HourlyRate < 0 || len([]rune(entry.Project)) > 100 || len([]rune(entry.Task)) > 100 {
+		return ErrInvalidTimeEntry
+	}
+	return nil
+}
+
+// entryDate drops the time of day, defaulting to today.
+func entryDate(t time.Time) time.Time {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// timeInvoiceItems turns time entries into invoice lines billed by the hour.
+// Entries in the same group but at different rates get their own lines, and
+// each line lists the descriptions of the entries on it. Hours are rounded to
+// the quantity precision.
+func timeInvoiceItems(entries []models.TimeEntry, groupBy string) []dto.CreateInvoiceItemDto {
+	type timeLine struct {
+		label        string
+		rate         float64
+		minutes      int64
+		descriptions []string
+		entryIds     []string
+	}
+
+	var lines []*timeLine
+	index := make(map[string]*timeLine)
+	for _, entry := range entries {
+		label := timeGroupLabel(entry, groupBy)
+		key := fmt.Sprintf("%s|%v", label, entry.HourlyRate)
+
+		line, ok := index[key]
+		if !ok {
+			line = &timeLine{label: label, rate: entry.HourlyRate}
+			index[key] = line
+			lines = append(lines, line)
+		}
+		line.minutes += int64(entry.Duration)
+		line.entryIds = append(line.entryIds, entry.ID.String())
+		if entry.Description != "" && !slices.Contains(line.descriptions, entry.Description) {
+			line.descriptions = append(line.descriptions, entry.Description)
+		}
+	}
+
+	items := make([]dto.CreateInvoiceItemDto, 0, len(lines))
+	for _, line := range lines {
+		description := line.label
+		if len(line.descriptions) > 0 {
+			description += ": " + strings.Join(line.descriptions, "; ")
+		}
+		hours := decimal.NewFromInt(line.minutes).Div(decimal.NewFromInt(60)).Round(int32(quantityPrecision()))
+
+		items = append(items, dto.CreateInvoiceItemDto{
+			Description:  description,
+			Price:        line.rate,
+			Quantity:     hours,
+			TimeEntryIDs: line.entryIds,
+			Unit:         "HUR",
+		})
+	}
+	return items
+}
+
+func timeGroupLabel(entry models.TimeEntry, groupBy string) string {
+	switch groupBy {
+	case groupTimeByDay:
+		return entry.Date.Format("Jan 2, 2006")
+	case groupTimeByTask:
+		if entry.Task != "" {
+			return entry.Task
+		}
+	default:
+		if entry.Project != "" {
+			return entry.Project
+		}
+	}
+	return "General"
+}
@@ -18,11 +18,14 @@ func NewUserService(database *gorm.DB) *UserService {
 	}
 }
 
-func (s *UserService) UpdateUser(id string, payload dto.UpdateUserDto) (*models.User, error) {
+func (s *UserService) UpdateUser(id string, version int, payload dto.UpdateUserDto) (*models.User, error) {
 	user, err := s.GetUser(id)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkVersion(user.Version, version); err != nil {
+		return nil, err
+	}
 
 	if payload.Name != nil {
 		user.Name = *payload.Name
@@ -53,6 +56,12 @@ func (s *UserService) UpdateUser(id string, payload dto.UpdateUserDto) (*models.
 		user.TaxId = *payload.TaxId
 	}
 
+	if payload.Address != nil {
+		if user.Address, err = addressFromDto(payload.Address); err != nil {
+			return nil, err
+		}
+	}
+
 	if payload.BankInformation != nil {
 		user.BankInformation = &models.BankInformation{
 			AccountName:   *payload.BankInformation.AccountName,
@@ -64,7 +73,7 @@ func (s *UserService) UpdateUser(id string, payload dto.UpdateUserDto) (*models.
 		}
 	}
 
-	if err := s.database.Save(user).Error; err != nil {
+	if err := saveVersioned(s.database, user, &user.Version); err != nil {
 		return nil, err
 	}
 
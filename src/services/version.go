@@ -0,0 +1,41 @@
+package services
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var ErrVersionConflict = errors.New("the record has changed since it was fetched; fetch it again and retry")
+
+// checkVersion fails fast when a record is no longer at the version the
+// client last saw. An expected version of 0 accepts any version.
+func checkVersion(current, expected int) error {
+	if expected != 0 && current != expected {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// saveVersioned saves the record and moves it to the next version in one
+// statement that only matches while the record is still at the version it
+// was read at, so a concurrent update in between fails with
+// ErrVersionConflict instead of being overwritten. Selecting every column
+// keeps Save from falling back to an insert when nothing matched.
+func saveVersioned(tx *gorm.DB, record interface{}, version *int) error {
+	read := *version
+	*version = read + 1
+
+	result := tx.Select("*").Where("version = ?", read).Save(record)
+	err := result.Error
+	if err == nil && result.RowsAffected == 0 {
+		err = ErrVersionConflict
+	}
+	if err != nil {
+		*version = read
+	}
+	return err
+}
+
+// nextVersion moves a record to its next version in a column update.
+var nextVersion = gorm.Expr("version + 1")
@@ -0,0 +1,633 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"invoicer-go/m/src/config"
+	"invoicer-go/m/src/dto"
+	"invoicer-go/m/src/models"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookService struct {
+	database *gorm.DB
+	client   *http.Client
+}
+
+func NewWebhookService(database *gorm.DB) *WebhookService {
+	return &WebhookService{
+		database: database,
+		client:   newWebhookClient(config.AppConfig.WebhookTimeout),
+	}
+}
+
+var (
+	ErrInvalidDeliveryStatus   = errors.New("delivery status must be pending, succeeded or failed")
+	ErrInvalidWebhookEvent     = errors.New("webhook events must be one or more of: " + strings.Join(models.WebhookEvents, ", "))
+	ErrInvalidWebhookURL       = errors.New("webhook url must be an absolute http or https url")
+	ErrWebhookAddressBlocked   = errors.New("webhook url must resolve to a public internet address")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrWebhookNotFound         = errors.New("webhook not found")
+)
+
+const (
+	// webhookBackoff is the wait before the first retry; it doubles with
+	// every failed attempt after that.
+	webhookBackoff       = 30 * time.Second
+	webhookMaxBackoff    = 24 * time.Hour
+	webhookBatchSize     = 50
+	webhookResponseLimit = 1024
+)
+
+// webhookPayload is the body of every webhook request. ID identifies the
+// event, so it is the same for every endpoint and on every retry.
+type webhookPayload struct {
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+	Event     string      `json:"event"`
+	ID        uuid.UUID   `json:"id"`
+}
+
+func (s *WebhookService) CreateWebhook(userId string, payload dto.CreateWebhookDto) (*models.WebhookEndpoint, error) {
+	events, err := normalizeWebhookEvents(payload.Events)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateWebhookURL(payload.URL); err != nil {
+		return nil, err
+	}
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := &models.WebhookEndpoint{
+		Active:      payload.Active == nil || *payload.Active,
+		Description: strings.TrimSpace(payload.Description),
+		Events:      events,
+		Secret:      secret,
+		URL:         strings.TrimSpace(payload.URL),
+		UserID:      uuid.MustParse(userId),
+	}
+	if err := s.database.Create(endpoint).Error; err != nil {
+		return nil, err
+	}
+
+	return endpoint, nil
+}
+
+func (s *WebhookService) GetWebhooks(userId string) ([]models.WebhookEndpoint, error) {
+	var endpoints []models.WebhookEndpoint
+	if err := s.database.Where("user_id = ?", userId).Order("created_at ASC").Find(&endpoints).Error; err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+func (s *WebhookService) UpdateWebhook(userId, id string, payload dto.UpdateWebhookDto) (*models.WebhookEndpoint, error) {
+	endpoint, err := s.FindWebhookById(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Active != nil {
+		endpoint.Active = *payload.Active
+	}
+	if payload.Description != nil {
+		endpoint.Description = strings.TrimSpace(*payload.Description)
+	}
+	if payload.Events != nil {
+		if endpoint.Events, err = normalizeWebhookEvents(payload.Events); err != nil {
+			return nil, err
+		}
+	}
+	if payload.URL != nil {
+		if err := validateWebhookURL(*payload.URL); err != nil {
+			return nil, err
+		}
+		endpoint.URL = strings.TrimSpace(*payload.URL)
+	}
+	if payload.RotateSecret {
+		if endpoint.Secret, err = generateWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.database.Save(endpoint).Error; err != nil {
+		return nil, err
+	}
+
+	return endpoint, nil
+}
+
+func (s *WebhookService) DeleteWebhook(userId, id string) error {
+	endpoint, err := s.FindWebhookById(userId, id)
+	if err != nil {
+		return err
+	}
+
+	return s.database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("endpoint_id = ?", endpoint.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(endpoint).Error
+	})
+}
+
+func (s *WebhookService) FindWebhookById(userId, id string) (*models.WebhookEndpoint, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	endpoint := &models.WebhookEndpoint{}
+	if err := s.database.Where("id = ? AND user_id = ?", id, userId).First(endpoint).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// GetDeliveries lists the endpoint's most recent deliveries, newest first.
+func (s *WebhookService) GetDeliveries(userId, id string, params dto.WebhookDeliveryParams) ([]models.WebhookDelivery, error) {
+	endpoint, err := s.FindWebhookById(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := params.Limit
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	query := s.database.Where("endpoint_id = ?", endpoint.ID)
+	if params.Status != "" {
+		status := models.WebhookDeliveryStatus(strings.ToLower(params.Status))
+		if status != models.DeliveryPending && status != models.DeliverySucceeded && status != models.DeliveryFailed {
+			return nil, ErrInvalidDeliveryStatus
+		}
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("created_at DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ReplayDelivery sends a past delivery's payload to the endpoint again as a
+// new delivery. It is attempted straight away and retried like any other if
+// that fails.
+func (s *WebhookService) ReplayDelivery(userId, id, deliveryId string) (*models.WebhookDelivery, error) {
+	endpoint, err := s.FindWebhookById(userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := uuid.Parse(deliveryId); err != nil {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+
+	original := &models.WebhookDelivery{}
+	if err := s.database.Where("id = ? AND endpoint_id = ?", deliveryId, endpoint.ID).First(original).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+
+	delivery := newWebhookDelivery(endpoint.ID, original.Event, original.Payload)
+	if err := s.database.Create(delivery).Error; err != nil {
+		return nil, err
+	}
+	if err := s.attempt(s.database, endpoint, delivery); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// Ping sends a ping event to the endpoint straight away, whether or not it
+// is active, so its owner can check it is reachable and verifies signatures.
+func (s *WebhookService) Ping(userId, id string) (*models.WebhookDelivery, error) {
+	endpoint, err := s.FindWebhookById(userId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := webhookBody(models.WebhookPing, map[string]interface{}{"webhookId": endpoint.ID})
+	if err != nil {
+		return nil, err
+	}
+	delivery := newWebhookDelivery(endpoint.ID, models.WebhookPing, body)
+	if err := s.database.Create(delivery).Error; err != nil {
+		return nil, err
+	}
+	if err := s.attempt(s.database, endpoint, delivery); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// Run sends due deliveries straight away and then on every interval. It
+// blocks, so start it on its own goroutine.
+func (s *WebhookService) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.DeliverDue(); err != nil {
+			log.Printf("Failed to deliver webhooks: %v", err)
+		}
+
+		<-ticker.C
+	}
+}
+
+// DeliverDue attempts the pending deliveries that are due, for active
+// endpoints only; those of a paused endpoint wait until it is reactivated.
+// Deliveries are claimed for long enough to send the whole batch, so other
+// instances skip them, and are sent outside the claiming transaction.
+func (s *WebhookService) DeliverDue() error {
+	now := time.Now()
+	lease := time.Duration(webhookBatchSize+1) * config.AppConfig.WebhookTimeout
+
+	var deliveries []models.WebhookDelivery
+	err := s.database.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+			Where("endpoint_id IN (?)", tx.Model(&models.WebhookEndpoint{}).Select("id").Where("active = ?", true)).
+			Order("next_attempt_at ASC").
+			Limit(webhookBatchSize).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, len(deliveries))
+		for i, delivery := range deliveries {
+			ids[i] = delivery.ID
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil || len(deliveries) == 0 {
+		return err
+	}
+
+	endpointIds := make([]uuid.UUID, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		endpointIds = append(endpointIds, delivery.EndpointID)
+	}
+	var endpoints []models.WebhookEndpoint
+	if err := s.database.Where("id IN ?", endpointIds).Find(&endpoints).Error; err != nil {
+		return err
+	}
+	byId := make(map[uuid.UUID]*models.WebhookEndpoint, len(endpoints))
+	for i := range endpoints {
+		byId[endpoints[i].ID] = &endpoints[i]
+	}
+
+	for i := range deliveries {
+		endpoint, ok := byId[deliveries[i].EndpointID]
+		if !ok {
+			continue
+		}
+		if err := s.attempt(s.database, endpoint, &deliveries[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attempt sends the delivery once and records the outcome. Any 2xx response
+// is a success; anything else is retried with exponential backoff until the
+// attempts run out.
+func (s *WebhookService) attempt(db *gorm.DB, endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery) error {
+	sentAt := time.Now()
+	status, body, err := s.send(endpoint, delivery, sentAt)
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = sql.NullTime{Time: sentAt, Valid: true}
+	delivery.ResponseStatus = status
+	delivery.ResponseBody = body
+	delivery.Error = ""
+	if err != nil {
+		delivery.Error = err.Error()
+	} else if status < 200 || status >= 300 {
+		delivery.Error = fmt.Sprintf("endpoint responded with status %d", status)
+	}
+
+	switch {
+	case delivery.Error == "":
+		delivery.Status = models.DeliverySucceeded
+		delivery.NextAttemptAt = sql.NullTime{}
+	case delivery.Attempts >= config.AppConfig.WebhookMaxAttempts:
+		delivery.Status = models.DeliveryFailed
+		delivery.NextAttemptAt = sql.NullTime{}
+	default:
+		delivery.Status = models.DeliveryPending
+		delivery.NextAttemptAt = sql.NullTime{Time: sentAt.Add(webhookRetryDelay(delivery.Attempts)), Valid: true}
+	}
+
+	return db.Save(delivery).Error
+}
+
+func (s *WebhookService) send(endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery, sentAt time.Time) (int, string, error) {
+	request, err := http.NewRequest(http.MethodPost, endpoint.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "Invoicer-Webhooks/1.0")
+	request.Header.Set("X-Invoicer-Delivery", delivery.ID.String())
+	request.Header.Set("X-Invoicer-Event", delivery.Event)
+	request.Header.Set("X-Invoicer-Signature", signWebhook(endpoint.Secret, sentAt, delivery.Payload))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return 0, "", err
+	}
+	defer response.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(response.Body, webhookResponseLimit))
+	body := strings.ReplaceAll(strings.ToValidUTF8(string(raw), ""), "\x00", "")
+	return response.StatusCode, body, nil
+}
+
+// signWebhook signs a request body for the X-Invoicer-Signature header as
+// "t=<unix time>,v1=<signature>", where the signature is the hex
+// HMAC-SHA256 of "<unix time>.<body>" keyed with the endpoint secret.
+// Receivers should recompute it and reject stale timestamps.
+func signWebhook(secret string, sentAt time.Time, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.%s", sentAt.Unix(), body)
+	return fmt.Sprintf("t=%d,v1=%s", sentAt.Unix(), hex.EncodeToString(mac.Sum(nil)))
+}
+
+func webhookRetryDelay(attempts int) time.Duration {
+	return min(webhookBackoff<<min(attempts-1, 16), webhookMaxBackoff)
+}
+
+// queueInvoiceWebhook queues the webhook, if any, that an invoice activity
+// event triggers for the account of the user behind it, or of the invoice's
+// owner for events with no user behind them, such as an invoice falling
+// overdue or a payment made through the portal. It runs in the transaction
+// that records the event, so nothing is sent for changes that are rolled
+// back.
+func queueInvoiceWebhook(tx *gorm.DB, event *models.InvoiceEvent) error {
+	name := invoiceWebhookEvent(event)
+	if name == "" {
+		return nil
+	}
+
+	userId := ""
+	if event.ActorID != nil {
+		userId = event.ActorID.String()
+	} else {
+		owner, err := invoiceOwner(tx, event.InvoiceID)
+		if err != nil || owner == nil {
+			return err
+		}
+		userId = owner.String()
+	}
+
+	endpoints, err := subscribedWebhooks(tx, userId, name)
+	if err != nil || len(endpoints) == 0 {
+		return err
+	}
+
+	invoice := &models.Invoice{}
+	if err := tx.Preload("Customer").Preload("Items").First(invoice, "id = ?", event.InvoiceID).Error; err != nil {
+		return err
+	}
+	return queueWebhookDeliveries(tx, endpoints, name, map[string]interface{}{
+		"details": event.Data,
+		"invoice": invoice,
+	})
+}
+
+// invoiceOwner returns the user who first acted on the invoice, normally the
+// one who created it, or nil when no user has.
+func invoiceOwner(tx *gorm.DB, invoiceId uuid.UUID) (*uuid.UUID, error) {
+	var events []models.InvoiceEvent
+	err := tx.Where("invoice_id = ? AND actor_id IS NOT NULL", invoiceId).
+		Order("created_at ASC").
+		Limit(1).
+		Find(&events).Error
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+	return events[0].ActorID, nil
+}
+
+// queueCustomerWebhook queues a customer event for the user's account, in
+// the transaction that changed the customer.
+func queueCustomerWebhook(tx *gorm.DB, userId, name string, customer *models.Customer) error {
+	endpoints, err := subscribedWebhooks(tx, userId, name)
+	if err != nil || len(endpoints) == 0 {
+		return err
+	}
+	return queueWebhookDeliveries(tx, endpoints, name, map[string]interface{}{"customer": customer})
+}
+
+func invoiceWebhookEvent(event *models.InvoiceEvent) string {
+	switch event.Type {
+	case models.InvoiceCreated:
+		return models.WebhookInvoiceCreated
+	case models.InvoiceSent:
+		return models.WebhookInvoiceSent
+	case models.InvoiceReminded:
+		return models.WebhookInvoiceReminded
+	case models.InvoiceArchived:
+		return models.WebhookInvoiceArchived
+	case models.InvoiceRestored:
+		return models.WebhookInvoiceRestored
+	case models.InvoicePaymentAdded:
+		return models.WebhookPaymentRecorded
+	case models.InvoicePaymentVoided:
+		return models.WebhookPaymentDeleted
+	case models.InvoiceStatusChanged:
+		switch fmt.Sprint(event.Data["to"]) {
+		case string(models.Paid):
+			return models.WebhookInvoicePaid
+		case string(models.Overdue):
+			return models.WebhookInvoiceOverdue
+		}
+	}
+	return ""
+}
+
+func subscribedWebhooks(tx *gorm.DB, userId, name string) ([]models.WebhookEndpoint, error) {
+	if _, err := uuid.Parse(userId); err != nil {
+		return nil, nil
+	}
+
+	subscription, err := json.Marshal([]string{name})
+	if err != nil {
+		return nil, err
+	}
+	var endpoints []models.WebhookEndpoint
+	err = tx.Where("user_id = ? AND active = ? AND events @> ?::jsonb", userId, true, string(subscription)).
+		Find(&endpoints).Error
+	return endpoints, err
+}
+
+func queueWebhookDeliveries(tx *gorm.DB, endpoints []models.WebhookEndpoint, name string, data interface{}) error {
+	body, err := webhookBody(name, data)
+	if err != nil {
+		return err
+	}
+
+	for _, endpoint := range endpoints {
+		if err := tx.Create(newWebhookDelivery(endpoint.ID, name, body)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newWebhookDelivery(endpointId uuid.UUID, name, body string) *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		EndpointID:    endpointId,
+		Event:         name,
+		NextAttemptAt: sql.NullTime{Time: time.Now(), Valid: true},
+		Payload:       body,
+		Status:        models.DeliveryPending,
+	}
+}
+
+func webhookBody(name string, data interface{}) (string, error) {
+	raw, err := json.Marshal(webhookPayload{
+		CreatedAt: time.Now(),
+		Data:      data,
+		Event:     name,
+		ID:        uuid.New(),
+	})
+	return string(raw), err
+}
+
+func normalizeWebhookEvents(events []string) (models.JSONStrings, error) {
+	normalized := models.JSONStrings{}
+	for _, event := range events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if !slices.Contains(models.WebhookEvents, event) {
+			return nil, ErrInvalidWebhookEvent
+		}
+		if !slices.Contains(normalized, event) {
+			normalized = append(normalized, event)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, ErrInvalidWebhookEvent
+	}
+	return normalized, nil
+}
+
+// validateWebhookURL accepts absolute http(s) urls whose host resolves only
+// to public addresses. The dialer checks again on every connection, since
+// the host can resolve differently by the time anything is sent.
+func validateWebhookURL(raw string) error {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || parsed.Hostname() == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return ErrInvalidWebhookURL
+	}
+
+	addresses, err := net.LookupIP(parsed.Hostname())
+	if err != nil || len(addresses) == 0 {
+		return ErrInvalidWebhookURL
+	}
+	for _, address := range addresses {
+		if !publicWebhookAddress(address) {
+			return ErrWebhookAddressBlocked
+		}
+	}
+	return nil
+}
+
+// newWebhookClient returns a client that only connects to public addresses
+// and does not follow redirects, so an endpoint cannot point deliveries at
+// the server's own network.
+func newWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicWebhookAddress(ip) {
+				return ErrWebhookAddressBlocked
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// reservedWebhookNetworks are special-purpose IPv4 ranges that are not
+// reachable on the public internet but are not covered by net.IP's checks:
+// "this network", carrier-grade NAT, protocol assignments, benchmarking and
+// the reserved class E range.
+var reservedWebhookNetworks = []*net.IPNet{
+	{IP: net.IPv4(0, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+	{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)},
+	{IP: net.IPv4(192, 0, 0, 0), Mask: net.CIDRMask(24, 32)},
+	{IP: net.IPv4(198, 18, 0, 0), Mask: net.CIDRMask(15, 32)},
+	{IP: net.IPv4(240, 0, 0, 0), Mask: net.CIDRMask(4, 32)},
+}
+
+// publicWebhookAddress reports whether deliveries may connect to ip: it must
+// not be loopback, private, link-local, unspecified, multicast or reserved.
+func publicWebhookAddress(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range reservedWebhookNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func generateWebhookSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(raw), nil
+}